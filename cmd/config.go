@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/viper"
+
+	"github.com/spf13/cobra"
+)
+
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "print",
+		Short: "Print the effective configuration and the layer of each value, with secrets redacted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			opts, err := configOptions(cmd)
+			if err != nil {
+				return err
+			}
+
+			conf, err := viper.NewConfiguration(opts)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+
+			for _, setting := range conf.Settings() {
+				value, err := formatValue(setting.Value)
+				if err != nil {
+					return errors.Wrapf(err, "Failed to format value of configuration key (%s)", setting.Key)
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\n", setting.Key, value, setting.Source)
+			}
+
+			return w.Flush()
+		},
+	})
+
+	return cmd
+}
+
+// formatValue prints strings as they are and other values as json
+func formatValue(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"microservice/internal/app/domain"
+	"microservice/internal/pkg/auth"
+	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/tenancy"
+	"microservice/models"
+	"microservice/wire"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultDocTimeout = 15 * time.Second
+	defaultDocSubject = "cli"
+	stdinFile         = "-"
+
+	flagTenant  = "tenant"
+	flagSubject = "subject"
+	flagFile    = "file"
+)
+
+func newDocCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doc",
+		Short: "Read and write documents",
+	}
+
+	cmd.PersistentFlags().Duration(flagTimeout, defaultDocTimeout, "timeout of the operation")
+	cmd.PersistentFlags().String(flagTenant, "", "tenant of the documents")
+	cmd.PersistentFlags().String(flagSubject, defaultDocSubject, "subject recorded as the creator of new documents")
+	_ = cmd.MarkPersistentFlagRequired(flagTenant)
+
+	put := &cobra.Command{
+		Use:   "put [id]",
+		Short: "Add a document, or create or replace the document of the given id",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: docRunner(func(ctx context.Context, cmd *cobra.Command, d *domain.Domain, args []string) error {
+			doc, err := readDocumentFile(cmd)
+			if err != nil {
+				return err
+			}
+
+			id := ""
+			if len(args) == 1 {
+				id = args[0]
+				_, err = d.PutDocument(ctx, id, doc)
+			} else {
+				id, err = d.AddDocument(ctx, doc, "")
+			}
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), id)
+			return nil
+		}),
+	}
+
+	put.Flags().String(flagFile, stdinFile, "json file of the document, - reads standard input")
+	put.Flags().String(flagAPIDir, defaultAPIDir, "directory of the api json schemas")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "get <id>",
+			Short: "Print the document of the given id",
+			Args:  cobra.ExactArgs(1),
+			RunE: docRunner(func(ctx context.Context, cmd *cobra.Command, d *domain.Domain, args []string) error {
+				doc, err := d.GetDocument(ctx, args[0], models.ReadOptions{})
+				if err != nil {
+					return err
+				}
+
+				b, err := json.MarshalIndent(doc, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "Failed to marshal document")
+				}
+
+				fmt.Fprintln(cmd.OutOrStdout(), string(b))
+				return nil
+			}),
+		},
+		put,
+	)
+
+	return cmd
+}
+
+// docRunner returns a command function that runs fn with a domain acting as an admin principal of the tenant flag
+func docRunner(fn func(ctx context.Context, cmd *cobra.Command, d *domain.Domain, args []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		timeout, err := cmd.Flags().GetDuration(flagTimeout)
+		if err != nil {
+			return err
+		}
+
+		tenant, err := cmd.Flags().GetString(flagTenant)
+		if err != nil {
+			return err
+		}
+
+		subject, err := cmd.Flags().GetString(flagSubject)
+		if err != nil {
+			return err
+		}
+
+		opts, err := configOptions(cmd)
+		if err != nil {
+			return err
+		}
+		opts.Overrides[confKeyWaitForDatabase] = "true"
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		d, err := wire.InitializeDomain(ctx, opts)
+		if err != nil {
+			return errors.Wrap(err, "Failed to inject dependencies")
+		}
+
+		ctx = auth.NewContext(ctx, models.Principal{Subject: subject, Roles: []string{domain.RoleAdmin}, Tenant: tenant})
+		ctx = tenancy.NewContext(ctx, tenant)
+
+		runErr := fn(ctx, cmd, d, args)
+		if err := d.Teardown(ctx); err != nil {
+			log.Errorf("Failed to tear down domain: %s", err)
+		}
+
+		return runErr
+	}
+}
+
+// readDocumentFile reads the document of the file flag and validates it against the document schema
+func readDocumentFile(cmd *cobra.Command) (models.Document, error) {
+	file, err := cmd.Flags().GetString(flagFile)
+	if err != nil {
+		return models.Document{}, err
+	}
+
+	apiDir, err := cmd.Flags().GetString(flagAPIDir)
+	if err != nil {
+		return models.Document{}, err
+	}
+
+	var b []byte
+	if file == stdinFile {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(file)
+	}
+
+	if err != nil {
+		return models.Document{}, errors.Wrapf(err, "Failed to read document file (%s)", file)
+	}
+
+	if err := validateSchema(apiDir, defaultSchema, b); err != nil {
+		return models.Document{}, err
+	}
+
+	var doc models.Document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return models.Document{}, errors.Wrap(err, "Failed to unmarshal document")
+	}
+
+	return doc, nil
+}
@@ -1,45 +1,92 @@
 package main
 
 import (
-	"context"
 	"os"
+	"strings"
 	"time"
 
-	"microservice/wire"
+	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/viper"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
 )
 
 const (
-	initializationTimeout = 15
-	stopTimeout           = 10
-	exitCauseDone         = 0
-	exitCauseError        = 1
+	defaultInitializationTimeout = 15 * time.Second
+	defaultStopTimeout           = 10 * time.Second
+	exitCauseDone                = 0
+	exitCauseError               = 1
+
+	flagConfig = "config"
+	flagEnv    = "env"
+	flagSet    = "set"
+
+	// confKeyWaitForDatabase is forced by the commands that can't run without the database
+	confKeyWaitForDatabase = "mongo.startup.waitForDatabase"
 )
 
 func main() {
-	initCtx, initCtxCancel := context.WithTimeout(context.Background(), initializationTimeout*time.Second)
+	if err := newRootCommand().Execute(); err != nil {
+		log.Error(err)
+		os.Exit(exitCauseError)
+	}
+
+	os.Exit(exitCauseDone)
+}
+
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "microservice",
+		Short:         "Document microservice",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	root.PersistentFlags().String(flagConfig, "", "path of the base configuration file (default "+viper.DefaultConfigFile+", or $"+viper.EnvConfigFile+")")
+	root.PersistentFlags().String(flagEnv, "", "environment whose overlay file <env>.yaml next to the base file is applied (or $"+viper.EnvEnvironment+")")
+	root.PersistentFlags().StringArray(flagSet, nil, "override a configuration value, as key=value (repeatable)")
 
-	app, err := wire.InitializeApplication(initCtx)
+	root.AddCommand(
+		newServeCommand(),
+		newMigrateCommand(),
+		newValidateSchemaCommand(),
+		newConfigCommand(),
+		newDocCommand(),
+	)
+
+	return root
+}
+
+// configOptions returns the configuration layers given by the config, env and set flags
+func configOptions(cmd *cobra.Command) (viper.Options, error) {
+	file, err := cmd.Flags().GetString(flagConfig)
 	if err != nil {
-		log.Errorf("Failed to inject dependencies: %s", err)
-		initCtxCancel()
-		os.Exit(exitCauseError)
+		return viper.Options{}, errors.Wrapf(err, "Failed to read flag (%s)", flagConfig)
 	}
 
-	exitCode := exitCauseDone
-	if err := app.Start(); err != nil {
-		log.Errorf("Failed to start application: %s", err)
-		exitCode = exitCauseError
+	env, err := cmd.Flags().GetString(flagEnv)
+	if err != nil {
+		return viper.Options{}, errors.Wrapf(err, "Failed to read flag (%s)", flagEnv)
+	}
+
+	sets, err := cmd.Flags().GetStringArray(flagSet)
+	if err != nil {
+		return viper.Options{}, errors.Wrapf(err, "Failed to read flag (%s)", flagSet)
 	}
 
-	stopCtx, stopCtxCancel := context.WithTimeout(context.Background(), stopTimeout*time.Second)
-	if err := app.Stop(stopCtx); err != nil {
-		log.Errorf("Failed to gracefully stop application: %s", err)
-		exitCode = exitCauseError
+	overrides := make(map[string]string, len(sets))
+	for _, set := range sets {
+		i := strings.Index(set, "=")
+		if i <= 0 {
+			return viper.Options{}, errors.Errorf("Invalid configuration override (%s), expected key=value", set)
+		}
+		overrides[set[:i]] = set[i+1:]
 	}
 
-	log.Info("Application closed")
-	stopCtxCancel()
-	os.Exit(exitCode)
+	return viper.Options{
+		File:        file,
+		Environment: env,
+		Overrides:   overrides,
+	}, nil
 }
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"microservice/internal/app"
+	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/mongodb"
+	"microservice/wire"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultMigrationTimeout = 10 * time.Minute
+
+	flagTimeout = "timeout"
+	flagDryRun  = "dry-run"
+)
+
+func newMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Reconcile the database indexes and apply the pending data migrations",
+		Args:  cobra.NoArgs,
+		RunE: migrateRunner(func(ctx context.Context, db *mongodb.MongoDB, dryRun bool) error {
+			if !dryRun {
+				if err := app.ReconcileIndexes(ctx, db); err != nil {
+					return err
+				}
+			}
+
+			return app.MigrateUp(ctx, db, dryRun)
+		}),
+	}
+
+	cmd.PersistentFlags().Duration(flagTimeout, defaultMigrationTimeout, "timeout of the migration")
+	cmd.PersistentFlags().Bool(flagDryRun, false, "report the documents the migrations would change without changing them")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply the pending data migrations",
+			Args:  cobra.NoArgs,
+			RunE: migrateRunner(func(ctx context.Context, db *mongodb.MongoDB, dryRun bool) error {
+				return app.MigrateUp(ctx, db, dryRun)
+			}),
+		},
+		&cobra.Command{
+			Use:   "down",
+			Short: "Revert the latest applied data migration",
+			Args:  cobra.NoArgs,
+			RunE: migrateRunner(func(ctx context.Context, db *mongodb.MongoDB, dryRun bool) error {
+				return app.MigrateDown(ctx, db, dryRun)
+			}),
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "List the applied and pending data migrations",
+			Args:  cobra.NoArgs,
+			RunE: migrateRunner(func(ctx context.Context, db *mongodb.MongoDB, _ bool) error {
+				return app.MigrationStatus(ctx, db)
+			}),
+		},
+	)
+
+	return cmd
+}
+
+// migrateRunner returns a command function that connects to the database, runs fn and disconnects
+func migrateRunner(fn func(ctx context.Context, db *mongodb.MongoDB, dryRun bool) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, _ []string) error {
+		timeout, err := cmd.Flags().GetDuration(flagTimeout)
+		if err != nil {
+			return err
+		}
+
+		dryRun, err := cmd.Flags().GetBool(flagDryRun)
+		if err != nil {
+			return err
+		}
+
+		opts, err := configOptions(cmd)
+		if err != nil {
+			return err
+		}
+		opts.Overrides[confKeyWaitForDatabase] = "true"
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		db, err := wire.InitializeMongoDB(ctx, opts)
+		if err != nil {
+			return errors.Wrap(err, "Failed to inject dependencies")
+		}
+
+		runErr := fn(ctx, db, dryRun)
+		if err := db.Teardown(ctx); err != nil {
+			log.Errorf("Failed to disconnect from mongodb: %s", err)
+		}
+
+		if runErr != nil {
+			return errors.Wrap(runErr, "Failed to migrate")
+		}
+
+		return nil
+	}
+}
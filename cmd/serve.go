@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/viper"
+	"microservice/wire"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newServeCommand() *cobra.Command {
+	var initTimeout, stopTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Start the rest server",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			opts, err := configOptions(cmd)
+			if err != nil {
+				return err
+			}
+
+			return serve(opts, initTimeout, stopTimeout)
+		},
+	}
+
+	cmd.Flags().DurationVar(&initTimeout, "init-timeout", defaultInitializationTimeout, "timeout of the initialization of the application")
+	cmd.Flags().DurationVar(&stopTimeout, "stop-timeout", defaultStopTimeout, "timeout of the graceful shutdown of the application")
+
+	return cmd
+}
+
+func serve(opts viper.Options, initTimeout time.Duration, stopTimeout time.Duration) error {
+	initCtx, initCtxCancel := context.WithTimeout(context.Background(), initTimeout)
+	defer initCtxCancel()
+
+	a, err := wire.InitializeApplication(initCtx, opts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to inject dependencies")
+	}
+
+	var startErr error
+	if err := a.Start(); err != nil {
+		startErr = errors.Wrap(err, "Failed to start application")
+	}
+
+	stopCtx, stopCtxCancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer stopCtxCancel()
+
+	if err := a.Stop(stopCtx); err != nil {
+		if startErr != nil {
+			log.Error(startErr)
+		}
+
+		return errors.Wrap(err, "Failed to gracefully stop application")
+	}
+
+	log.Info("Application closed")
+	return startErr
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/jsonschema"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultAPIDir = "./api"
+	defaultSchema = "postDocumentSchema"
+
+	flagAPIDir = "api-dir"
+	flagSchema = "schema"
+)
+
+func newValidateSchemaCommand() *cobra.Command {
+	var apiDir, schema string
+
+	cmd := &cobra.Command{
+		Use:   "validate-schema <file>",
+		Short: "Validate a json file against a schema of the api",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				return errors.Wrapf(err, "Failed to read file (%s)", args[0])
+			}
+
+			if err := validateSchema(apiDir, schema, input); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is valid according to %s\n", args[0], schema)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&apiDir, flagAPIDir, defaultAPIDir, "directory of the api json schemas")
+	cmd.Flags().StringVar(&schema, flagSchema, defaultSchema, "name of the schema file in the api directory, without the .json extension")
+
+	return cmd
+}
+
+// validateSchema validates input against the schema of the given name in apiDir
+func validateSchema(apiDir string, schema string, input []byte) error {
+	schemaFile := filepath.Join(apiDir, schema+".json")
+	s, err := ioutil.ReadFile(schemaFile)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read schema file (%s)", schemaFile)
+	}
+
+	js := jsonschema.NewJSONSchemaService()
+	if err := js.SetSchemaFromBytes(schema, s); err != nil {
+		return errors.Wrapf(err, "Invalid schema file (%s)", schemaFile)
+	}
+
+	return js.ValidateSchemaFromBytes(schema, input)
+}
@@ -3,17 +3,27 @@ module microservice
 go 1.13
 
 require (
+	github.com/evanphx/json-patch v5.6.0+incompatible
+	github.com/fsnotify/fsnotify v1.4.7
+	github.com/fxamacker/cbor/v2 v2.5.0
 	github.com/go-chi/chi v4.0.3+incompatible
+	github.com/golang-jwt/jwt/v4 v4.5.2
 	github.com/golang/mock v1.4.3
-	github.com/google/uuid v1.1.1
+	github.com/google/uuid v1.6.0
 	github.com/google/wire v0.4.0
+	github.com/klauspost/compress v1.13.6
 	github.com/konsorten/go-windows-terminal-sequences v1.0.2 // indirect
+	github.com/oklog/ulid/v2 v2.1.1
 	github.com/pelletier/go-toml v1.4.0 // indirect
 	github.com/prometheus/common v0.4.0
 	github.com/sirupsen/logrus v1.4.2
 	github.com/spf13/cast v1.3.0
-	github.com/spf13/viper v1.6.2
+	github.com/spf13/cobra v1.1.3
+	github.com/spf13/viper v1.7.0
+	github.com/vmihailenco/msgpack/v5 v5.3.5
 	github.com/xeipuuv/gojsonpointer v0.0.0-20190905194746-02993c407bfb // indirect
 	github.com/xeipuuv/gojsonschema v1.2.0
 	go.mongodb.org/mongo-driver v1.11.2
+	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
+	gopkg.in/yaml.v2 v2.4.0
 )
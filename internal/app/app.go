@@ -5,27 +5,14 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
+	"microservice/internal/pkg/config"
 	"microservice/internal/pkg/errors"
 
 	log "github.com/sirupsen/logrus"
 )
 
-const (
-	confKeyLogBase  = "log"
-	confKeyLogLevel = confKeyLogBase + ".level"
-)
-
-// Configuration expose an interface of configuration related actions
-type Configuration interface {
-	Get(key string) interface{}
-	GetString(key string) (string, error)
-	GetBool(key string) (bool, error)
-	GetInt(key string) (int, error)
-	GetDuration(key string) (time.Duration, error)
-	IsSet(key string) bool
-}
+const confKeyLogLevel = "log.level"
 
 // RestServer defines a driving adapter interface
 type RestServer interface {
@@ -33,14 +20,54 @@ type RestServer interface {
 	Stop(context.Context) error
 }
 
+// ConfigReloader reloads the configuration on demand and when its files change
+type ConfigReloader interface {
+	Reload() error
+	Watch(onReload func(err error))
+	Subscribe(keys []string, handler config.ChangeHandler)
+}
+
 // App defines the application struct
 type App struct {
 	restServer RestServer
+	conf       ConfigReloader
 }
 
 // NewApp returns a new instance of the App struct
-func NewApp(conf Configuration, rs RestServer) (*App, error) {
-	logLevel, err := conf.GetString(confKeyLogLevel)
+func NewApp(ctx context.Context, logConf config.Log, startup config.Startup, mongoConf config.Mongo, conf ConfigReloader, rs RestServer, indexes IndexManager, migrations Migrator) (*App, error) {
+	logrusLevel, err := log.ParseLevel(logConf.Level)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse log level (%s)", logConf.Level)
+	}
+
+	log.SetLevel(logrusLevel)
+	conf.Subscribe([]string{confKeyLogLevel}, reloadLogLevel)
+
+	if mongoConf.Startup.WaitForDatabase {
+		log.WithFields(mongoConf.LogFields()).Info("Connected to mongodb")
+	} else {
+		log.WithFields(mongoConf.LogFields()).Info("Connecting to mongodb in the background, not ready until it is reachable")
+	}
+
+	if startup.ReconcileIndexes {
+		if err := ReconcileIndexes(ctx, indexes); err != nil {
+			return nil, err
+		}
+	}
+
+	if mongoConf.Startup.WaitForDatabase {
+		WarnPendingMigrations(ctx, migrations)
+	}
+
+	return &App{
+		restServer: rs,
+		conf:       conf,
+	}, nil
+}
+
+// reloadLogLevel prepares the change of the log level to the one of candidate
+func reloadLogLevel(candidate config.Values) (func(), error) {
+	logLevel, err := candidate.GetString(confKeyLogLevel)
 	if err != nil {
 		return nil, err
 	}
@@ -50,18 +77,19 @@ func NewApp(conf Configuration, rs RestServer) (*App, error) {
 		return nil, errors.Wrapf(err, "Failed to parse log level (%s)", logLevel)
 	}
 
-	log.SetLevel(logrusLevel)
-
-	return &App{
-		restServer: rs,
+	return func() {
+		log.SetLevel(logrusLevel)
 	}, nil
 }
 
-// Start begins the flow of the app
+// Start begins the flow of the app. The configuration is reloaded on SIGHUP and when its files change
 func (a *App) Start() error {
 	sig := make(chan os.Signal, 1)
 	errChan := make(chan error, 1)
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sig)
+
+	a.conf.Watch(logReload)
 
 	go func() {
 		if err := a.restServer.Start(); err != nil {
@@ -69,13 +97,31 @@ func (a *App) Start() error {
 		}
 	}()
 
-	select {
-	case err := <-errChan:
-		return errors.Wrap(err, "Failed to start drivers")
-	case s := <-sig:
-		log.Infof("Got (%s) signal to terminate application", s.String())
-		return nil
+	for {
+		select {
+		case err := <-errChan:
+			return errors.Wrap(err, "Failed to start drivers")
+		case s := <-sig:
+			if s == syscall.SIGHUP {
+				log.Info("Got (hangup) signal to reload configuration")
+				logReload(a.conf.Reload())
+				continue
+			}
+
+			log.Infof("Got (%s) signal to terminate application", s.String())
+			return nil
+		}
+	}
+}
+
+// logReload logs the outcome of a configuration reload
+func logReload(err error) {
+	if err != nil {
+		log.Errorf("Kept the current configuration: %s", err)
+		return
 	}
+
+	log.Info("Configuration reloaded")
 }
 
 // Stop does a graceful shutdown of the app
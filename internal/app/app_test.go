@@ -2,64 +2,103 @@ package app
 
 import (
 	"context"
+	"os"
 	"reflect"
+	"syscall"
 	"testing"
 	"time"
 
+	"microservice/internal/pkg/config"
 	"microservice/internal/pkg/errors"
 	"microservice/mocks"
+	"microservice/models"
 
 	"github.com/golang/mock/gomock"
 )
 
 func TestNewApp(t *testing.T) {
-	type getLogLevelMockData struct {
-		times    int
-		logLevel string
-		err      error
+	type reconcileIndexesMockData struct {
+		times int
+		err   error
+	}
+
+	successfulReconcileIndexes := reconcileIndexesMockData{
+		times: 1,
+		err:   nil,
 	}
 
-	getValidLogLevel := getLogLevelMockData{
-		times:    1,
-		logLevel: "info",
-		err:      nil,
+	failedToReconcileIndexes := reconcileIndexesMockData{
+		times: 1,
+		err:   errors.New("some-error"),
+	}
+
+	type migrationStatusMockData struct {
+		times    int
+		statuses []models.MigrationStatus
+		err      error
 	}
 
-	getInvalidLogLevel := getLogLevelMockData{
-		times:    1,
-		logLevel: "fake-level",
-		err:      nil,
+	pendingMigrations := migrationStatusMockData{
+		times: 1,
+		statuses: []models.MigrationStatus{
+			{Database: "db", Version: 1, Description: "some-migration", Applied: true},
+			{Database: "db", Version: 2, Description: "other-migration"},
+		},
 	}
 
-	failedToGetLogLevel := getLogLevelMockData{
+	failedToGetMigrationStatus := migrationStatusMockData{
 		times: 1,
 		err:   errors.New("some-error"),
 	}
 
+	connected := config.Mongo{Startup: config.MongoStartup{WaitForDatabase: true}}
+
 	tests := []struct {
-		name          string
-		getLogLevelMD getLogLevelMockData
-		wantErr       bool
+		name               string
+		logConf            config.Log
+		startup            config.Startup
+		mongoConf          config.Mongo
+		reconcileIndexesMD reconcileIndexesMockData
+		migrationStatusMD  migrationStatusMockData
+		wantErr            bool
 	}{
 		{
-			name:          "valid creation expect no error",
-			getLogLevelMD: getValidLogLevel,
-			wantErr:       false,
+			name:    "valid creation expect no error",
+			logConf: config.Log{Level: "info"},
+			wantErr: false,
+		},
+		{
+			name:               "valid creation with indexes reconciled on startup expect no error",
+			logConf:            config.Log{Level: "info"},
+			startup:            config.Startup{ReconcileIndexes: true},
+			reconcileIndexesMD: successfulReconcileIndexes,
+			wantErr:            false,
+		},
+		{
+			name:              "valid creation with pending migrations expect no error",
+			logConf:           config.Log{Level: "info"},
+			mongoConf:         connected,
+			migrationStatusMD: pendingMigrations,
+			wantErr:           false,
 		},
 		{
-			name:          "failed to get log level from configuration expect error",
-			getLogLevelMD: failedToGetLogLevel,
-			wantErr:       true,
+			name:              "failed to get migrations status on startup expect no error",
+			logConf:           config.Log{Level: "info"},
+			mongoConf:         connected,
+			migrationStatusMD: failedToGetMigrationStatus,
+			wantErr:           false,
 		},
 		{
-			name:          "failed to get log level from configuration expect error",
-			getLogLevelMD: failedToGetLogLevel,
-			wantErr:       true,
+			name:    "invalid log level expect error",
+			logConf: config.Log{Level: "fake-level"},
+			wantErr: true,
 		},
 		{
-			name:          "get invalid log level from configuration expect error",
-			getLogLevelMD: getInvalidLogLevel,
-			wantErr:       true,
+			name:               "failed to reconcile indexes on startup expect error",
+			logConf:            config.Log{Level: "info"},
+			startup:            config.Startup{ReconcileIndexes: true},
+			reconcileIndexesMD: failedToReconcileIndexes,
+			wantErr:            true,
 		},
 	}
 	for _, tt := range tests {
@@ -68,10 +107,21 @@ func TestNewApp(t *testing.T) {
 			defer c.Finish()
 
 			restServer := mocks.NewMockRestServer(c)
-			conf := mocks.NewMockConfigurationService(c)
-			conf.EXPECT().GetString(confKeyLogLevel).Times(tt.getLogLevelMD.times).Return(tt.getLogLevelMD.logLevel, tt.getLogLevelMD.err)
 
-			got, err := NewApp(conf, restServer)
+			conf := mocks.NewMockConfigReloader(c)
+			conf.EXPECT().Subscribe([]string{confKeyLogLevel}, gomock.Any()).AnyTimes()
+
+			indexes := mocks.NewMockIndexManager(c)
+			indexes.EXPECT().ReconcileIndexes(gomock.Any()).
+				Times(tt.reconcileIndexesMD.times).
+				Return(nil, tt.reconcileIndexesMD.err)
+
+			migrations := mocks.NewMockMigrator(c)
+			migrations.EXPECT().MigrationStatus(gomock.Any()).
+				Times(tt.migrationStatusMD.times).
+				Return(tt.migrationStatusMD.statuses, tt.migrationStatusMD.err)
+
+			got, err := NewApp(context.TODO(), tt.logConf, tt.startup, tt.mongoConf, conf, restServer, indexes, migrations)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewApp() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -83,6 +133,7 @@ func TestNewApp(t *testing.T) {
 
 			want := &App{
 				restServer: restServer,
+				conf:       conf,
 			}
 
 			if !reflect.DeepEqual(got, want) {
@@ -97,6 +148,11 @@ func TestApp_Start(t *testing.T) {
 		err error
 	}
 
+	type reloadMockData struct {
+		times int
+		err   error
+	}
+
 	successfulStartRestServer := startRestServerMockData{
 		err: nil,
 	}
@@ -105,9 +161,21 @@ func TestApp_Start(t *testing.T) {
 		err: errors.New("some-error"),
 	}
 
+	successfulReload := reloadMockData{
+		times: 1,
+		err:   nil,
+	}
+
+	rejectedReload := reloadMockData{
+		times: 1,
+		err:   errors.New("some-error"),
+	}
+
 	tests := []struct {
 		name              string
 		startRestServerMD startRestServerMockData
+		hangup            bool
+		reloadMD          reloadMockData
 		wantErr           bool
 	}{
 		{
@@ -120,6 +188,20 @@ func TestApp_Start(t *testing.T) {
 			startRestServerMD: failedToStartRestServer,
 			wantErr:           true,
 		},
+		{
+			name:              "hangup signal reloads configuration",
+			startRestServerMD: successfulStartRestServer,
+			hangup:            true,
+			reloadMD:          successfulReload,
+			wantErr:           false,
+		},
+		{
+			name:              "rejected configuration reload on hangup signal keeps application running",
+			startRestServerMD: successfulStartRestServer,
+			hangup:            true,
+			reloadMD:          rejectedReload,
+			wantErr:           false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -129,8 +211,18 @@ func TestApp_Start(t *testing.T) {
 			restServer := mocks.NewMockRestServer(c)
 			restServer.EXPECT().Start().AnyTimes().Return(tt.startRestServerMD.err)
 
+			watching := make(chan struct{})
+			reloaded := make(chan struct{}, 1)
+			conf := mocks.NewMockConfigReloader(c)
+			conf.EXPECT().Watch(gomock.Any()).Times(1).Do(func(func(error)) { close(watching) })
+			conf.EXPECT().Reload().Times(tt.reloadMD.times).DoAndReturn(func() error {
+				reloaded <- struct{}{}
+				return tt.reloadMD.err
+			})
+
 			a := &App{
 				restServer: restServer,
+				conf:       conf,
 			}
 
 			appStartErrorChan := make(chan error, 1)
@@ -138,11 +230,33 @@ func TestApp_Start(t *testing.T) {
 				appStartErrorChan <- a.Start()
 			}()
 
+			if tt.hangup {
+				<-watching
+				if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+					t.Fatalf("Failed to send hangup signal: %v", err)
+				}
+
+				select {
+				case <-reloaded:
+				case <-time.After(3 * time.Second):
+					t.Fatalf("App.Start() did not reload configuration on hangup signal")
+				}
+			}
+
 			select {
 			case <-time.After(3 * time.Second):
 				if tt.wantErr {
 					t.Fatalf("App.Start() succssed, wantErr %v", tt.wantErr)
 				}
+
+				// stop the application, so it doesn't receive the signals of the next cases
+				if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+					t.Fatalf("Failed to send interrupt signal: %v", err)
+				}
+
+				if err := <-appStartErrorChan; err != nil {
+					t.Fatalf("App.Start() error = %v on interrupt signal", err)
+				}
 			case err := <-appStartErrorChan:
 				if (err != nil) != tt.wantErr {
 					t.Fatalf("App.Start() error = %v, wantErr %v", err, tt.wantErr)
@@ -2,42 +2,199 @@ package domain
 
 import (
 	"context"
+	"encoding/json"
+	"io/ioutil"
 
 	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/tenancy"
 	"microservice/models"
+
+	jsonpatch "github.com/evanphx/json-patch"
+)
+
+const (
+	// documentSchemaName is the name under which the document json schema is registered
+	documentSchemaName = "PostDocument"
+
+	// documentSchemaFile is the json schema the content of documents is validated against
+	documentSchemaFile = "api/postDocumentSchema.json"
 )
 
 // DocumentDB expose CRUD related operations for document
 type DocumentDB interface {
-	GetDocumentByID(ctx context.Context, id string, result interface{}) error
+	GetDocumentByID(ctx context.Context, id string, opts models.ReadOptions, result interface{}) error
+	GetDocumentByName(ctx context.Context, name string, result interface{}) error
 	SaveDocument(ctx context.Context, doc models.Document) (string, error)
+	SaveDocumentWithID(ctx context.Context, id string, doc models.Document) error
+	UpdateDocument(ctx context.Context, id string, doc models.Document) error
+	UpsertDocumentByName(ctx context.Context, doc models.Document) (string, bool, error)
+	CountDocuments(ctx context.Context) (int64, error)
+	SearchDocuments(ctx context.Context, query models.SearchQuery) ([]models.SearchResult, error)
 	Teardown(ctx context.Context) error
 }
 
+// QuotaProvider returns the resource quota of a tenant
+type QuotaProvider interface {
+	Quota(tenant string) models.TenantQuota
+}
+
+// IdempotencyStore records the idempotency keys of requests and the documents they created
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(ctx context.Context, key string, requestHash string) (models.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, key string, documentID string) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+// JSONSchemaValidator registers json schemas and validates json input against them
+type JSONSchemaValidator interface {
+	SetSchemaFromBytes(name string, inputJSON []byte) error
+	ValidateSchemaFromBytes(name string, inputJSON []byte) error
+}
+
 // Domain implement a Domain Service
 type Domain struct {
-	db DocumentDB
+	db         DocumentDB
+	jsonSchema JSONSchemaValidator
+	quotas     QuotaProvider
+	idem       IdempotencyStore
 }
 
-// NewDomain returns a new instance of the Domain struct
-func NewDomain(db DocumentDB) (*Domain, error) {
+// documentBody is the json representation of a document as accepted by the api
+type documentBody struct {
+	Name string                 `json:"name"`
+	Doc  map[string]interface{} `json:"doc"`
+}
+
+// NewDomain returns a new instance of the Domain struct.
+// It registers the document schema it validates patched documents against, so it works whichever driver calls it
+func NewDomain(db DocumentDB, js JSONSchemaValidator, quotas QuotaProvider, idem IdempotencyStore) (*Domain, error) {
+	schema, err := ioutil.ReadFile(documentSchemaFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read document schema")
+	}
+
+	if err := js.SetSchemaFromBytes(documentSchemaName, schema); err != nil {
+		return nil, errors.Wrap(err, "Failed to set document schema")
+	}
+
 	return &Domain{
-		db: db,
+		db:         db,
+		jsonSchema: js,
+		quotas:     quotas,
+		idem:       idem,
 	}, nil
 }
 
-// GetDocument gets an id and return the document of that id
-func (d *Domain) GetDocument(ctx context.Context, id string) (models.Document, error) {
+// GetDocument gets an id and return the document of that id, with only the fields selected by opts
+func (d *Domain) GetDocument(ctx context.Context, id string, opts models.ReadOptions) (models.Document, error) {
+	if _, err := authorize(ctx, actionRead, nil); err != nil {
+		return models.Document{}, err
+	}
+
+	if _, err := tenantFromContext(ctx); err != nil {
+		return models.Document{}, err
+	}
+
+	opts, err := validateReadOptions(opts)
+	if err != nil {
+		return models.Document{}, err
+	}
+
 	var doc models.Document
-	if err := d.db.GetDocumentByID(ctx, id, &doc); err != nil {
+	if err := d.db.GetDocumentByID(ctx, id, opts, &doc); err != nil {
 		return models.Document{}, errors.Wrapf(err, "Failed to get document by id (%s) from DocumentDB", id)
 	}
 
 	return doc, nil
 }
 
-// AddDocument gets a document, save it to the document db and return id of that document for further queries
-func (d *Domain) AddDocument(ctx context.Context, doc models.Document) (string, error) {
+// GetDocumentByName gets a name and return the document of that name
+func (d *Domain) GetDocumentByName(ctx context.Context, name string) (models.Document, error) {
+	if _, err := authorize(ctx, actionRead, nil); err != nil {
+		return models.Document{}, err
+	}
+
+	if _, err := tenantFromContext(ctx); err != nil {
+		return models.Document{}, err
+	}
+
+	var doc models.Document
+	if err := d.db.GetDocumentByName(ctx, name, &doc); err != nil {
+		return models.Document{}, errors.Wrapf(err, "Failed to get document by name (%s) from DocumentDB", name)
+	}
+
+	return doc, nil
+}
+
+// SearchDocuments returns the documents that match query, the most relevant to its text first
+func (d *Domain) SearchDocuments(ctx context.Context, query models.SearchQuery) ([]models.SearchResult, error) {
+	if _, err := authorize(ctx, actionRead, nil); err != nil {
+		return nil, err
+	}
+
+	if _, err := tenantFromContext(ctx); err != nil {
+		return nil, err
+	}
+
+	query, err := validateSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := d.db.SearchDocuments(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to search documents in DocumentDB")
+	}
+
+	return results, nil
+}
+
+// AddDocument gets a document, save it to the document db and return id of that document for further queries.
+// When an idempotency key is given, a replay of the same request returns the id of the document it created
+func (d *Domain) AddDocument(ctx context.Context, doc models.Document, idempotencyKey string) (string, error) {
+	p, err := authorize(ctx, actionCreate, nil)
+	if err != nil {
+		return "", err
+	}
+
+	tenant, err := tenantFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if idempotencyKey == "" {
+		return d.saveDocument(ctx, tenant, p, doc)
+	}
+
+	id, reserved, err := d.reserveIdempotencyKey(ctx, idempotencyKey, p, doc)
+	if err != nil || !reserved {
+		return id, err
+	}
+
+	id, err = d.saveDocument(ctx, tenant, p, doc)
+	if err != nil {
+		if releaseErr := d.idem.ReleaseIdempotencyKey(ctx, idempotencyKey); releaseErr != nil {
+			return "", errors.Wrapf(err, "Failed to release idempotency key (%s): %s", idempotencyKey, releaseErr)
+		}
+
+		return "", err
+	}
+
+	d.completeIdempotencyKey(ctx, idempotencyKey, id)
+
+	return id, nil
+}
+
+func (d *Domain) saveDocument(ctx context.Context, tenant string, p models.Principal, doc models.Document) (string, error) {
+	if err := checkLimits(doc); err != nil {
+		return "", err
+	}
+
+	if err := d.checkQuota(ctx, tenant, doc, true); err != nil {
+		return "", err
+	}
+
+	doc.CreatedBy = p.Subject
 	id, err := d.db.SaveDocument(ctx, doc)
 	if err != nil {
 		return "", errors.Wrapf(err, "Failed save document (%v) in DocumentDB", doc)
@@ -46,6 +203,239 @@ func (d *Domain) AddDocument(ctx context.Context, doc models.Document) (string,
 	return id, nil
 }
 
+// PutDocument creates the document under an id chosen by the caller, or replaces the document of that id if it exists.
+// It returns true if the document was created
+func (d *Domain) PutDocument(ctx context.Context, id string, doc models.Document) (bool, error) {
+	p, err := authorize(ctx, actionCreate, nil)
+	if err != nil {
+		return false, err
+	}
+
+	tenant, err := tenantFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := checkLimits(doc); err != nil {
+		return false, err
+	}
+
+	// the version of the existing document is checked on replace, so it can't come from the cache
+	var existing models.Document
+	err = d.db.GetDocumentByID(ctx, id, models.ReadOptions{Fresh: true}, &existing)
+	if errors.IsType(err, errors.ErrorTypeNotFound) {
+		if err := d.checkQuota(ctx, tenant, doc, true); err != nil {
+			return false, err
+		}
+
+		doc.CreatedBy = p.Subject
+		if err := d.db.SaveDocumentWithID(ctx, id, doc); err != nil {
+			return false, errors.Wrapf(err, "Failed to save document with id (%s) in DocumentDB", id)
+		}
+
+		return true, nil
+	} else if err != nil {
+		return false, errors.Wrapf(err, "Failed to get document by id (%s) from DocumentDB", id)
+	}
+
+	if _, err := authorize(ctx, actionUpdate, &existing); err != nil {
+		return false, err
+	}
+
+	replaced := models.Document{
+		Name:      doc.Name,
+		Doc:       doc.Doc,
+		CreatedBy: existing.CreatedBy,
+		Version:   existing.Version,
+	}
+
+	if err := d.checkQuota(ctx, tenant, replaced, false); err != nil {
+		return false, err
+	}
+
+	if err := d.db.UpdateDocument(ctx, id, replaced); err != nil {
+		return false, errors.Wrapf(err, "Failed to replace document with id (%s) in DocumentDB", id)
+	}
+
+	return false, nil
+}
+
+// UpsertDocument stores doc as the latest document of its name, replacing the content of an existing document
+// of that name. It returns the id of the document and true if the document was created
+func (d *Domain) UpsertDocument(ctx context.Context, doc models.Document) (string, bool, error) {
+	p, err := authorize(ctx, actionCreate, nil)
+	if err != nil {
+		return "", false, err
+	}
+
+	tenant, err := tenantFromContext(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	if err := checkLimits(doc); err != nil {
+		return "", false, err
+	}
+
+	var existing models.Document
+	err = d.db.GetDocumentByName(ctx, doc.Name, &existing)
+	exists := err == nil
+	if err != nil && !errors.IsType(err, errors.ErrorTypeNotFound) {
+		return "", false, errors.Wrapf(err, "Failed to get document by name (%s) from DocumentDB", doc.Name)
+	}
+
+	if exists {
+		if _, err := authorize(ctx, actionUpdate, &existing); err != nil {
+			return "", false, err
+		}
+	}
+
+	if err := d.checkQuota(ctx, tenant, doc, !exists); err != nil {
+		return "", false, err
+	}
+
+	doc.CreatedBy = p.Subject
+	id, created, err := d.db.UpsertDocumentByName(ctx, doc)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "Failed to upsert document with name (%s) in DocumentDB", doc.Name)
+	}
+
+	return id, created, nil
+}
+
+// PatchDocument applies a patch to the document of the given id, validates the result and saves it.
+// The update fails with a conflict error if the document was changed since it was read
+func (d *Domain) PatchDocument(ctx context.Context, id string, patchType models.PatchType, patch []byte) (models.Document, error) {
+	if _, err := authorize(ctx, actionUpdate, nil); err != nil {
+		return models.Document{}, err
+	}
+
+	tenant, err := tenantFromContext(ctx)
+	if err != nil {
+		return models.Document{}, err
+	}
+
+	// the patch applies to the current version of the document, so it can't come from the cache
+	var doc models.Document
+	if err := d.db.GetDocumentByID(ctx, id, models.ReadOptions{Fresh: true}, &doc); err != nil {
+		return models.Document{}, errors.Wrapf(err, "Failed to get document by id (%s) from DocumentDB", id)
+	}
+
+	if _, err := authorize(ctx, actionUpdate, &doc); err != nil {
+		return models.Document{}, err
+	}
+
+	original, err := json.Marshal(documentBody{Name: doc.Name, Doc: doc.Doc})
+	if err != nil {
+		return models.Document{}, errors.Wrapf(err, "Failed to marshal document with id (%s)", id).SetType(errors.ErrorTypeInternal)
+	}
+
+	patched, err := applyPatch(patchType, original, patch)
+	if err != nil {
+		return models.Document{}, err
+	}
+
+	if err := d.jsonSchema.ValidateSchemaFromBytes(documentSchemaName, patched); err != nil {
+		if errors.IsType(err, errors.ErrorTypeBadRequest) {
+			return models.Document{}, errors.Errorf("Patched document is invalid: %s", err).SetType(errors.ErrorTypeUnprocessable)
+		}
+
+		return models.Document{}, errors.Wrap(err, "Failed to validate patched document")
+	}
+
+	var body documentBody
+	if err := json.Unmarshal(patched, &body); err != nil {
+		return models.Document{}, errors.Wrap(err, "Failed to unmarshal patched document").SetType(errors.ErrorTypeInternal)
+	}
+
+	updated := models.Document{
+		Name:      body.Name,
+		Doc:       body.Doc,
+		CreatedBy: doc.CreatedBy,
+		Version:   doc.Version,
+	}
+
+	if err := checkLimits(updated); err != nil {
+		return models.Document{}, err
+	}
+
+	if err := d.checkQuota(ctx, tenant, updated, false); err != nil {
+		return models.Document{}, err
+	}
+
+	if err := d.db.UpdateDocument(ctx, id, updated); err != nil {
+		return models.Document{}, errors.Wrapf(err, "Failed to update document with id (%s) in DocumentDB", id)
+	}
+
+	updated.Version++
+	return updated, nil
+}
+
+// checkQuota verifies that storing doc keeps the tenant within its quota.
+// The document count is checked before insertion, so concurrent inserts may overshoot the limit slightly
+func (d *Domain) checkQuota(ctx context.Context, tenant string, doc models.Document, isNew bool) error {
+	quota := d.quotas.Quota(tenant)
+
+	if quota.MaxDocumentBytes > 0 {
+		b, err := json.Marshal(documentBody{Name: doc.Name, Doc: doc.Doc})
+		if err != nil {
+			return errors.Wrap(err, "Failed to marshal document").SetType(errors.ErrorTypeInternal)
+		}
+
+		if int64(len(b)) > quota.MaxDocumentBytes {
+			return errors.Errorf("Document size (%d bytes) exceeds the quota of tenant (%s) of %d bytes", len(b), tenant, quota.MaxDocumentBytes).SetType(errors.ErrorTypeQuotaExceeded)
+		}
+	}
+
+	if isNew && quota.MaxDocuments > 0 {
+		count, err := d.db.CountDocuments(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to count documents of tenant (%s) in DocumentDB", tenant)
+		}
+
+		if count >= quota.MaxDocuments {
+			return errors.Errorf("Tenant (%s) reached its quota of %d documents", tenant, quota.MaxDocuments).SetType(errors.ErrorTypeQuotaExceeded)
+		}
+	}
+
+	return nil
+}
+
+func tenantFromContext(ctx context.Context) (string, error) {
+	tenant, ok := tenancy.FromContext(ctx)
+	if !ok {
+		return "", errors.New("Request has no tenant").SetType(errors.ErrorTypeBadRequest)
+	}
+
+	return tenant, nil
+}
+
+func applyPatch(patchType models.PatchType, original []byte, patch []byte) ([]byte, error) {
+	switch patchType {
+	case models.PatchTypeJSONPatch:
+		p, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, errors.Errorf("Invalid json patch: %s", err).SetType(errors.ErrorTypeBadRequest)
+		}
+
+		patched, err := p.Apply(original)
+		if err != nil {
+			return nil, errors.Errorf("Failed to apply json patch: %s", err).SetType(errors.ErrorTypeUnprocessable)
+		}
+
+		return patched, nil
+	case models.PatchTypeMergePatch:
+		patched, err := jsonpatch.MergePatch(original, patch)
+		if err != nil {
+			return nil, errors.Errorf("Invalid json merge patch: %s", err).SetType(errors.ErrorTypeBadRequest)
+		}
+
+		return patched, nil
+	default:
+		return nil, errors.Errorf("Unsupported patch type (%s)", patchType).SetType(errors.ErrorTypeBadRequest)
+	}
+}
+
 // Teardown closes every open connection of the domain
 func (d *Domain) Teardown(ctx context.Context) error {
 	if err := d.db.Teardown(ctx); err != nil {
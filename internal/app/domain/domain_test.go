@@ -2,10 +2,16 @@ package domain
 
 import (
 	"context"
+	"os"
+	"path"
 	"reflect"
+	"runtime"
 	"testing"
+	"time"
 
+	"microservice/internal/pkg/auth"
 	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/tenancy"
 	"microservice/mocks"
 	"microservice/models"
 
@@ -19,31 +25,695 @@ func TestDomain_AddDocument(t *testing.T) {
 		err   error
 	}
 
+	type dbCountDocumentsMockData struct {
+		times int
+		count int64
+		err   error
+	}
+
 	successfulAddDocument := dbAddDocumentMockData{
 		times: 1,
 		err:   nil,
 	}
 
-	failedToAddDocument := dbAddDocumentMockData{
+	failedToAddDocument := dbAddDocumentMockData{
+		times: 1,
+		err:   errors.New("some-error"),
+	}
+
+	belowDocumentsQuota := dbCountDocumentsMockData{
+		times: 1,
+		count: 9,
+	}
+
+	reachedDocumentsQuota := dbCountDocumentsMockData{
+		times: 1,
+		count: 10,
+	}
+
+	failedToCountDocuments := dbCountDocumentsMockData{
+		times: 1,
+		err:   errors.New("some-error"),
+	}
+
+	tests := []struct {
+		name             string
+		quota            models.TenantQuota
+		countDocumentsMD dbCountDocumentsMockData
+		addDocumentMD    dbAddDocumentMockData
+		wantErrType      errors.ErrorType
+		wantErr          bool
+	}{
+		{
+			name:          "successful add document tp db expect no error",
+			addDocumentMD: successfulAddDocument,
+			wantErr:       false,
+		},
+		{
+			name:          "failed to add document to db expect error",
+			addDocumentMD: failedToAddDocument,
+			wantErr:       true,
+		},
+		{
+			name:             "tenant below documents quota expect no error",
+			quota:            models.TenantQuota{MaxDocuments: 10},
+			countDocumentsMD: belowDocumentsQuota,
+			addDocumentMD:    successfulAddDocument,
+			wantErr:          false,
+		},
+		{
+			name:             "tenant reached documents quota expect quota exceeded error",
+			quota:            models.TenantQuota{MaxDocuments: 10},
+			countDocumentsMD: reachedDocumentsQuota,
+			wantErrType:      errors.ErrorTypeQuotaExceeded,
+			wantErr:          true,
+		},
+		{
+			name:             "failed to count documents of tenant expect error",
+			quota:            models.TenantQuota{MaxDocuments: 10},
+			countDocumentsMD: failedToCountDocuments,
+			wantErrType:      errors.ErrorTypeUnknown,
+			wantErr:          true,
+		},
+		{
+			name:        "document larger than tenant quota expect quota exceeded error",
+			quota:       models.TenantQuota{MaxDocumentBytes: 10},
+			wantErrType: errors.ErrorTypeQuotaExceeded,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			id := uuid.New().String()
+
+			db := mocks.NewMockDocumentDB(c)
+			db.EXPECT().CountDocuments(gomock.Any()).
+				Times(tt.countDocumentsMD.times).
+				Return(tt.countDocumentsMD.count, tt.countDocumentsMD.err)
+			db.EXPECT().SaveDocument(gomock.Any(), gomock.AssignableToTypeOf(models.Document{})).
+				Times(tt.addDocumentMD.times).
+				Do(func(_ interface{}, doc models.Document) {
+					if doc.CreatedBy != "tamir" {
+						t.Errorf("SaveDocument() createdBy = %s, want %s", doc.CreatedBy, "tamir")
+					}
+				}).
+				Return(id, tt.addDocumentMD.err)
+
+			quotas := mocks.NewMockQuotaProvider(c)
+			quotas.EXPECT().Quota(testTenant).AnyTimes().Return(tt.quota)
+
+			d := &Domain{
+				db:     db,
+				quotas: quotas,
+			}
+
+			docToAdd := models.Document{
+				Name: "tamir",
+				Doc: map[string]interface{}{
+					"key": "value",
+				},
+			}
+
+			got, err := d.AddDocument(contextWithPrincipal("tamir", RoleWriter), docToAdd, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AddDocument() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				if !errors.IsType(err, tt.wantErrType) {
+					t.Errorf("AddDocument() error = %v, want error type %v", err, tt.wantErrType)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(got, id) {
+				t.Errorf("AddDocument() got = %v, want %v", got, id)
+			}
+		})
+	}
+}
+
+func TestDomain_AddDocumentWithIdempotencyKey(t *testing.T) {
+	type idemReserveMockData struct {
+		times    int
+		record   models.IdempotencyRecord
+		reserved bool
+		err      error
+	}
+
+	type idemMockData struct {
+		times int
+		err   error
+	}
+
+	type idemCompleteMockData struct {
+		times int
+		// failures are the number of attempts that fail before one succeeds
+		failures int
+	}
+
+	type dbAddDocumentMockData struct {
+		times int
+		err   error
+	}
+
+	const (
+		key        = "key-1"
+		existingID = "existing-id"
+	)
+
+	docToAdd := models.Document{
+		Name: "tamir",
+		Doc: map[string]interface{}{
+			"key": "value",
+		},
+	}
+
+	hash, err := requestHash(models.Principal{Subject: "tamir"}, docToAdd)
+	if err != nil {
+		t.Fatalf("requestHash() error = %v", err)
+	}
+
+	reservedKey := idemReserveMockData{
+		times:    1,
+		reserved: true,
+	}
+
+	replayedKey := idemReserveMockData{
+		times:  1,
+		record: models.IdempotencyRecord{Key: key, RequestHash: hash, DocumentID: existingID},
+	}
+
+	reusedKey := idemReserveMockData{
+		times:  1,
+		record: models.IdempotencyRecord{Key: key, RequestHash: "other-hash", DocumentID: existingID},
+	}
+
+	inProgressKey := idemReserveMockData{
+		times:  1,
+		record: models.IdempotencyRecord{Key: key, RequestHash: hash},
+	}
+
+	failedToReserveKey := idemReserveMockData{
+		times: 1,
+		err:   errors.New("some-error"),
+	}
+
+	successfulIdem := idemMockData{
+		times: 1,
+	}
+
+	successfulComplete := idemCompleteMockData{
+		times: 1,
+	}
+
+	retriedComplete := idemCompleteMockData{
+		times:    2,
+		failures: 1,
+	}
+
+	failedToComplete := idemCompleteMockData{
+		times:    completeAttempts,
+		failures: completeAttempts,
+	}
+
+	successfulAddDocument := dbAddDocumentMockData{
+		times: 1,
+	}
+
+	failedToAddDocument := dbAddDocumentMockData{
+		times: 1,
+		err:   errors.New("some-error"),
+	}
+
+	tests := []struct {
+		name          string
+		reserveMD     idemReserveMockData
+		addDocumentMD dbAddDocumentMockData
+		completeMD    idemCompleteMockData
+		releaseMD     idemMockData
+		wantID        string
+		wantErrType   errors.ErrorType
+		wantErr       bool
+	}{
+		{
+			name:          "new idempotency key expect document saved and key completed",
+			reserveMD:     reservedKey,
+			addDocumentMD: successfulAddDocument,
+			completeMD:    successfulComplete,
+			wantErr:       false,
+		},
+		{
+			name:          "failed to complete idempotency key once expect completion retried and id returned",
+			reserveMD:     reservedKey,
+			addDocumentMD: successfulAddDocument,
+			completeMD:    retriedComplete,
+			wantErr:       false,
+		},
+		{
+			name:          "failed to complete idempotency key on every attempt expect id of the saved document",
+			reserveMD:     reservedKey,
+			addDocumentMD: successfulAddDocument,
+			completeMD:    failedToComplete,
+			wantErr:       false,
+		},
+		{
+			name:      "replayed idempotency key expect id of the original document",
+			reserveMD: replayedKey,
+			wantID:    existingID,
+			wantErr:   false,
+		},
+		{
+			name:        "idempotency key reused with different document expect unprocessable error",
+			reserveMD:   reusedKey,
+			wantErrType: errors.ErrorTypeUnprocessable,
+			wantErr:     true,
+		},
+		{
+			name:        "idempotency key of request in progress expect conflict error",
+			reserveMD:   inProgressKey,
+			wantErrType: errors.ErrorTypeConflict,
+			wantErr:     true,
+		},
+		{
+			name:        "failed to reserve idempotency key expect error",
+			reserveMD:   failedToReserveKey,
+			wantErrType: errors.ErrorTypeUnknown,
+			wantErr:     true,
+		},
+		{
+			name:          "failed to add document expect idempotency key released",
+			reserveMD:     reservedKey,
+			addDocumentMD: failedToAddDocument,
+			releaseMD:     successfulIdem,
+			wantErrType:   errors.ErrorTypeUnknown,
+			wantErr:       true,
+		},
+	}
+	defer func(delay time.Duration) { completeRetryDelay = delay }(completeRetryDelay)
+	completeRetryDelay = 0
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			id := uuid.New().String()
+
+			db := mocks.NewMockDocumentDB(c)
+			db.EXPECT().SaveDocument(gomock.Any(), gomock.AssignableToTypeOf(models.Document{})).
+				Times(tt.addDocumentMD.times).
+				Return(id, tt.addDocumentMD.err)
+
+			quotas := mocks.NewMockQuotaProvider(c)
+			quotas.EXPECT().Quota(testTenant).AnyTimes().Return(models.TenantQuota{})
+
+			idem := mocks.NewMockIdempotencyStore(c)
+			idem.EXPECT().ReserveIdempotencyKey(gomock.Any(), key, hash).
+				Times(tt.reserveMD.times).
+				Return(tt.reserveMD.record, tt.reserveMD.reserved, tt.reserveMD.err)
+			attempts := 0
+			idem.EXPECT().CompleteIdempotencyKey(gomock.Any(), key, id).
+				Times(tt.completeMD.times).
+				DoAndReturn(func(context.Context, string, string) error {
+					attempts++
+					if attempts <= tt.completeMD.failures {
+						return errors.New("some-error")
+					}
+					return nil
+				})
+			idem.EXPECT().ReleaseIdempotencyKey(gomock.Any(), key).
+				Times(tt.releaseMD.times).
+				Return(tt.releaseMD.err)
+
+			d := &Domain{
+				db:     db,
+				quotas: quotas,
+				idem:   idem,
+			}
+
+			got, err := d.AddDocument(contextWithPrincipal("tamir", RoleWriter), docToAdd, key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AddDocument() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				if !errors.IsType(err, tt.wantErrType) {
+					t.Errorf("AddDocument() error = %v, want error type %v", err, tt.wantErrType)
+				}
+				return
+			}
+
+			want := id
+			if tt.wantID != "" {
+				want = tt.wantID
+			}
+
+			if got != want {
+				t.Errorf("AddDocument() got = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDomain_GetDocument(t *testing.T) {
+	type dbGetDocumentMockData struct {
+		times int
+		err   error
+	}
+
+	successfulGetDocument := dbGetDocumentMockData{
+		times: 1,
+		err:   nil,
+	}
+
+	failedToGetDocument := dbGetDocumentMockData{
+		times: 1,
+		err:   errors.New("some-error"),
+	}
+
+	notCalled := dbGetDocumentMockData{
+		times: 0,
+	}
+
+	tests := []struct {
+		name          string
+		getDocumentMD dbGetDocumentMockData
+		opts          models.ReadOptions
+		wantOpts      models.ReadOptions
+		wantErr       bool
+	}{
+		{
+			name:          "successful get document from db expect no error",
+			getDocumentMD: successfulGetDocument,
+			wantErr:       false,
+		},
+		{
+			name:          "successful get selected fields from db expect fields passed in stored case",
+			getDocumentMD: successfulGetDocument,
+			opts:          models.ReadOptions{Fields: []string{"Name", "doc.address.city"}},
+			wantOpts:      models.ReadOptions{Fields: []string{"name", "doc.address.city"}},
+			wantErr:       false,
+		},
+		{
+			name:          "successful get excluding fields from db expect excluded fields passed",
+			getDocumentMD: successfulGetDocument,
+			opts:          models.ReadOptions{Exclude: []string{"doc.secret"}},
+			wantOpts:      models.ReadOptions{Exclude: []string{"doc.secret"}},
+			wantErr:       false,
+		},
+		{
+			name:          "successful fresh get of selected fields expect fresh read passed",
+			getDocumentMD: successfulGetDocument,
+			opts:          models.ReadOptions{Fields: []string{"name"}, Fresh: true},
+			wantOpts:      models.ReadOptions{Fields: []string{"name"}, Fresh: true},
+			wantErr:       false,
+		},
+		{
+			name:          "failed to get document from db expect error",
+			getDocumentMD: failedToGetDocument,
+			wantErr:       true,
+		},
+		{
+			name:          "fields both selected and excluded expect bad request error",
+			getDocumentMD: notCalled,
+			opts:          models.ReadOptions{Fields: []string{"name"}, Exclude: []string{"doc.secret"}},
+			wantErr:       true,
+		},
+		{
+			name:          "field path with an empty key expect bad request error",
+			getDocumentMD: notCalled,
+			opts:          models.ReadOptions{Fields: []string{"doc..city"}},
+			wantErr:       true,
+		},
+		{
+			name:          "field path with an operator key expect bad request error",
+			getDocumentMD: notCalled,
+			opts:          models.ReadOptions{Fields: []string{"doc.$where"}},
+			wantErr:       true,
+		},
+		{
+			name:          "field path outside the document expect bad request error",
+			getDocumentMD: notCalled,
+			opts:          models.ReadOptions{Fields: []string{"tenant"}},
+			wantErr:       true,
+		},
+		{
+			name:          "overlapping field paths expect bad request error",
+			getDocumentMD: notCalled,
+			opts:          models.ReadOptions{Exclude: []string{"doc.address", "doc.address.city"}},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			id := uuid.New().String()
+			docToReturn := models.Document{
+				Name: "tamir",
+				Doc: map[string]interface{}{
+					"key": "value",
+				},
+			}
+			db := mocks.NewMockDocumentDB(c)
+			db.EXPECT().GetDocumentByID(gomock.Any(), id, gomock.Any(), gomock.AssignableToTypeOf(&models.Document{})).
+				Times(tt.getDocumentMD.times).
+				Do(func(_ interface{}, _ interface{}, opts models.ReadOptions, doc *models.Document) {
+					if !reflect.DeepEqual(opts, tt.wantOpts) {
+						t.Errorf("GetDocumentByID() opts = %v, want %v", opts, tt.wantOpts)
+					}
+					*doc = docToReturn
+				}).
+				Return(tt.getDocumentMD.err)
+
+			d := &Domain{
+				db: db,
+			}
+
+			got, err := d.GetDocument(contextWithPrincipal("tamir", RoleReader), id, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetDocument() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				if tt.getDocumentMD.times == 0 && !errors.IsType(err, errors.ErrorTypeBadRequest) {
+					t.Errorf("GetDocument() error = %v, want bad request error", err)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(got, docToReturn) {
+				t.Errorf("GetDocument() got = %v, want %v", got, docToReturn)
+			}
+		})
+	}
+}
+
+func TestDomain_GetDocumentByName(t *testing.T) {
+	type dbGetDocumentMockData struct {
+		times int
+		err   error
+	}
+
+	successfulGetDocument := dbGetDocumentMockData{
+		times: 1,
+		err:   nil,
+	}
+
+	documentNotFound := dbGetDocumentMockData{
+		times: 1,
+		err:   errors.New("not-found").SetType(errors.ErrorTypeNotFound),
+	}
+
+	tests := []struct {
+		name          string
+		getDocumentMD dbGetDocumentMockData
+		wantErrType   errors.ErrorType
+		wantErr       bool
+	}{
+		{
+			name:          "successful get document by name from db expect no error",
+			getDocumentMD: successfulGetDocument,
+			wantErr:       false,
+		},
+		{
+			name:          "name doesn't exist in db expect not found error",
+			getDocumentMD: documentNotFound,
+			wantErrType:   errors.ErrorTypeNotFound,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			docToReturn := models.Document{
+				Name: "tamir",
+				Doc: map[string]interface{}{
+					"key": "value",
+				},
+			}
+			db := mocks.NewMockDocumentDB(c)
+			db.EXPECT().GetDocumentByName(gomock.Any(), docToReturn.Name, gomock.AssignableToTypeOf(&models.Document{})).
+				Times(tt.getDocumentMD.times).
+				Do(func(_ interface{}, _ interface{}, doc *models.Document) {
+					*doc = docToReturn
+				}).
+				Return(tt.getDocumentMD.err)
+
+			d := &Domain{
+				db: db,
+			}
+
+			got, err := d.GetDocumentByName(contextWithPrincipal("tamir", RoleReader), docToReturn.Name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetDocumentByName() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				if !errors.IsType(err, tt.wantErrType) {
+					t.Errorf("GetDocumentByName() error = %v, want error type %v", err, tt.wantErrType)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(got, docToReturn) {
+				t.Errorf("GetDocumentByName() got = %v, want %v", got, docToReturn)
+			}
+		})
+	}
+}
+
+func TestDomain_UpsertDocument(t *testing.T) {
+	type dbGetDocumentMockData struct {
+		times int
+		err   error
+	}
+
+	type dbUpsertDocumentMockData struct {
+		times   int
+		created bool
+		err     error
+	}
+
+	storedDoc := models.Document{
+		Name:      "tamir",
+		Doc:       map[string]interface{}{"city": "Tel Aviv"},
+		CreatedBy: "tamir",
+	}
+
+	docToUpsert := models.Document{
+		Name: "tamir",
+		Doc:  map[string]interface{}{"city": "Haifa"},
+	}
+
+	owner := models.Principal{Subject: "tamir", Roles: []string{RoleWriter}}
+	otherWriter := models.Principal{Subject: "someone-else", Roles: []string{RoleWriter}}
+	reader := models.Principal{Subject: "tamir", Roles: []string{RoleReader}}
+
+	existingDocument := dbGetDocumentMockData{
+		times: 1,
+		err:   nil,
+	}
+
+	missingDocument := dbGetDocumentMockData{
+		times: 1,
+		err:   errors.New("not-found").SetType(errors.ErrorTypeNotFound),
+	}
+
+	failedToGetDocument := dbGetDocumentMockData{
 		times: 1,
 		err:   errors.New("some-error"),
 	}
 
+	createdDocument := dbUpsertDocumentMockData{
+		times:   1,
+		created: true,
+	}
+
+	updatedDocument := dbUpsertDocumentMockData{
+		times:   1,
+		created: false,
+	}
+
+	failedToUpsertDocument := dbUpsertDocumentMockData{
+		times: 1,
+		err:   errors.New("conflict").SetType(errors.ErrorTypeConflict),
+	}
+
 	tests := []struct {
-		name          string
-		addDocumentMD dbAddDocumentMockData
-		wantErr       bool
+		name             string
+		principal        models.Principal
+		quota            models.TenantQuota
+		getDocumentMD    dbGetDocumentMockData
+		upsertDocumentMD dbUpsertDocumentMockData
+		wantCreated      bool
+		wantErrType      errors.ErrorType
+		wantErr          bool
 	}{
 		{
-			name:          "successful add document tp db expect no error",
-			addDocumentMD: successfulAddDocument,
-			wantErr:       false,
+			name:             "upsert new name expect document created",
+			principal:        owner,
+			getDocumentMD:    missingDocument,
+			upsertDocumentMD: createdDocument,
+			wantCreated:      true,
+			wantErr:          false,
 		},
 		{
-			name:          "failed to add document to db expect error",
-			addDocumentMD: failedToAddDocument,
+			name:             "upsert existing name expect document updated",
+			principal:        owner,
+			getDocumentMD:    existingDocument,
+			upsertDocumentMD: updatedDocument,
+			wantCreated:      false,
+			wantErr:          false,
+		},
+		{
+			name:             "upsert existing name without counting documents expect no quota error",
+			principal:        owner,
+			quota:            models.TenantQuota{MaxDocuments: 1},
+			getDocumentMD:    existingDocument,
+			upsertDocumentMD: updatedDocument,
+			wantCreated:      false,
+			wantErr:          false,
+		},
+		{
+			name:          "writer upserts document of another principal expect forbidden error",
+			principal:     otherWriter,
+			getDocumentMD: existingDocument,
+			wantErrType:   errors.ErrorTypeForbidden,
 			wantErr:       true,
 		},
+		{
+			name:        "reader upserts document expect forbidden error",
+			principal:   reader,
+			wantErrType: errors.ErrorTypeForbidden,
+			wantErr:     true,
+		},
+		{
+			name:          "failed to get document by name expect error",
+			principal:     owner,
+			getDocumentMD: failedToGetDocument,
+			wantErrType:   errors.ErrorTypeUnknown,
+			wantErr:       true,
+		},
+		{
+			name:             "failed to upsert document expect conflict error",
+			principal:        owner,
+			getDocumentMD:    missingDocument,
+			upsertDocumentMD: failedToUpsertDocument,
+			wantErrType:      errors.ErrorTypeConflict,
+			wantErr:          true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -53,42 +723,93 @@ func TestDomain_AddDocument(t *testing.T) {
 			id := uuid.New().String()
 
 			db := mocks.NewMockDocumentDB(c)
-			db.EXPECT().SaveDocument(gomock.Any(), gomock.AssignableToTypeOf(models.Document{})).Times(tt.addDocumentMD.times).Return(id, tt.addDocumentMD.err)
+			db.EXPECT().GetDocumentByName(gomock.Any(), docToUpsert.Name, gomock.AssignableToTypeOf(&models.Document{})).
+				Times(tt.getDocumentMD.times).
+				Do(func(_ interface{}, _ interface{}, doc *models.Document) {
+					*doc = storedDoc
+				}).
+				Return(tt.getDocumentMD.err)
+			db.EXPECT().UpsertDocumentByName(gomock.Any(), gomock.AssignableToTypeOf(models.Document{})).
+				Times(tt.upsertDocumentMD.times).
+				Do(func(_ interface{}, doc models.Document) {
+					if doc.CreatedBy != tt.principal.Subject {
+						t.Errorf("UpsertDocumentByName() createdBy = %s, want %s", doc.CreatedBy, tt.principal.Subject)
+					}
+				}).
+				Return(id, tt.upsertDocumentMD.created, tt.upsertDocumentMD.err)
 
-			d := &Domain{
-				db: db,
-			}
+			quotas := mocks.NewMockQuotaProvider(c)
+			quotas.EXPECT().Quota(testTenant).AnyTimes().Return(tt.quota)
 
-			docToAdd := models.Document{
-				Name: "tamir",
-				Doc: map[string]interface{}{
-					"key": "value",
-				},
+			d := &Domain{
+				db:     db,
+				quotas: quotas,
 			}
 
-			got, err := d.AddDocument(context.TODO(), docToAdd)
+			ctx := tenancy.NewContext(auth.NewContext(context.TODO(), tt.principal), testTenant)
+			gotID, gotCreated, err := d.UpsertDocument(ctx, docToUpsert)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("AddDocument() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("UpsertDocument() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
 			if tt.wantErr {
+				if !errors.IsType(err, tt.wantErrType) {
+					t.Errorf("UpsertDocument() error = %v, want error type %v", err, tt.wantErrType)
+				}
 				return
 			}
 
-			if !reflect.DeepEqual(got, id) {
-				t.Errorf("AddDocument() got = %v, want %v", got, id)
+			if gotID != id || gotCreated != tt.wantCreated {
+				t.Errorf("UpsertDocument() got = (%v, %v), want (%v, %v)", gotID, gotCreated, id, tt.wantCreated)
 			}
 		})
 	}
 }
 
-func TestDomain_GetDocument(t *testing.T) {
+func TestDomain_PatchDocument(t *testing.T) {
 	type dbGetDocumentMockData struct {
 		times int
 		err   error
 	}
 
+	type jsonSchemaValidatorMockData struct {
+		times int
+		err   error
+	}
+
+	type dbUpdateDocumentMockData struct {
+		times int
+		err   error
+	}
+
+	storedDoc := models.Document{
+		Name: "tamir",
+		Doc: map[string]interface{}{
+			"address": map[string]interface{}{
+				"city": "Tel Aviv",
+			},
+		},
+		CreatedBy: "tamir",
+		Version:   3,
+	}
+
+	patchedDoc := models.Document{
+		Name: "tamir",
+		Doc: map[string]interface{}{
+			"address": map[string]interface{}{
+				"city": "Haifa",
+			},
+		},
+		CreatedBy: "tamir",
+		Version:   4,
+	}
+
+	owner := models.Principal{Subject: "tamir", Roles: []string{RoleWriter}}
+	otherWriter := models.Principal{Subject: "someone-else", Roles: []string{RoleWriter}}
+	admin := models.Principal{Subject: "administrator", Roles: []string{RoleAdmin}}
+	reader := models.Principal{Subject: "tamir", Roles: []string{RoleReader}}
+
 	successfulGetDocument := dbGetDocumentMockData{
 		times: 1,
 		err:   nil,
@@ -96,24 +817,317 @@ func TestDomain_GetDocument(t *testing.T) {
 
 	failedToGetDocument := dbGetDocumentMockData{
 		times: 1,
-		err:   errors.New("some-error"),
+		err:   errors.New("not-found").SetType(errors.ErrorTypeNotFound),
+	}
+
+	validPatchedDocument := jsonSchemaValidatorMockData{
+		times: 1,
+		err:   nil,
+	}
+
+	invalidPatchedDocument := jsonSchemaValidatorMockData{
+		times: 1,
+		err:   errors.New("bad-request").SetType(errors.ErrorTypeBadRequest),
+	}
+
+	successfulUpdateDocument := dbUpdateDocumentMockData{
+		times: 1,
+		err:   nil,
+	}
+
+	conflictOnUpdateDocument := dbUpdateDocumentMockData{
+		times: 1,
+		err:   errors.New("conflict").SetType(errors.ErrorTypeConflict),
+	}
+
+	tests := []struct {
+		name             string
+		principal        models.Principal
+		patchType        models.PatchType
+		patch            string
+		getDocumentMD    dbGetDocumentMockData
+		jsonSchemaMD     jsonSchemaValidatorMockData
+		updateDocumentMD dbUpdateDocumentMockData
+		wantErrType      errors.ErrorType
+		wantErr          bool
+	}{
+		{
+			name:             "successful json patch expect no error",
+			principal:        owner,
+			patchType:        models.PatchTypeJSONPatch,
+			patch:            `[{"op": "replace", "path": "/doc/address/city", "value": "Haifa"}]`,
+			getDocumentMD:    successfulGetDocument,
+			jsonSchemaMD:     validPatchedDocument,
+			updateDocumentMD: successfulUpdateDocument,
+			wantErr:          false,
+		},
+		{
+			name:             "successful merge patch expect no error",
+			principal:        owner,
+			patchType:        models.PatchTypeMergePatch,
+			patch:            `{"doc": {"address": {"city": "Haifa"}}}`,
+			getDocumentMD:    successfulGetDocument,
+			jsonSchemaMD:     validPatchedDocument,
+			updateDocumentMD: successfulUpdateDocument,
+			wantErr:          false,
+		},
+		{
+			name:          "failed to get document from db expect not found error",
+			principal:     owner,
+			patchType:     models.PatchTypeJSONPatch,
+			patch:         `[]`,
+			getDocumentMD: failedToGetDocument,
+			wantErrType:   errors.ErrorTypeNotFound,
+			wantErr:       true,
+		},
+		{
+			name:          "malformed json patch expect bad request error",
+			principal:     owner,
+			patchType:     models.PatchTypeJSONPatch,
+			patch:         `{"op": "replace"}`,
+			getDocumentMD: successfulGetDocument,
+			wantErrType:   errors.ErrorTypeBadRequest,
+			wantErr:       true,
+		},
+		{
+			name:          "json patch on missing path expect unprocessable error",
+			principal:     owner,
+			patchType:     models.PatchTypeJSONPatch,
+			patch:         `[{"op": "replace", "path": "/doc/missing/city", "value": "Haifa"}]`,
+			getDocumentMD: successfulGetDocument,
+			wantErrType:   errors.ErrorTypeUnprocessable,
+			wantErr:       true,
+		},
+		{
+			name:          "failing json patch test operation expect unprocessable error",
+			principal:     owner,
+			patchType:     models.PatchTypeJSONPatch,
+			patch:         `[{"op": "test", "path": "/name", "value": "someone-else"}]`,
+			getDocumentMD: successfulGetDocument,
+			wantErrType:   errors.ErrorTypeUnprocessable,
+			wantErr:       true,
+		},
+		{
+			name:          "patched document violates json schema expect unprocessable error",
+			principal:     owner,
+			patchType:     models.PatchTypeMergePatch,
+			patch:         `{"doc": null}`,
+			getDocumentMD: successfulGetDocument,
+			jsonSchemaMD:  invalidPatchedDocument,
+			wantErrType:   errors.ErrorTypeUnprocessable,
+			wantErr:       true,
+		},
+		{
+			name:             "admin patches document of another principal expect no error",
+			principal:        admin,
+			patchType:        models.PatchTypeJSONPatch,
+			patch:            `[{"op": "replace", "path": "/doc/address/city", "value": "Haifa"}]`,
+			getDocumentMD:    successfulGetDocument,
+			jsonSchemaMD:     validPatchedDocument,
+			updateDocumentMD: successfulUpdateDocument,
+			wantErr:          false,
+		},
+		{
+			name:          "writer patches document of another principal expect forbidden error",
+			principal:     otherWriter,
+			patchType:     models.PatchTypeJSONPatch,
+			patch:         `[{"op": "replace", "path": "/doc/address/city", "value": "Haifa"}]`,
+			getDocumentMD: successfulGetDocument,
+			wantErrType:   errors.ErrorTypeForbidden,
+			wantErr:       true,
+		},
+		{
+			name:        "reader patches document expect forbidden error",
+			principal:   reader,
+			patchType:   models.PatchTypeJSONPatch,
+			patch:       `[{"op": "replace", "path": "/doc/address/city", "value": "Haifa"}]`,
+			wantErrType: errors.ErrorTypeForbidden,
+			wantErr:     true,
+		},
+		{
+			name:             "document modified concurrently expect conflict error",
+			principal:        owner,
+			patchType:        models.PatchTypeJSONPatch,
+			patch:            `[{"op": "replace", "path": "/doc/address/city", "value": "Haifa"}]`,
+			getDocumentMD:    successfulGetDocument,
+			jsonSchemaMD:     validPatchedDocument,
+			updateDocumentMD: conflictOnUpdateDocument,
+			wantErrType:      errors.ErrorTypeConflict,
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			id := uuid.New().String()
+
+			db := mocks.NewMockDocumentDB(c)
+			db.EXPECT().GetDocumentByID(gomock.Any(), id, models.ReadOptions{Fresh: true}, gomock.AssignableToTypeOf(&models.Document{})).
+				Times(tt.getDocumentMD.times).
+				Do(func(_ interface{}, _ interface{}, _ models.ReadOptions, doc *models.Document) {
+					*doc = storedDoc
+				}).
+				Return(tt.getDocumentMD.err)
+			db.EXPECT().UpdateDocument(gomock.Any(), id, gomock.AssignableToTypeOf(models.Document{})).
+				Times(tt.updateDocumentMD.times).
+				Do(func(_ interface{}, _ interface{}, doc models.Document) {
+					if doc.Version != storedDoc.Version {
+						t.Errorf("UpdateDocument() version = %d, want %d", doc.Version, storedDoc.Version)
+					}
+				}).
+				Return(tt.updateDocumentMD.err)
+
+			js := mocks.NewMockJSONSchemaValidator(c)
+			js.EXPECT().ValidateSchemaFromBytes(documentSchemaName, gomock.AssignableToTypeOf([]byte{})).
+				Times(tt.jsonSchemaMD.times).
+				Return(tt.jsonSchemaMD.err)
+
+			quotas := mocks.NewMockQuotaProvider(c)
+			quotas.EXPECT().Quota(testTenant).AnyTimes().Return(models.TenantQuota{})
+
+			d := &Domain{
+				db:         db,
+				jsonSchema: js,
+				quotas:     quotas,
+			}
+
+			ctx := tenancy.NewContext(auth.NewContext(context.TODO(), tt.principal), testTenant)
+			got, err := d.PatchDocument(ctx, id, tt.patchType, []byte(tt.patch))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PatchDocument() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				if !errors.IsType(err, tt.wantErrType) {
+					t.Errorf("PatchDocument() error = %v, want error type %v", err, tt.wantErrType)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(got, patchedDoc) {
+				t.Errorf("PatchDocument() got = %v, want %v", got, patchedDoc)
+			}
+		})
+	}
+}
+
+func TestDomain_PutDocument(t *testing.T) {
+	type dbGetDocumentMockData struct {
+		times int
+		err   error
+	}
+
+	type dbMockData struct {
+		times int
+		err   error
+	}
+
+	storedDoc := models.Document{
+		Name:      "tamir",
+		Doc:       map[string]interface{}{"city": "Tel Aviv"},
+		CreatedBy: "tamir",
+		Version:   3,
+	}
+
+	docToPut := models.Document{
+		Name: "tamir",
+		Doc:  map[string]interface{}{"city": "Haifa"},
+	}
+
+	owner := models.Principal{Subject: "tamir", Roles: []string{RoleWriter}}
+	otherWriter := models.Principal{Subject: "someone-else", Roles: []string{RoleWriter}}
+	reader := models.Principal{Subject: "tamir", Roles: []string{RoleReader}}
+
+	existingDocument := dbGetDocumentMockData{
+		times: 1,
+		err:   nil,
+	}
+
+	missingDocument := dbGetDocumentMockData{
+		times: 1,
+		err:   errors.New("not-found").SetType(errors.ErrorTypeNotFound),
+	}
+
+	invalidID := dbGetDocumentMockData{
+		times: 1,
+		err:   errors.New("bad-request").SetType(errors.ErrorTypeBadRequest),
+	}
+
+	successful := dbMockData{
+		times: 1,
+		err:   nil,
+	}
+
+	conflict := dbMockData{
+		times: 1,
+		err:   errors.New("conflict").SetType(errors.ErrorTypeConflict),
 	}
 
 	tests := []struct {
 		name          string
+		principal     models.Principal
 		getDocumentMD dbGetDocumentMockData
+		saveMD        dbMockData
+		updateMD      dbMockData
+		wantCreated   bool
+		wantErrType   errors.ErrorType
 		wantErr       bool
 	}{
 		{
-			name:          "successful get document from db expect no error",
-			getDocumentMD: successfulGetDocument,
+			name:          "put missing document expect document created",
+			principal:     owner,
+			getDocumentMD: missingDocument,
+			saveMD:        successful,
+			wantCreated:   true,
 			wantErr:       false,
 		},
 		{
-			name:          "failed to get document from db expect error",
-			getDocumentMD: failedToGetDocument,
+			name:          "put existing document expect document replaced",
+			principal:     owner,
+			getDocumentMD: existingDocument,
+			updateMD:      successful,
+			wantCreated:   false,
+			wantErr:       false,
+		},
+		{
+			name:          "document created concurrently expect conflict error",
+			principal:     owner,
+			getDocumentMD: missingDocument,
+			saveMD:        conflict,
+			wantErrType:   errors.ErrorTypeConflict,
+			wantErr:       true,
+		},
+		{
+			name:          "document replaced concurrently expect conflict error",
+			principal:     owner,
+			getDocumentMD: existingDocument,
+			updateMD:      conflict,
+			wantErrType:   errors.ErrorTypeConflict,
+			wantErr:       true,
+		},
+		{
+			name:          "invalid id expect bad request error",
+			principal:     owner,
+			getDocumentMD: invalidID,
+			wantErrType:   errors.ErrorTypeBadRequest,
 			wantErr:       true,
 		},
+		{
+			name:          "writer replaces document of another principal expect forbidden error",
+			principal:     otherWriter,
+			getDocumentMD: existingDocument,
+			wantErrType:   errors.ErrorTypeForbidden,
+			wantErr:       true,
+		},
+		{
+			name:        "reader puts document expect forbidden error",
+			principal:   reader,
+			wantErrType: errors.ErrorTypeForbidden,
+			wantErr:     true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -121,36 +1135,55 @@ func TestDomain_GetDocument(t *testing.T) {
 			defer c.Finish()
 
 			id := uuid.New().String()
-			docToReturn := models.Document{
-				Name: "tamir",
-				Doc: map[string]interface{}{
-					"key": "value",
-				},
-			}
+
 			db := mocks.NewMockDocumentDB(c)
-			db.EXPECT().GetDocumentByID(gomock.Any(), id, gomock.AssignableToTypeOf(&models.Document{})).
+			db.EXPECT().GetDocumentByID(gomock.Any(), id, models.ReadOptions{Fresh: true}, gomock.AssignableToTypeOf(&models.Document{})).
 				Times(tt.getDocumentMD.times).
-				Do(func(_ interface{}, _ interface{}, doc *models.Document) {
-					*doc = docToReturn
+				Do(func(_ interface{}, _ interface{}, _ models.ReadOptions, doc *models.Document) {
+					*doc = storedDoc
 				}).
 				Return(tt.getDocumentMD.err)
+			db.EXPECT().SaveDocumentWithID(gomock.Any(), id, gomock.AssignableToTypeOf(models.Document{})).
+				Times(tt.saveMD.times).
+				Do(func(_ interface{}, _ interface{}, doc models.Document) {
+					if doc.CreatedBy != tt.principal.Subject {
+						t.Errorf("SaveDocumentWithID() createdBy = %s, want %s", doc.CreatedBy, tt.principal.Subject)
+					}
+				}).
+				Return(tt.saveMD.err)
+			db.EXPECT().UpdateDocument(gomock.Any(), id, gomock.AssignableToTypeOf(models.Document{})).
+				Times(tt.updateMD.times).
+				Do(func(_ interface{}, _ interface{}, doc models.Document) {
+					if doc.Version != storedDoc.Version || doc.CreatedBy != storedDoc.CreatedBy {
+						t.Errorf("UpdateDocument() got = %v, want version %d created by %s", doc, storedDoc.Version, storedDoc.CreatedBy)
+					}
+				}).
+				Return(tt.updateMD.err)
+
+			quotas := mocks.NewMockQuotaProvider(c)
+			quotas.EXPECT().Quota(testTenant).AnyTimes().Return(models.TenantQuota{})
 
 			d := &Domain{
-				db: db,
+				db:     db,
+				quotas: quotas,
 			}
 
-			got, err := d.GetDocument(context.TODO(), id)
+			ctx := tenancy.NewContext(auth.NewContext(context.TODO(), tt.principal), testTenant)
+			got, err := d.PutDocument(ctx, id, docToPut)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("GetDocument() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("PutDocument() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
 			if tt.wantErr {
+				if !errors.IsType(err, tt.wantErrType) {
+					t.Errorf("PutDocument() error = %v, want error type %v", err, tt.wantErrType)
+				}
 				return
 			}
 
-			if !reflect.DeepEqual(got, docToReturn) {
-				t.Errorf("GetDocument() got = %v, want %v", got, docToReturn)
+			if got != tt.wantCreated {
+				t.Errorf("PutDocument() got = %v, want %v", got, tt.wantCreated)
 			}
 		})
 	}
@@ -208,13 +1241,35 @@ func TestDomain_Teardown(t *testing.T) {
 }
 
 func TestNewDomain(t *testing.T) {
+	type jsonSchemaMockData struct {
+		times int
+		err   error
+	}
+
+	successfulSetJSONSchema := jsonSchemaMockData{
+		times: 1,
+		err:   nil,
+	}
+
+	failedToSetJSONSchema := jsonSchemaMockData{
+		times: 1,
+		err:   errors.New("some-error"),
+	}
+
 	tests := []struct {
-		name    string
-		wantErr bool
+		name          string
+		setJSONSchema jsonSchemaMockData
+		wantErr       bool
 	}{
 		{
-			name:    "valid creation expect no error",
-			wantErr: false,
+			name:          "valid creation expect document schema registered and no error",
+			setJSONSchema: successfulSetJSONSchema,
+			wantErr:       false,
+		},
+		{
+			name:          "failed to set document schema expect error",
+			setJSONSchema: failedToSetJSONSchema,
+			wantErr:       true,
 		},
 	}
 	for _, tt := range tests {
@@ -223,17 +1278,37 @@ func TestNewDomain(t *testing.T) {
 			defer c.Finish()
 
 			db := mocks.NewMockDocumentDB(c)
+			js := mocks.NewMockJSONSchemaValidator(c)
+			js.EXPECT().SetSchemaFromBytes(documentSchemaName, gomock.AssignableToTypeOf([]byte{})).Times(tt.setJSONSchema.times).Return(tt.setJSONSchema.err)
+			quotas := mocks.NewMockQuotaProvider(c)
+
+			idem := mocks.NewMockIdempotencyStore(c)
 
-			got, err := NewDomain(db)
+			_, filename, _, _ := runtime.Caller(0)
+			dir := path.Join(path.Dir(filename), "../../../")
+			_ = os.Chdir(dir)
+
+			got, err := NewDomain(db, js, quotas, idem)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewDomain() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
-			want := &Domain{db: db}
+			if tt.wantErr {
+				return
+			}
+
+			want := &Domain{db: db, jsonSchema: js, quotas: quotas, idem: idem}
 			if !reflect.DeepEqual(got, want) {
 				t.Errorf("NewDomain() got = %v, want %v", got, want)
 			}
 		})
 	}
 }
+
+const testTenant = "tenant-a"
+
+func contextWithPrincipal(subject string, roles ...string) context.Context {
+	ctx := auth.NewContext(context.TODO(), models.Principal{Subject: subject, Roles: roles})
+	return tenancy.NewContext(ctx, testTenant)
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"strings"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+)
+
+const (
+	// maxReadFields is the largest number of fields a read may select or exclude
+	maxReadFields = 100
+
+	fieldName = "name"
+	fieldDoc  = "doc"
+)
+
+// validateReadOptions checks that the field paths of opts are well-formed and returns them with their top level
+// field in the case documents are stored with. Paths start with name, or with doc followed by the keys of the document
+func validateReadOptions(opts models.ReadOptions) (models.ReadOptions, error) {
+	if len(opts.Fields) > 0 && len(opts.Exclude) > 0 {
+		return models.ReadOptions{}, errors.New("Fields can't be both selected and excluded").SetType(errors.ErrorTypeBadRequest)
+	}
+
+	fields, err := validateFieldPaths(opts.Fields)
+	if err != nil {
+		return models.ReadOptions{}, err
+	}
+
+	exclude, err := validateFieldPaths(opts.Exclude)
+	if err != nil {
+		return models.ReadOptions{}, err
+	}
+
+	return models.ReadOptions{Fields: fields, Exclude: exclude, Fresh: opts.Fresh}, nil
+}
+
+func validateFieldPaths(paths []string) ([]string, error) {
+	if len(paths) > maxReadFields {
+		return nil, errors.Errorf("Too many fields (%d), expected at most %d", len(paths), maxReadFields).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	var valid []string
+	for _, path := range paths {
+		keys, ok := splitFieldPath(path)
+		if !ok {
+			return nil, errors.Errorf("Invalid field path (%s)", path).SetType(errors.ErrorTypeBadRequest)
+		}
+
+		switch top := strings.ToLower(keys[0]); {
+		case top == fieldName && len(keys) == 1, top == fieldDoc:
+			keys[0] = top
+		default:
+			return nil, errors.Errorf("Unknown field path (%s), expected (%s) or a path in (%s)", path, fieldName, fieldDoc).SetType(errors.ErrorTypeBadRequest)
+		}
+
+		path = strings.Join(keys, ".")
+		for _, other := range valid {
+			// mongodb rejects projections of a field together with a field it contains
+			if path == other || strings.HasPrefix(path, other+".") || strings.HasPrefix(other, path+".") {
+				return nil, errors.Errorf("Field path (%s) overlaps field path (%s)", path, other).SetType(errors.ErrorTypeBadRequest)
+			}
+		}
+		valid = append(valid, path)
+	}
+
+	return valid, nil
+}
+
+// splitFieldPath returns the keys of a dot separated field path, and whether they are all valid keys
+func splitFieldPath(path string) ([]string, bool) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys {
+		// mongodb reads keys starting with $ as operators
+		if key == "" || strings.HasPrefix(key, "$") || strings.ContainsRune(key, 0) {
+			return nil, false
+		}
+	}
+
+	return keys, true
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// maxIdempotencyKeyLength bounds the size of the stored keys
+	maxIdempotencyKeyLength = 255
+
+	// completeAttempts bounds the attempts to record the document created for a key
+	completeAttempts = 3
+)
+
+// completeRetryDelay is waited between attempts to record the document created for a key
+var completeRetryDelay = 100 * time.Millisecond
+
+// reserveIdempotencyKey reserves the key for the request of doc. When the key was already used by the same
+// request it returns the id of the document that request created and false
+func (d *Domain) reserveIdempotencyKey(ctx context.Context, key string, p models.Principal, doc models.Document) (string, bool, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return "", false, errors.Errorf("Idempotency key is longer than %d characters", maxIdempotencyKeyLength).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	hash, err := requestHash(p, doc)
+	if err != nil {
+		return "", false, err
+	}
+
+	rec, reserved, err := d.idem.ReserveIdempotencyKey(ctx, key, hash)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "Failed to reserve idempotency key (%s)", key)
+	}
+
+	if reserved {
+		return "", true, nil
+	}
+
+	if rec.RequestHash != hash {
+		return "", false, errors.Errorf("Idempotency key (%s) was already used with a different request", key).SetType(errors.ErrorTypeUnprocessable)
+	}
+
+	if rec.DocumentID == "" {
+		return "", false, errors.Errorf("Request with idempotency key (%s) is still in progress until %s", key, rec.ReservedUntil.Format(time.RFC3339)).SetType(errors.ErrorTypeConflict)
+	}
+
+	return rec.DocumentID, false, nil
+}
+
+// completeIdempotencyKey records the document created by the request of key, retrying failed attempts.
+// The document exists even if it can't be recorded, so the failure is only logged: the key stays reserved until its
+// reservation expires and is then taken over by the next request that uses it
+func (d *Domain) completeIdempotencyKey(ctx context.Context, key string, id string) {
+	err := d.idem.CompleteIdempotencyKey(ctx, key, id)
+	for i := 1; i < completeAttempts && err != nil && ctx.Err() == nil; i++ {
+		time.Sleep(completeRetryDelay)
+		err = d.idem.CompleteIdempotencyKey(ctx, key, id)
+	}
+
+	if err != nil {
+		log.Errorf("Failed to complete idempotency key (%s) of document (%s): %s", key, id, err)
+	}
+}
+
+// requestHash identifies a request by its principal and document body
+func requestHash(p models.Principal, doc models.Document) (string, error) {
+	b, err := json.Marshal(documentBody{Name: doc.Name, Doc: doc.Doc})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to marshal document for idempotency").SetType(errors.ErrorTypeInternal)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(p.Subject))
+	h.Write([]byte{0})
+	h.Write(b)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
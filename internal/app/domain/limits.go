@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// maxDocumentBytes is the size of the largest bson document mongodb stores
+	maxDocumentBytes = 16 * 1024 * 1024
+
+	// maxDocumentDepth is the deepest nesting of embedded documents and arrays mongodb stores,
+	// counting the stored document itself
+	maxDocumentDepth = 100
+)
+
+// checkLimits verifies that mongodb can store doc, so documents it would reject fail before reaching the database.
+// The depth is checked first, as it is cheaper than encoding the document to measure its size
+func checkLimits(doc models.Document) error {
+	// the stored document embeds Doc
+	if depth := 1 + nestingDepth(doc.Doc); depth > maxDocumentDepth {
+		return errors.Errorf("Document nesting depth (%d) exceeds the maximum depth of %d", depth, maxDocumentDepth).SetType(errors.ErrorTypeDocumentTooDeep)
+	}
+
+	b, err := bson.Marshal(doc)
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal document").SetType(errors.ErrorTypeInternal)
+	}
+
+	if len(b) > maxDocumentBytes {
+		return errors.Errorf("Document size (%d bytes) exceeds the maximum size of %d bytes", len(b), maxDocumentBytes).SetType(errors.ErrorTypeDocumentTooLarge)
+	}
+
+	return nil
+}
+
+// nestingDepth returns the number of levels of maps and slices of v, 0 for scalars
+func nestingDepth(v interface{}) int {
+	deepest := 0
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, value := range v {
+			if d := nestingDepth(value); d > deepest {
+				deepest = d
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if d := nestingDepth(value); d > deepest {
+				deepest = d
+			}
+		}
+	default:
+		return 0
+	}
+
+	return deepest + 1
+}
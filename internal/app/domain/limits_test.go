@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+)
+
+func Test_checkLimits(t *testing.T) {
+	nested := func(depth int) map[string]interface{} {
+		doc := map[string]interface{}{"leaf": true}
+		for i := 1; i < depth; i++ {
+			doc = map[string]interface{}{"level": []interface{}{doc}}
+		}
+		return doc
+	}
+
+	tests := []struct {
+		name     string
+		doc      models.Document
+		wantErr  bool
+		wantType errors.ErrorType
+	}{
+		{
+			name:    "document within limits expect no error",
+			doc:     models.Document{Name: "tamir", Doc: map[string]interface{}{"lastName": "Aviv"}},
+			wantErr: false,
+		},
+		{
+			name:    "document within maximum depth expect no error",
+			doc:     models.Document{Name: "tamir", Doc: nested((maxDocumentDepth - 1) / 2)},
+			wantErr: false,
+		},
+		{
+			name:     "document deeper than maximum depth expect document too deep error",
+			doc:      models.Document{Name: "tamir", Doc: nested(maxDocumentDepth)},
+			wantErr:  true,
+			wantType: errors.ErrorTypeDocumentTooDeep,
+		},
+		{
+			name:     "document larger than maximum size expect document too large error",
+			doc:      models.Document{Name: "tamir", Doc: map[string]interface{}{"blob": strings.Repeat("a", maxDocumentBytes)}},
+			wantErr:  true,
+			wantType: errors.ErrorTypeDocumentTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkLimits(tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.IsType(err, tt.wantType) {
+				t.Errorf("checkLimits() error = %v, want error of type %v", err, tt.wantType)
+			}
+		})
+	}
+}
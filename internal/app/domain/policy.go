@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"context"
+
+	"microservice/internal/pkg/auth"
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+)
+
+const (
+	// RoleReader may read documents
+	RoleReader = "reader"
+
+	// RoleWriter may read documents, create documents and modify the documents it created
+	RoleWriter = "writer"
+
+	// RoleAdmin may perform every action on every document
+	RoleAdmin = "admin"
+)
+
+// action is an operation a principal performs on documents
+type action string
+
+const (
+	actionRead   action = "read"
+	actionCreate action = "create"
+	actionUpdate action = "update"
+)
+
+// actionRoles lists the roles that grant each action
+var actionRoles = map[action][]string{
+	actionRead:   {RoleReader, RoleWriter, RoleAdmin},
+	actionCreate: {RoleWriter, RoleAdmin},
+	actionUpdate: {RoleWriter, RoleAdmin},
+}
+
+// authorize checks that the principal of ctx may perform the action and returns that principal.
+// doc is the target document for actions on an existing document and nil otherwise.
+// Only the creator of a document or an admin may update it
+func authorize(ctx context.Context, a action, doc *models.Document) (models.Principal, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return models.Principal{}, errors.New("Request has no authenticated principal").SetType(errors.ErrorTypeUnauthorized)
+	}
+
+	if !hasAnyRole(p, actionRoles[a]...) {
+		return models.Principal{}, errors.Errorf("Principal (%s) is not allowed to %s documents", p.Subject, a).SetType(errors.ErrorTypeForbidden)
+	}
+
+	if a == actionUpdate && doc != nil && !hasAnyRole(p, RoleAdmin) && doc.CreatedBy != p.Subject {
+		return models.Principal{}, errors.Errorf("Principal (%s) is not the owner of the document", p.Subject).SetType(errors.ErrorTypeForbidden)
+	}
+
+	return p, nil
+}
+
+func hasAnyRole(p models.Principal, roles ...string) bool {
+	for _, have := range p.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"microservice/internal/pkg/auth"
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+)
+
+func TestAuthorize(t *testing.T) {
+	ownedDoc := &models.Document{
+		Name:      "tamir",
+		CreatedBy: "tamir",
+	}
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		action      action
+		doc         *models.Document
+		wantErrType errors.ErrorType
+		wantErr     bool
+	}{
+		{
+			name:        "no principal in context expect unauthorized error",
+			ctx:         context.TODO(),
+			action:      actionRead,
+			wantErrType: errors.ErrorTypeUnauthorized,
+			wantErr:     true,
+		},
+		{
+			name:    "reader reads document expect no error",
+			ctx:     contextWithPrincipal("tamir", RoleReader),
+			action:  actionRead,
+			wantErr: false,
+		},
+		{
+			name:        "principal without roles reads document expect forbidden error",
+			ctx:         contextWithPrincipal("tamir"),
+			action:      actionRead,
+			wantErrType: errors.ErrorTypeForbidden,
+			wantErr:     true,
+		},
+		{
+			name:        "reader creates document expect forbidden error",
+			ctx:         contextWithPrincipal("tamir", RoleReader),
+			action:      actionCreate,
+			wantErrType: errors.ErrorTypeForbidden,
+			wantErr:     true,
+		},
+		{
+			name:    "writer creates document expect no error",
+			ctx:     contextWithPrincipal("tamir", RoleWriter),
+			action:  actionCreate,
+			wantErr: false,
+		},
+		{
+			name:    "owner updates document expect no error",
+			ctx:     contextWithPrincipal("tamir", RoleWriter),
+			action:  actionUpdate,
+			doc:     ownedDoc,
+			wantErr: false,
+		},
+		{
+			name:        "writer updates document of another principal expect forbidden error",
+			ctx:         contextWithPrincipal("someone-else", RoleWriter),
+			action:      actionUpdate,
+			doc:         ownedDoc,
+			wantErrType: errors.ErrorTypeForbidden,
+			wantErr:     true,
+		},
+		{
+			name:    "admin updates document of another principal expect no error",
+			ctx:     contextWithPrincipal("administrator", RoleAdmin),
+			action:  actionUpdate,
+			doc:     ownedDoc,
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := authorize(tt.ctx, tt.action, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("authorize() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				if !errors.IsType(err, tt.wantErrType) {
+					t.Errorf("authorize() error = %v, want error type %v", err, tt.wantErrType)
+				}
+				return
+			}
+
+			want, _ := auth.FromContext(tt.ctx)
+			if got.Subject != want.Subject {
+				t.Errorf("authorize() got = %v, want %v", got, want)
+			}
+		})
+	}
+}
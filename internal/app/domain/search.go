@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"encoding/json"
+	"strings"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+)
+
+const (
+	// defaultSearchLimit is the number of results of searches that don't set a limit
+	defaultSearchLimit = 20
+
+	// maxSearchLimit is the largest number of results a search may ask for
+	maxSearchLimit = 100
+
+	// maxSearchTextLength is the length of the longest text searched for
+	maxSearchTextLength = 512
+
+	// maxFilterDepth is the deepest nesting of the combinations of a filter
+	maxFilterDepth = 8
+
+	// maxFilterConditions is the largest number of conditions and combinations of a filter, counting the values of in
+	maxFilterConditions = 100
+)
+
+// validateSearchQuery checks that q is a search the document database can run and returns it with its defaults set.
+// Filters test the fields inside doc with scalar values only, numbers are converted to integers or floats
+func validateSearchQuery(q models.SearchQuery) (models.SearchQuery, error) {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" && q.Filter == nil {
+		return models.SearchQuery{}, errors.New("Search needs a text or a filter").SetType(errors.ErrorTypeBadRequest)
+	}
+
+	if len(q.Text) > maxSearchTextLength {
+		return models.SearchQuery{}, errors.Errorf("Search text is longer than %d characters", maxSearchTextLength).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	switch {
+	case q.Limit == 0:
+		q.Limit = defaultSearchLimit
+	case q.Limit < 0 || q.Limit > maxSearchLimit:
+		return models.SearchQuery{}, errors.Errorf("Invalid search limit (%d), expected 1 to %d", q.Limit, maxSearchLimit).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	opts, err := validateReadOptions(q.ReadOptions)
+	if err != nil {
+		return models.SearchQuery{}, err
+	}
+	q.ReadOptions = opts
+
+	if q.Filter != nil {
+		conditions := 0
+		filter, err := validateFilter(*q.Filter, 1, &conditions)
+		if err != nil {
+			return models.SearchQuery{}, err
+		}
+		q.Filter = &filter
+	}
+
+	return q, nil
+}
+
+// validateFilter checks a filter at the given depth, counting its conditions in conditions
+func validateFilter(f models.Filter, depth int, conditions *int) (models.Filter, error) {
+	if depth > maxFilterDepth {
+		return models.Filter{}, errors.Errorf("Filter is nested deeper than %d levels", maxFilterDepth).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	if *conditions++; *conditions > maxFilterConditions {
+		return models.Filter{}, errors.Errorf("Filter has more than %d conditions", maxFilterConditions).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	kinds := 0
+	for _, set := range []bool{len(f.And) > 0, len(f.Or) > 0, f.Not != nil, f.Field != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return models.Filter{}, errors.New("Filter must have exactly one of and, or, not and field").SetType(errors.ErrorTypeBadRequest)
+	}
+
+	var err error
+	switch {
+	case len(f.And) > 0:
+		f.And, err = validateFilters(f.And, depth, conditions)
+	case len(f.Or) > 0:
+		f.Or, err = validateFilters(f.Or, depth, conditions)
+	case f.Not != nil:
+		var not models.Filter
+		not, err = validateFilter(*f.Not, depth+1, conditions)
+		f.Not = &not
+	default:
+		f, err = validateCondition(f, conditions)
+	}
+
+	return f, err
+}
+
+func validateFilters(filters []models.Filter, depth int, conditions *int) ([]models.Filter, error) {
+	valid := make([]models.Filter, len(filters))
+	for i, f := range filters {
+		var err error
+		if valid[i], err = validateFilter(f, depth+1, conditions); err != nil {
+			return nil, err
+		}
+	}
+
+	return valid, nil
+}
+
+// validateCondition checks the condition of a filter on a field, which tests the field in a single way
+func validateCondition(f models.Filter, conditions *int) (models.Filter, error) {
+	keys, ok := splitFieldPath(f.Field)
+	if !ok || len(keys) < 2 || keys[0] != fieldDoc {
+		return models.Filter{}, errors.Errorf("Invalid filter field (%s), expected a path in (%s)", f.Field, fieldDoc).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	ranged := f.Gt != nil || f.Gte != nil || f.Lt != nil || f.Lte != nil
+	kinds := 0
+	for _, set := range []bool{f.Eq != nil, ranged, len(f.In) > 0, f.Exists != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return models.Filter{}, errors.Errorf("Filter of field (%s) must have exactly one of eq, a range, in and exists", f.Field).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	var err error
+	for _, value := range []*interface{}{&f.Eq, &f.Gt, &f.Gte, &f.Lt, &f.Lte} {
+		if *value == nil {
+			continue
+		}
+
+		if *value, err = scalar(*value); err != nil {
+			return models.Filter{}, errors.Wrapf(err, "Invalid filter of field (%s)", f.Field).SetType(errors.ErrorTypeBadRequest)
+		}
+	}
+
+	if *conditions += len(f.In); *conditions > maxFilterConditions {
+		return models.Filter{}, errors.Errorf("Filter has more than %d conditions", maxFilterConditions).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	in := make([]interface{}, len(f.In))
+	for i, value := range f.In {
+		if in[i], err = scalar(value); err != nil {
+			return models.Filter{}, errors.Wrapf(err, "Invalid filter of field (%s)", f.Field).SetType(errors.ErrorTypeBadRequest)
+		}
+	}
+	if len(in) > 0 {
+		f.In = in
+	}
+
+	return f, nil
+}
+
+// scalar returns v if it is a string, a boolean or a number, converting json numbers to integers or floats
+func scalar(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string, bool, int, int32, int64, float64:
+		return v, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	default:
+		return nil, errors.Errorf("Unsupported value (%v) of type (%T), expected a string, a boolean or a number", v, v)
+	}
+}
@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+)
+
+func Test_validateSearchQuery(t *testing.T) {
+	exists := true
+
+	nested := func(depth int) *models.Filter {
+		f := models.Filter{Field: "doc.name", Eq: "aviv"}
+		for i := 1; i < depth; i++ {
+			f = models.Filter{Not: &models.Filter{And: []models.Filter{f}}}
+		}
+		return &f
+	}
+
+	tests := []struct {
+		name    string
+		query   models.SearchQuery
+		want    models.SearchQuery
+		wantErr bool
+	}{
+		{
+			name:    "text search without limit expect default limit",
+			query:   models.SearchQuery{Text: " aviv "},
+			want:    models.SearchQuery{Text: "aviv", Limit: defaultSearchLimit},
+			wantErr: false,
+		},
+		{
+			name: "filter with json numbers expect numbers converted",
+			query: models.SearchQuery{
+				Filter: &models.Filter{And: []models.Filter{
+					{Field: "doc.age", Gte: json.Number("18"), Lt: json.Number("65.5")},
+					{Field: "doc.tags", In: []interface{}{"a", json.Number("2")}},
+					{Not: &models.Filter{Field: "doc.deleted", Exists: &exists}},
+				}},
+				Limit: 5,
+			},
+			want: models.SearchQuery{
+				Filter: &models.Filter{And: []models.Filter{
+					{Field: "doc.age", Gte: int64(18), Lt: 65.5},
+					{Field: "doc.tags", In: []interface{}{"a", int64(2)}},
+					{Not: &models.Filter{Field: "doc.deleted", Exists: &exists}},
+				}},
+				Limit: 5,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "neither text nor filter expect error",
+			query:   models.SearchQuery{Text: "  "},
+			wantErr: true,
+		},
+		{
+			name:    "text longer than maximum length expect error",
+			query:   models.SearchQuery{Text: strings.Repeat("a", maxSearchTextLength+1)},
+			wantErr: true,
+		},
+		{
+			name:    "limit larger than maximum limit expect error",
+			query:   models.SearchQuery{Text: "aviv", Limit: maxSearchLimit + 1},
+			wantErr: true,
+		},
+		{
+			name:    "filter of field outside doc expect error",
+			query:   models.SearchQuery{Filter: &models.Filter{Field: "tenant", Eq: "other"}},
+			wantErr: true,
+		},
+		{
+			name:    "filter of field with operator expect error",
+			query:   models.SearchQuery{Filter: &models.Filter{Field: "doc.$where", Eq: "1"}},
+			wantErr: true,
+		},
+		{
+			name:    "filter with both eq and in expect error",
+			query:   models.SearchQuery{Filter: &models.Filter{Field: "doc.age", Eq: json.Number("1"), In: []interface{}{"a"}}},
+			wantErr: true,
+		},
+		{
+			name:    "filter combining and with a field expect error",
+			query:   models.SearchQuery{Filter: &models.Filter{Field: "doc.age", And: []models.Filter{{Field: "doc.age", Eq: "a"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "filter with object value expect error",
+			query:   models.SearchQuery{Filter: &models.Filter{Field: "doc.age", Eq: map[string]interface{}{"$gt": ""}}},
+			wantErr: true,
+		},
+		{
+			name:    "filter within maximum depth expect no error",
+			query:   models.SearchQuery{Filter: nested(maxFilterDepth / 2)},
+			want:    models.SearchQuery{Filter: nested(maxFilterDepth / 2), Limit: defaultSearchLimit},
+			wantErr: false,
+		},
+		{
+			name:    "filter deeper than maximum depth expect error",
+			query:   models.SearchQuery{Filter: nested(maxFilterDepth)},
+			wantErr: true,
+		},
+		{
+			name:    "selected fields expect top level fields in stored case",
+			query:   models.SearchQuery{Text: "aviv", ReadOptions: models.ReadOptions{Fields: []string{"Name", "DOC.city"}}},
+			want:    models.SearchQuery{Text: "aviv", Limit: defaultSearchLimit, ReadOptions: models.ReadOptions{Fields: []string{"name", "doc.city"}}},
+			wantErr: false,
+		},
+		{
+			name:    "fields both selected and excluded expect error",
+			query:   models.SearchQuery{Text: "aviv", ReadOptions: models.ReadOptions{Fields: []string{"name"}, Exclude: []string{"doc.history"}}},
+			wantErr: true,
+		},
+		{
+			name:    "excluded field outside the document expect error",
+			query:   models.SearchQuery{Text: "aviv", ReadOptions: models.ReadOptions{Exclude: []string{"tenant"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateSearchQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateSearchQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !errors.IsType(err, errors.ErrorTypeBadRequest) {
+					t.Errorf("validateSearchQuery() error = %v, want bad request error", err)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateSearchQuery() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
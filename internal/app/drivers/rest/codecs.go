@@ -0,0 +1,277 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"microservice/internal/pkg/errors"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	mediaTypeJSON    = "application/json"
+	mediaTypeMsgPack = "application/msgpack"
+	mediaTypeCBOR    = "application/cbor"
+	mediaTypeBSON    = "application/bson"
+	mediaTypeYAML    = "application/yaml"
+
+	headerAccept = "Accept"
+	headerVary   = "Vary"
+)
+
+// codec translates documents between json and another media type.
+// Documents are validated and handled as json, other media types are decoded to and encoded from generic values
+type codec struct {
+	mediaType string
+	// decode returns the generic value of maps, slices and scalars encoded in data
+	decode func(data []byte) (interface{}, error)
+	// encode returns the encoding of a generic value
+	encode func(v interface{}) ([]byte, error)
+}
+
+// codecs are the supported media types in order of preference, json is the default
+var codecs = []codec{
+	{
+		mediaType: mediaTypeJSON,
+		decode:    decodeJSON,
+		encode:    json.Marshal,
+	},
+	{
+		mediaType: mediaTypeMsgPack,
+		decode: func(data []byte) (interface{}, error) {
+			var v interface{}
+			err := msgpack.Unmarshal(data, &v)
+			return v, err
+		},
+		encode: msgpack.Marshal,
+	},
+	{
+		mediaType: mediaTypeCBOR,
+		decode: func(data []byte) (interface{}, error) {
+			var v interface{}
+			err := cbor.Unmarshal(data, &v)
+			return v, err
+		},
+		encode: cbor.Marshal,
+	},
+	{
+		mediaType: mediaTypeBSON,
+		decode: func(data []byte) (interface{}, error) {
+			if err := bson.Raw(data).Validate(); err != nil {
+				return nil, err
+			}
+
+			// relaxed extended json keeps numbers and strings as they are, and wraps the types json lacks
+			extJSON, err := bson.MarshalExtJSON(bson.Raw(data), false, false)
+			if err != nil {
+				return nil, err
+			}
+
+			return decodeJSON(extJSON)
+		},
+		encode: bson.Marshal,
+	},
+	{
+		mediaType: mediaTypeYAML,
+		decode: func(data []byte) (interface{}, error) {
+			var v interface{}
+			err := yaml.Unmarshal(data, &v)
+			return v, err
+		},
+		encode: yaml.Marshal,
+	},
+}
+
+// mediaTypeAliases are the other names under which the media types of codecs are known
+var mediaTypeAliases = map[string]string{
+	"application/x-msgpack": mediaTypeMsgPack,
+	"application/x-bson":    mediaTypeBSON,
+	"application/x-yaml":    mediaTypeYAML,
+	"text/yaml":             mediaTypeYAML,
+}
+
+// requestCodec returns the codec of the Content-Type of a request body, json when it has none
+func requestCodec(contentType string) (codec, bool) {
+	if contentType == "" {
+		return codecs[0], true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return codec{}, false
+	}
+
+	return codecOf(mediaType)
+}
+
+// responseCodec returns the codec of the media type the client prefers among those in Accept, json when it has none
+func responseCodec(accept string) (codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return codecs[0], true
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+
+	// ranges of the same quality keep the order the client listed them in
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	for _, r := range ranges {
+		if r.mediaType == "*/*" || r.mediaType == "application/*" {
+			return codecs[0], true
+		}
+
+		if c, ok := codecOf(r.mediaType); ok {
+			return c, true
+		}
+	}
+
+	return codec{}, false
+}
+
+func codecOf(mediaType string) (codec, bool) {
+	if alias, ok := mediaTypeAliases[mediaType]; ok {
+		mediaType = alias
+	}
+
+	for _, c := range codecs {
+		if c.mediaType == mediaType {
+			return c, true
+		}
+	}
+
+	return codec{}, false
+}
+
+// supportedMediaTypes lists the media types of codecs, for error messages
+func supportedMediaTypes() string {
+	mediaTypes := make([]string, len(codecs))
+	for i, c := range codecs {
+		mediaTypes[i] = c.mediaType
+	}
+
+	return strings.Join(mediaTypes, ", ")
+}
+
+// toJSON returns the json of a request body encoded by c
+func (c codec) toJSON(data []byte) ([]byte, error) {
+	if c.mediaType == mediaTypeJSON {
+		return data, nil
+	}
+
+	v, err := c.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err = normalize(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+// fromJSON returns the encoding by c of a json response body
+func (c codec) fromJSON(data []byte) ([]byte, error) {
+	if c.mediaType == mediaTypeJSON {
+		return data, nil
+	}
+
+	v, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.encode(v)
+}
+
+// decodeJSON decodes json into generic values, keeping integers apart from floating point numbers
+func decodeJSON(data []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return normalize(v)
+}
+
+// normalize converts the maps of v to maps of string keys, as json has them, and json numbers to integers or floats
+func normalize(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			k, ok := key.(string)
+			if !ok {
+				return nil, errors.Errorf("Unsupported map key (%v) of type (%T), expected a string", key, key)
+			}
+
+			n, err := normalize(value)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = n
+		}
+		return m, nil
+	case map[string]interface{}:
+		for key, value := range v {
+			n, err := normalize(value)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = n
+		}
+		return v, nil
+	case []interface{}:
+		for i, value := range v {
+			n, err := normalize(value)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = n
+		}
+		return v, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,215 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"microservice/mocks"
+	"microservice/models"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+)
+
+func TestResponseCodec(t *testing.T) {
+	tests := []struct {
+		name          string
+		accept        string
+		wantMediaType string
+		wantOK        bool
+	}{
+		{
+			name:          "no accept header expect json",
+			accept:        "",
+			wantMediaType: mediaTypeJSON,
+			wantOK:        true,
+		},
+		{
+			name:          "any media type expect json",
+			accept:        "*/*",
+			wantMediaType: mediaTypeJSON,
+			wantOK:        true,
+		},
+		{
+			name:          "single supported media type expect its codec",
+			accept:        mediaTypeMsgPack,
+			wantMediaType: mediaTypeMsgPack,
+			wantOK:        true,
+		},
+		{
+			name:          "alias of supported media type expect its codec",
+			accept:        "application/x-yaml",
+			wantMediaType: mediaTypeYAML,
+			wantOK:        true,
+		},
+		{
+			name:          "highest quality supported media type expect its codec",
+			accept:        "application/json;q=0.5, application/cbor;q=0.9, text/html",
+			wantMediaType: mediaTypeCBOR,
+			wantOK:        true,
+		},
+		{
+			name:          "same quality expect the first listed",
+			accept:        "application/bson, application/json",
+			wantMediaType: mediaTypeBSON,
+			wantOK:        true,
+		},
+		{
+			name:   "only unsupported media types expect not acceptable",
+			accept: "text/html, application/xml",
+			wantOK: false,
+		},
+		{
+			name:   "supported media type refused with zero quality expect not acceptable",
+			accept: "application/json;q=0",
+			wantOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := responseCodec(tt.accept)
+			if ok != tt.wantOK {
+				t.Fatalf("responseCodec() ok = %v, want %v", ok, tt.wantOK)
+			}
+
+			if got.mediaType != tt.wantMediaType {
+				t.Errorf("responseCodec() media type = %v, want %v", got.mediaType, tt.wantMediaType)
+			}
+		})
+	}
+}
+
+func TestCodec_roundTrip(t *testing.T) {
+	doc := []byte(`{"Name":"name","Doc":{"count":3,"ratio":0.5,"tags":["a","b"],"nested":{"ok":true}}}`)
+
+	var want interface{}
+	if err := json.Unmarshal(doc, &want); err != nil {
+		t.Fatalf("Failed to unmarshal document. Error: %s", err)
+	}
+
+	for _, c := range codecs {
+		t.Run(c.mediaType, func(t *testing.T) {
+			encoded, err := c.fromJSON(doc)
+			if err != nil {
+				t.Fatalf("fromJSON() error = %v", err)
+			}
+
+			decoded, err := c.toJSON(encoded)
+			if err != nil {
+				t.Fatalf("toJSON() error = %v", err)
+			}
+
+			var got interface{}
+			if err := json.Unmarshal(decoded, &got); err != nil {
+				t.Fatalf("Failed to unmarshal decoded document. Error: %s", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip of (%s) = %v, want %v", c.mediaType, got, want)
+			}
+		})
+	}
+}
+
+func TestAdapter_negotiationErrors(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		headers          map[string]string
+		wantedStatusCode int
+	}{
+		{
+			name:             "add document with unsupported content type expect status unsupported media type (415)",
+			method:           http.MethodPost,
+			headers:          map[string]string{headerContentType: "text/plain"},
+			wantedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:             "add document with undecodable body expect status bad request (400)",
+			method:           http.MethodPost,
+			headers:          map[string]string{headerContentType: mediaTypeBSON},
+			wantedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:             "get document in unsupported media type expect status not acceptable (406)",
+			method:           http.MethodGet,
+			headers:          map[string]string{headerAccept: "text/html"},
+			wantedStatusCode: http.StatusNotAcceptable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			s := &Adapter{
+				domainSvc:  mocks.NewMockDomainService(c),
+				jsonSchema: mocks.NewMockJSONSchemaValidator(c),
+			}
+
+			r := chi.NewRouter()
+			r.Post("/documents", s.addDocument)
+			r.Get("/documents/{id}", s.getDocument)
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			path := "/documents"
+			if tt.method == http.MethodGet {
+				path = "/documents/id"
+			}
+
+			res, _ := testRequestWithHeaders(t, ts, tt.method, path, tt.headers, bytes.NewReader([]byte(`{"Name":"name"}`)))
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+		})
+	}
+}
+
+func TestAdapter_getDocumentEncoded(t *testing.T) {
+	doc := models.Document{Name: "name", Doc: map[string]interface{}{"key": "value"}}
+
+	for _, want := range codecs {
+		t.Run(want.mediaType, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			domainService := mocks.NewMockDomainService(c)
+			domainService.EXPECT().GetDocument(gomock.Any(), "id", models.ReadOptions{}).Times(1).Return(doc, nil)
+
+			s := &Adapter{
+				domainSvc: domainService,
+			}
+
+			r := chi.NewRouter()
+			r.Get("/documents/{id}", s.getDocument)
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, body := testRequestWithHeaders(t, ts, http.MethodGet, "/documents/id", map[string]string{headerAccept: want.mediaType}, nil)
+			statusCodeCheck(t, res, http.StatusOK)
+
+			if got := res.Header.Get(headerContentType); got != want.mediaType {
+				t.Fatalf("getDocument() Content-Type = %v, want %v", got, want.mediaType)
+			}
+
+			decoded, err := want.toJSON(body)
+			if err != nil {
+				t.Fatalf("Failed to decode response body. Error: %s", err)
+			}
+
+			var got models.Document
+			if err := json.Unmarshal(decoded, &got); err != nil {
+				t.Fatalf("Failed to unmarshal response body to 'Document'. Error: %s", err)
+			}
+
+			if !reflect.DeepEqual(got, doc) {
+				t.Fatalf("getDocument() got = %v, want %v", got, doc)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package rest
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/errors"
+
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	headerAcceptEncoding  = "Accept-Encoding"
+	headerContentEncoding = "Content-Encoding"
+	headerContentLength   = "Content-Length"
+
+	encodingGzip     = "gzip"
+	encodingDeflate  = "deflate"
+	encodingZstd     = "zstd"
+	encodingIdentity = "identity"
+)
+
+// encoder is the writer of a response encoding, which is reset to write another response once closed
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// encoderPools keep the writers of the supported response encodings across responses, since every new writer
+// allocates its compression state.
+// The deflate content coding is the zlib format (RFC 7230, section 4.2.2), not raw deflate
+var encoderPools = map[string]*sync.Pool{
+	encodingGzip: {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+	encodingDeflate: {New: func() interface{} {
+		return zlib.NewWriter(nil)
+	}},
+	encodingZstd: {New: func() interface{} {
+		// the options are valid, so the writer is always created
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return enc
+	}},
+}
+
+// newEncoder returns a writer of encoding to w, reusing one of a previous response when there is one
+func newEncoder(encoding string, w io.Writer) (encoder, error) {
+	pool, ok := encoderPools[encoding]
+	if !ok {
+		return nil, errors.Errorf("Unsupported response encoding (%s)", encoding)
+	}
+
+	enc, ok := pool.Get().(encoder)
+	if !ok {
+		return nil, errors.Errorf("Failed to create (%s) encoder", encoding)
+	}
+
+	enc.Reset(w)
+	return enc, nil
+}
+
+// compress compresses the responses of a route in the encoding the client prefers among those of the route,
+// and decompresses its gzip request bodies when the route accepts them
+func (s *Adapter) compress(route string) func(http.Handler) http.Handler {
+	conf := s.routes.Get(route).Compression
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get(headerContentEncoding))); {
+			case encoding == "" || encoding == encodingIdentity:
+			case encoding == encodingGzip && conf.DecompressRequests:
+				body, err := newDecompressedBody(r.Body, int64(conf.MaxDecompressedSize))
+				if err != nil {
+					returnReadBodyError(w, err)
+					return
+				}
+
+				r.Body = body
+				r.ContentLength = -1
+				r.Header.Del(headerContentEncoding)
+				r.Header.Del(headerContentLength)
+			default:
+				log.Debugf("Unsupported content encoding (%s) of route (%s)", encoding, route)
+				w.Header().Set(headerAcceptEncoding, acceptedEncodings(conf))
+				returnHTTPError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported content encoding (%s)", encoding))
+				return
+			}
+
+			if !conf.Enabled || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add(headerVary, headerAcceptEncoding)
+
+			encoding := responseEncoding(r.Header.Get(headerAcceptEncoding), conf.Encodings)
+			if encoding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: conf.MinSize}
+			defer cw.close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// acceptedEncodings lists the request encodings of a route, for the Accept-Encoding header of 415 responses
+func acceptedEncodings(conf config.Compression) string {
+	if conf.DecompressRequests {
+		return encodingGzip
+	}
+
+	return encodingIdentity
+}
+
+// responseEncoding returns the encoding the client prefers among those offered, in Accept-Encoding.
+// Encodings of the same quality are chosen in the order offered. It returns empty for identity
+func responseEncoding(acceptEncoding string, offered []string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			var err error
+			if q, err = strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err != nil {
+				q = 0
+			}
+		}
+
+		qualities[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range offered {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = qualities["*"]
+		}
+
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+// compressWriter buffers a response until it reaches the minimum size, then compresses it.
+// Smaller responses, responses without a body and responses already encoded are written as they are
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+
+	cw.status = status
+	if !bodyAllowed(status) || cw.Header().Get(headerContentEncoding) != "" {
+		cw.passThrough()
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if cw.enc != nil {
+		return cw.enc.Write(p)
+	}
+
+	if cw.decided {
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) < cw.minSize {
+		return len(p), nil
+	}
+
+	if err := cw.startCompression(); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (cw *compressWriter) startCompression() error {
+	enc, err := newEncoder(cw.encoding, cw.ResponseWriter)
+	if err != nil {
+		log.Errorf("Failed to create (%s) encoder. Error: %s", cw.encoding, err)
+		cw.passThrough()
+		return nil
+	}
+
+	h := cw.Header()
+	h.Set(headerContentEncoding, cw.encoding)
+	h.Del(headerContentLength)
+	// the compressed bytes differ from those the entity tag was computed on
+	if tag := h.Get(headerETag); tag != "" && !strings.HasPrefix(tag, "W/") {
+		h.Set(headerETag, "W/"+tag)
+	}
+
+	cw.decided = true
+	cw.enc = enc
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	_, err = enc.Write(buf)
+	return err
+}
+
+// passThrough writes the response as it is
+func (cw *compressWriter) passThrough() {
+	cw.decided = true
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) > 0 {
+		buf := cw.buf
+		cw.buf = nil
+		if _, err := cw.ResponseWriter.Write(buf); err != nil {
+			log.Error("Failed to write HTTP response")
+		}
+	}
+}
+
+// close writes what remains of the response
+func (cw *compressWriter) close() {
+	switch {
+	case cw.enc != nil:
+		if err := cw.enc.Close(); err != nil {
+			log.Errorf("Failed to close (%s) encoder. Error: %s", cw.encoding, err)
+			return
+		}
+
+		encoderPools[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	case !cw.decided && cw.status != 0:
+		cw.passThrough()
+	}
+}
+
+func bodyAllowed(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// gzipBody is a gzip request body, whose errors of decompression are those of an invalid request
+type gzipBody struct {
+	body io.ReadCloser
+	gz   *gzip.Reader
+}
+
+// newDecompressedBody returns the decompressed body of a gzip request body, which fails with a TooLarge error once
+// more than max bytes are decompressed, so small requests can't expand into bodies that exhaust memory
+func newDecompressedBody(body io.ReadCloser, max int64) (io.ReadCloser, error) {
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, invalidGzip(err)
+	}
+
+	return &limitedBody{ReadCloser: &gzipBody{body: body, gz: gz}, max: max, name: "Decompressed request body"}, nil
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	n, err := b.gz.Read(p)
+	if err != nil && err != io.EOF {
+		return n, invalidGzip(err)
+	}
+
+	return n, err
+}
+
+func (b *gzipBody) Close() error {
+	b.gz.Close()
+	return b.body.Close()
+}
+
+// invalidGzip marks errors of decompression as errors of the request. Errors of the compressed body, such as it
+// exceeding the size limit of the route, are kept as they are
+func invalidGzip(err error) error {
+	if _, ok := err.(*errors.Err); ok {
+		return err
+	}
+
+	return errors.Wrap(err, "Invalid gzip request body").SetType(errors.ErrorTypeBadRequest)
+}
@@ -0,0 +1,280 @@
+package rest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"microservice/internal/pkg/config"
+
+	"github.com/go-chi/chi"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestResponseEncoding(t *testing.T) {
+	offered := []string{encodingZstd, encodingGzip, encodingDeflate}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+	}{
+		{
+			name:           "no accept encoding header expect identity",
+			acceptEncoding: "",
+			want:           "",
+		},
+		{
+			name:           "single offered encoding expect it",
+			acceptEncoding: "gzip",
+			want:           encodingGzip,
+		},
+		{
+			name:           "several encodings of the same quality expect the first offered",
+			acceptEncoding: "gzip, deflate, zstd",
+			want:           encodingZstd,
+		},
+		{
+			name:           "highest quality encoding expect it",
+			acceptEncoding: "zstd;q=0.5, deflate;q=0.8, gzip;q=0.7",
+			want:           encodingDeflate,
+		},
+		{
+			name:           "any encoding except one refused expect the next offered",
+			acceptEncoding: "*, zstd;q=0",
+			want:           encodingGzip,
+		},
+		{
+			name:           "only encodings not offered expect identity",
+			acceptEncoding: "br, compress",
+			want:           "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := responseEncoding(tt.acceptEncoding, offered); got != tt.want {
+				t.Errorf("responseEncoding() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdapter_compress(t *testing.T) {
+	large := bytes.Repeat([]byte(`{"key":"value"}`), 200)
+	small := []byte(`{"key":"value"}`)
+
+	compression := config.Compression{
+		Enabled:             true,
+		MinSize:             1024,
+		Encodings:           []string{encodingZstd, encodingGzip, encodingDeflate},
+		DecompressRequests:  true,
+		MaxDecompressedSize: 4096,
+	}
+
+	tests := []struct {
+		name                string
+		compression         config.Compression
+		requestHeaders      map[string]string
+		requestBody         []byte
+		responseBody        []byte
+		wantedStatusCode    int
+		wantContentEncoding string
+	}{
+		{
+			name:                "large response accepting gzip expect gzip response",
+			compression:         compression,
+			requestHeaders:      map[string]string{headerAcceptEncoding: "gzip"},
+			responseBody:        large,
+			wantedStatusCode:    http.StatusOK,
+			wantContentEncoding: encodingGzip,
+		},
+		{
+			name:                "large response accepting deflate expect deflate response",
+			compression:         compression,
+			requestHeaders:      map[string]string{headerAcceptEncoding: "deflate"},
+			responseBody:        large,
+			wantedStatusCode:    http.StatusOK,
+			wantContentEncoding: encodingDeflate,
+		},
+		{
+			name:                "large response accepting zstd expect zstd response",
+			compression:         compression,
+			requestHeaders:      map[string]string{headerAcceptEncoding: "gzip, zstd"},
+			responseBody:        large,
+			wantedStatusCode:    http.StatusOK,
+			wantContentEncoding: encodingZstd,
+		},
+		{
+			name:             "response below minimum size expect uncompressed response",
+			compression:      compression,
+			requestHeaders:   map[string]string{headerAcceptEncoding: "gzip"},
+			responseBody:     small,
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "compression disabled on route expect uncompressed response",
+			compression:      config.Compression{},
+			requestHeaders:   map[string]string{headerAcceptEncoding: "gzip"},
+			responseBody:     large,
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "gzip request body expect decompressed body",
+			compression:      compression,
+			requestHeaders:   map[string]string{headerContentEncoding: "gzip"},
+			requestBody:      gzipped(t, small),
+			responseBody:     small,
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "gzip request body larger than limit once decompressed expect status request entity too large (413)",
+			compression:      compression,
+			requestHeaders:   map[string]string{headerContentEncoding: "gzip"},
+			requestBody:      gzipped(t, bytes.Repeat([]byte(" "), 1<<20)),
+			wantedStatusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:             "invalid gzip request body expect status bad request (400)",
+			compression:      compression,
+			requestHeaders:   map[string]string{headerContentEncoding: "gzip"},
+			requestBody:      small,
+			wantedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:             "gzip request body on route without decompression expect status unsupported media type (415)",
+			compression:      config.Compression{Enabled: true, MinSize: 1024, Encodings: []string{encodingGzip}},
+			requestHeaders:   map[string]string{headerContentEncoding: "gzip"},
+			requestBody:      gzipped(t, small),
+			wantedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:             "unsupported request encoding expect status unsupported media type (415)",
+			compression:      compression,
+			requestHeaders:   map[string]string{headerContentEncoding: "br"},
+			requestBody:      small,
+			wantedStatusCode: http.StatusUnsupportedMediaType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Adapter{
+				routes: config.Routes{config.DefaultRoute: config.Route{Compression: tt.compression}},
+			}
+
+			r := chi.NewRouter()
+			r.With(s.compress(routeAddDocument)).Post("/documents", func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					returnReadBodyError(w, err)
+					return
+				}
+
+				// echo the request body as the handler read it
+				if len(body) == 0 {
+					body = tt.responseBody
+				}
+				httpReturn(w, http.StatusOK, body)
+			})
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, body := testRequestWithHeaders(t, ts, http.MethodPost, "/documents", tt.requestHeaders, bytes.NewReader(tt.requestBody))
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+			if tt.wantedStatusCode != http.StatusOK {
+				return
+			}
+
+			if got := res.Header.Get(headerContentEncoding); got != tt.wantContentEncoding {
+				t.Fatalf("compress() Content-Encoding = %v, want %v", got, tt.wantContentEncoding)
+			}
+
+			if tt.compression.Enabled && !strings.Contains(strings.Join(res.Header.Values(headerVary), ","), headerAcceptEncoding) {
+				t.Errorf("compress() Vary = %v, want it to contain %v", res.Header.Values(headerVary), headerAcceptEncoding)
+			}
+
+			if got := decompressed(t, tt.wantContentEncoding, body); !bytes.Equal(got, tt.responseBody) {
+				t.Errorf("compress() body = %s, want %s", got, tt.responseBody)
+			}
+		})
+	}
+}
+
+func TestNewEncoder_reused(t *testing.T) {
+	responses := [][]byte{bytes.Repeat([]byte(`{"key":"value"}`), 200), []byte(`{"other":"response"}`)}
+
+	for _, encoding := range []string{encodingGzip, encodingDeflate, encodingZstd} {
+		t.Run(encoding+" encoder closed and reused expect every response decodable", func(t *testing.T) {
+			for _, response := range responses {
+				var buf bytes.Buffer
+				cw := &compressWriter{ResponseWriter: httptest.NewRecorder(), encoding: encoding}
+
+				enc, err := newEncoder(encoding, &buf)
+				if err != nil {
+					t.Fatalf("newEncoder() error = %v", err)
+				}
+				cw.enc = enc
+
+				if _, err := enc.Write(response); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+				cw.close()
+
+				if got := decompressed(t, encoding, buf.Bytes()); !bytes.Equal(got, response) {
+					t.Errorf("encoder wrote %s, want %s", got, response)
+				}
+			}
+		})
+	}
+
+	if _, err := newEncoder("br", ioutil.Discard); err == nil {
+		t.Error("newEncoder() error = nil, want error of the unsupported encoding")
+	}
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func decompressed(t *testing.T, encoding string, data []byte) []byte {
+	var (
+		r   io.Reader
+		err error
+	)
+
+	switch encoding {
+	case encodingGzip:
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	case encodingDeflate:
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	case encodingZstd:
+		r, err = zstd.NewReader(bytes.NewReader(data))
+	default:
+		return data
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
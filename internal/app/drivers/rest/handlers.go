@@ -1,9 +1,16 @@
 package rest
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"microservice/internal/pkg/errors"
 	"microservice/models"
@@ -13,21 +20,51 @@ import (
 )
 
 const (
-	urlParamID = "id"
+	urlParamID   = "id"
+	urlParamName = "name"
+
+	queryParamFields  = "fields"
+	queryParamExclude = "exclude"
+
+	queryParamText   = "q"
+	queryParamFilter = "filter"
+	queryParamLimit  = "limit"
+
+	headerContentType = "Content-Type"
+	headerAcceptPatch = "Accept-Patch"
+
+	headerIdempotencyKey = "Idempotency-Key"
+
+	headerCacheControl  = "Cache-Control"
+	cacheControlNoCache = "no-cache"
+
+	headerETag            = "ETag"
+	headerLastModified    = "Last-Modified"
+	headerIfNoneMatch     = "If-None-Match"
+	headerIfModifiedSince = "If-Modified-Since"
 )
 
 func (s *Adapter) getDocument(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, urlParamID)
-	doc, err := s.domainSvc.GetDocument(ctx, id)
+
+	c, ok := responseCodec(r.Header.Get(headerAccept))
+	if !ok {
+		log.Debugf("Unacceptable media types (%s)", r.Header.Get(headerAccept))
+		returnHTTPError(w, http.StatusNotAcceptable, fmt.Sprintf("None of the accepted media types (%s) is supported, expected one of (%s)", r.Header.Get(headerAccept), supportedMediaTypes()))
+		return
+	}
+	doc, err := s.domainSvc.GetDocument(ctx, id, readOptions(r))
 	if err != nil {
-		if errors.IsType(err, errors.ErrorTypeNotFound) {
+		if returnAuthorizationError(w, err) || returnUnavailableError(w, err) {
+			return
+		} else if errors.IsType(err, errors.ErrorTypeNotFound) {
 			log.Debugf("Could not found document with id (%s)", id)
 			returnHTTPError(w, http.StatusNotFound, err.Error())
 			return
 		} else if errors.IsType(err, errors.ErrorTypeBadRequest) {
 			log.Debugf("Failed to get document with id (%s) from domain. Error: %s", id, err)
-			returnHTTPError(w, http.StatusBadRequest, "Invalid request id")
+			returnHTTPError(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
@@ -36,6 +73,55 @@ func (s *Adapter) getDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	b, err := json.Marshal(doc)
+	if err != nil {
+		log.Errorf("Failed to marshal document (%+v). Error: %s", doc, err)
+		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	b, err = c.fromJSON(b)
+	if err != nil {
+		log.Errorf("Failed to encode document (%+v) as (%s). Error: %s", doc, c.mediaType, err)
+		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Add(headerVary, headerAccept)
+	tag := etag(b)
+	w.Header().Set(headerETag, tag)
+	if !doc.ModifiedAt.IsZero() {
+		w.Header().Set(headerLastModified, doc.ModifiedAt.UTC().Format(http.TimeFormat))
+	}
+	if s.cacheControl != "" {
+		w.Header().Set(headerCacheControl, s.cacheControl)
+	}
+
+	if notModified(r, tag, doc.ModifiedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	httpReturnAs(w, http.StatusOK, c.mediaType, b)
+}
+
+func (s *Adapter) getDocumentByName(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := chi.URLParam(r, urlParamName)
+	doc, err := s.domainSvc.GetDocumentByName(ctx, name)
+	if err != nil {
+		if returnAuthorizationError(w, err) || returnUnavailableError(w, err) {
+			return
+		} else if errors.IsType(err, errors.ErrorTypeNotFound) {
+			log.Debugf("Could not found document with name (%s)", name)
+			returnHTTPError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		log.Errorf("Failed to get document with name (%s) from domain. Error: %s", name, err)
+		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
 	b, err := json.Marshal(doc)
 	if err != nil {
 		log.Errorf("Failed to marshal document (%+v). Error: %s", doc, err)
@@ -45,15 +131,214 @@ func (s *Adapter) getDocument(w http.ResponseWriter, r *http.Request) {
 	httpReturn(w, http.StatusOK, b)
 }
 
+// searchResults is the response body of a search
+type searchResults struct {
+	Results []models.SearchResult `json:"results"`
+}
+
+func (s *Adapter) searchDocuments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	c, ok := responseCodec(r.Header.Get(headerAccept))
+	if !ok {
+		log.Debugf("Unacceptable media types (%s)", r.Header.Get(headerAccept))
+		returnHTTPError(w, http.StatusNotAcceptable, fmt.Sprintf("None of the accepted media types (%s) is supported, expected one of (%s)", r.Header.Get(headerAccept), supportedMediaTypes()))
+		return
+	}
+
+	query, err := searchQuery(r)
+	if err != nil {
+		log.Debugf("Invalid search request. Error: %s", err)
+		returnHTTPError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, err := s.domainSvc.SearchDocuments(ctx, query)
+	if err != nil {
+		if returnAuthorizationError(w, err) || returnUnavailableError(w, err) {
+			return
+		} else if errors.IsType(err, errors.ErrorTypeBadRequest) {
+			log.Debugf("Invalid search request. Error: %s", err)
+			returnHTTPError(w, http.StatusBadRequest, err.Error())
+			return
+		} else if errors.IsType(err, errors.ErrorTypeUnprocessable) {
+			log.Warnf("Failed to search documents. Error: %s", err)
+			returnHTTPError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		log.Errorf("Failed to search documents. Error: %s", err)
+		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	if results == nil {
+		results = []models.SearchResult{}
+	}
+
+	b, err := json.Marshal(searchResults{Results: results})
+	if err != nil {
+		log.Errorf("Failed to marshal search results. Error: %s", err)
+		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	b, err = c.fromJSON(b)
+	if err != nil {
+		log.Errorf("Failed to encode search results as (%s). Error: %s", c.mediaType, err)
+		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Add(headerVary, headerAccept)
+	httpReturnAs(w, http.StatusOK, c.mediaType, b)
+}
+
 func (s *Adapter) addDocument(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	body, err := ioutil.ReadAll(r.Body)
+	doc, ok := s.readDocument(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := s.domainSvc.AddDocument(ctx, doc, r.Header.Get(headerIdempotencyKey))
 	if err != nil {
-		log.Errorf("Failed to read request body. Error: %s", err)
+		if returnAuthorizationError(w, err) || returnUnavailableError(w, err) || returnDocumentLimitError(w, err) {
+			return
+		} else if errors.IsType(err, errors.ErrorTypeQuotaExceeded) {
+			log.Debugf("Document (%v) exceeds tenant quota. Error: %s", doc, err)
+			returnHTTPError(w, http.StatusForbidden, err.Error())
+			return
+		} else if errors.IsType(err, errors.ErrorTypeUnprocessable) {
+			log.Debugf("Idempotency key was reused with a different document (%v). Error: %s", doc, err)
+			returnHTTPError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		} else if errors.IsType(err, errors.ErrorTypeConflict) {
+			log.Debugf("Request with the same idempotency key is in progress. Error: %s", err)
+			returnHTTPError(w, http.StatusConflict, err.Error())
+			return
+		} else if errors.IsType(err, errors.ErrorTypeBadRequest) {
+			log.Debugf("Invalid add document request. Error: %s", err)
+			returnHTTPError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		log.Errorf("Failed to validate request body: %s", err)
+		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	httpReturn(w, http.StatusOK, []byte(id))
+}
+
+func (s *Adapter) putDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, urlParamID)
+	doc, ok := s.readDocument(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := s.domainSvc.PutDocument(ctx, id, doc)
+	if err != nil {
+		if returnAuthorizationError(w, err) || returnUnavailableError(w, err) || returnDocumentLimitError(w, err) {
+			return
+		} else if errors.IsType(err, errors.ErrorTypeQuotaExceeded) {
+			log.Debugf("Document with id (%s) exceeds tenant quota. Error: %s", id, err)
+			returnHTTPError(w, http.StatusForbidden, err.Error())
+			return
+		} else if errors.IsType(err, errors.ErrorTypeConflict) {
+			log.Debugf("Document with id (%s) was modified concurrently. Error: %s", id, err)
+			returnHTTPError(w, http.StatusConflict, err.Error())
+			return
+		} else if errors.IsType(err, errors.ErrorTypeBadRequest) {
+			log.Debugf("Failed to put document with id (%s). Error: %s", id, err)
+			returnHTTPError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		log.Errorf("Failed to put document with id (%s): %s", id, err)
+		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	httpReturn(w, status, []byte(id))
+}
+
+func (s *Adapter) upsertDocumentByName(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := chi.URLParam(r, urlParamName)
+	doc, ok := s.readDocument(w, r)
+	if !ok {
+		return
+	}
+
+	if doc.Name != name {
+		log.Debugf("Document name (%s) doesn't match the name in the url (%s)", doc.Name, name)
+		returnHTTPError(w, http.StatusBadRequest, fmt.Sprintf("Document name (%s) doesn't match the name in the url (%s)", doc.Name, name))
+		return
+	}
+
+	id, created, err := s.domainSvc.UpsertDocument(ctx, doc)
+	if err != nil {
+		if returnAuthorizationError(w, err) || returnUnavailableError(w, err) || returnDocumentLimitError(w, err) {
+			return
+		} else if errors.IsType(err, errors.ErrorTypeQuotaExceeded) {
+			log.Debugf("Document with name (%s) exceeds tenant quota. Error: %s", name, err)
+			returnHTTPError(w, http.StatusForbidden, err.Error())
+			return
+		} else if errors.IsType(err, errors.ErrorTypeConflict) {
+			log.Debugf("Document with name (%s) was modified concurrently. Error: %s", name, err)
+			returnHTTPError(w, http.StatusConflict, err.Error())
+			return
+		} else if errors.IsType(err, errors.ErrorTypeBadRequest) {
+			log.Debugf("Failed to upsert document with name (%s). Error: %s", name, err)
+			returnHTTPError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		log.Errorf("Failed to upsert document with name (%s): %s", name, err)
 		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
 		return
 	}
 
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	httpReturn(w, status, []byte(id))
+}
+
+// readDocument reads the document of the request body and validates it against the document schema.
+// It writes the error response and returns false if the body is not a valid document
+func (s *Adapter) readDocument(w http.ResponseWriter, r *http.Request) (models.Document, bool) {
+	c, ok := requestCodec(r.Header.Get(headerContentType))
+	if !ok {
+		log.Debugf("Unsupported content type (%s)", r.Header.Get(headerContentType))
+		returnHTTPError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported content type (%s), expected one of (%s)", r.Header.Get(headerContentType), supportedMediaTypes()))
+		return models.Document{}, false
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		returnReadBodyError(w, err)
+		return models.Document{}, false
+	}
+
+	// the schema applies to the decoded document, whatever its encoding
+	body, err = c.toJSON(body)
+	if err != nil {
+		log.Debugf("Failed to decode request body as (%s). Error: %s", c.mediaType, err)
+		returnHTTPError(w, http.StatusBadRequest, fmt.Sprintf("Invalid (%s) request body", c.mediaType))
+		return models.Document{}, false
+	}
+
 	if err := s.jsonSchema.ValidateSchemaFromBytes(postDocumentSchemaName, body); err != nil {
 		if errors.IsType(err, errors.ErrorTypeBadRequest) {
 			log.Debugf("Invalid schema: %s", err)
@@ -62,28 +347,262 @@ func (s *Adapter) addDocument(w http.ResponseWriter, r *http.Request) {
 			log.Errorf("Failed to validate request body: %s", err)
 			returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
 		}
-		return
+		return models.Document{}, false
 	}
 
 	var doc models.Document
 	if err := json.Unmarshal(body, &doc); err != nil {
 		log.Debugf("Failed to unmarshal document. Error: %s", err)
 		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return models.Document{}, false
+	}
+
+	return doc, true
+}
+
+func (s *Adapter) patchDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, urlParamID)
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get(headerContentType))
+	patchType := models.PatchType(mediaType)
+	if err != nil || (patchType != models.PatchTypeJSONPatch && patchType != models.PatchTypeMergePatch) {
+		log.Debugf("Unsupported patch content type (%s)", r.Header.Get(headerContentType))
+		w.Header().Set(headerAcceptPatch, strings.Join([]string{string(models.PatchTypeJSONPatch), string(models.PatchTypeMergePatch)}, ", "))
+		returnHTTPError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported patch content type (%s)", r.Header.Get(headerContentType)))
 		return
 	}
 
-	id, err := s.domainSvc.AddDocument(ctx, doc)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		log.Errorf("Failed to validate request body: %s", err)
+		returnReadBodyError(w, err)
+		return
+	}
+
+	doc, err := s.domainSvc.PatchDocument(ctx, id, patchType, body)
+	if err != nil {
+		if returnAuthorizationError(w, err) || returnUnavailableError(w, err) || returnDocumentLimitError(w, err) {
+			return
+		}
+
+		switch {
+		case errors.IsType(err, errors.ErrorTypeNotFound):
+			log.Debugf("Could not found document with id (%s)", id)
+			returnHTTPError(w, http.StatusNotFound, err.Error())
+		case errors.IsType(err, errors.ErrorTypeUnprocessable):
+			log.Debugf("Failed to apply patch to document with id (%s). Error: %s", id, err)
+			returnHTTPError(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.IsType(err, errors.ErrorTypeQuotaExceeded):
+			log.Debugf("Patched document with id (%s) exceeds tenant quota. Error: %s", id, err)
+			returnHTTPError(w, http.StatusForbidden, err.Error())
+		case errors.IsType(err, errors.ErrorTypeConflict):
+			log.Debugf("Conflict while patching document with id (%s). Error: %s", id, err)
+			returnHTTPError(w, http.StatusConflict, err.Error())
+		case errors.IsType(err, errors.ErrorTypeBadRequest):
+			log.Debugf("Invalid patch for document with id (%s). Error: %s", id, err)
+			returnHTTPError(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Errorf("Failed to patch document with id (%s). Error: %s", id, err)
+			returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+		return
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		log.Errorf("Failed to marshal document (%+v). Error: %s", doc, err)
 		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
 		return
 	}
+	httpReturn(w, http.StatusOK, b)
+}
 
-	httpReturn(w, http.StatusOK, []byte(id))
+// live reports that the service is running, regardless of its dependencies
+func (s *Adapter) live(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// ready reports whether the service can serve requests, responding with 503 while a dependency isn't ready
+func (s *Adapter) ready(w http.ResponseWriter, r *http.Request) {
+	health := models.Health{Ready: true, Checks: s.healthChecker.CheckHealth(r.Context())}
+	for _, check := range health.Checks {
+		health.Ready = health.Ready && check.Ready
+	}
+
+	b, err := json.Marshal(health)
+	if err != nil {
+		log.Errorf("Failed to marshal health (%+v). Error: %s", health, err)
+		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	statusCode := http.StatusOK
+	if !health.Ready {
+		statusCode = http.StatusServiceUnavailable
+	}
+	httpReturn(w, statusCode, b)
+}
+
+// etag returns a strong entity tag of a response body
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// readOptions returns the fields a read request selects with the fields and exclude query parameters,
+// comma separated lists of field paths
+func readOptions(r *http.Request) models.ReadOptions {
+	split := func(param string) []string {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			return nil
+		}
+
+		paths := strings.Split(value, ",")
+		for i, path := range paths {
+			paths[i] = strings.TrimSpace(path)
+		}
+		return paths
+	}
+
+	return models.ReadOptions{Fields: split(queryParamFields), Exclude: split(queryParamExclude), Fresh: noCache(r)}
+}
+
+// searchQuery returns the search of a request: the text of the q query parameter, the json filter of the filter
+// query parameter, the number of results of the limit query parameter, and the fields of the results selected as
+// in reads of a document
+func searchQuery(r *http.Request) (models.SearchQuery, error) {
+	params := r.URL.Query()
+	query := models.SearchQuery{Text: params.Get(queryParamText), ReadOptions: readOptions(r)}
+
+	if filter := params.Get(queryParamFilter); filter != "" {
+		d := json.NewDecoder(strings.NewReader(filter))
+		d.UseNumber()
+		// fields outside the filter language are refused rather than ignored
+		d.DisallowUnknownFields()
+
+		query.Filter = &models.Filter{}
+		if err := d.Decode(query.Filter); err != nil {
+			return models.SearchQuery{}, errors.Errorf("Invalid filter: %s", err).SetType(errors.ErrorTypeBadRequest)
+		}
+	}
+
+	if limit := params.Get(queryParamLimit); limit != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			return models.SearchQuery{}, errors.Errorf("Invalid limit (%s), expected a number", limit).SetType(errors.ErrorTypeBadRequest)
+		}
+	}
+
+	return query, nil
+}
+
+// notModified evaluates the conditional headers of a read request against the current entity tag and modification
+// time of a document. If-Modified-Since is only evaluated when the request has no If-None-Match (RFC 7232, section 6)
+func notModified(r *http.Request, tag string, modifiedAt time.Time) bool {
+	if ifNoneMatch := r.Header.Get(headerIfNoneMatch); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == tag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if modifiedAt.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get(headerIfModifiedSince))
+	if err != nil {
+		return false
+	}
+
+	return !modifiedAt.Truncate(time.Second).After(since)
+}
+
+// noCache reports whether the client asked for a fresh response, that isn't served from a cache
+func noCache(r *http.Request) bool {
+	for _, directive := range strings.Split(r.Header.Get(headerCacheControl), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), cacheControlNoCache) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// returnAuthorizationError writes the response of authentication and authorization errors.
+// It reports whether err was such an error
+func returnAuthorizationError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.IsType(err, errors.ErrorTypeUnauthorized):
+		log.Debugf("Unauthenticated request. Error: %s", err)
+		w.Header().Set(headerWWWAuthenticate, authenticateChallenge)
+		returnProblem(w, err)
+		return true
+	case errors.IsType(err, errors.ErrorTypeForbidden):
+		log.Debugf("Forbidden request. Error: %s", err)
+		returnHTTPError(w, http.StatusForbidden, err.Error())
+		return true
+	default:
+		return false
+	}
+}
+
+// returnReadBodyError writes the response of errors reading a request body, which is too large when it exceeds a
+// limit of the route and invalid when it can't be decoded from its content encoding
+func returnReadBodyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.IsType(err, errors.ErrorTypeTooLarge):
+		log.Debugf("Request body too large. Error: %s", err)
+		returnHTTPError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.IsType(err, errors.ErrorTypeBadRequest):
+		log.Debugf("Invalid request body. Error: %s", err)
+		returnHTTPError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Errorf("Failed to read request body. Error: %s", err)
+		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+// returnDocumentLimitError writes the response of documents that can't be stored, as they are too large or nested
+// too deep. It reports whether err was such an error
+func returnDocumentLimitError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.IsType(err, errors.ErrorTypeDocumentTooLarge):
+		log.Debugf("Document too large. Error: %s", err)
+		returnHTTPError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return true
+	case errors.IsType(err, errors.ErrorTypeDocumentTooDeep):
+		log.Debugf("Document nested too deep. Error: %s", err)
+		returnHTTPError(w, http.StatusUnprocessableEntity, err.Error())
+		return true
+	default:
+		return false
+	}
+}
+
+// returnUnavailableError writes the response of errors of dependencies that can't be reached.
+// It reports whether err was such an error
+func returnUnavailableError(w http.ResponseWriter, err error) bool {
+	if !errors.IsType(err, errors.ErrorTypeUnavailable) {
+		return false
+	}
+
+	log.Warnf("Dependency unavailable. Error: %s", err)
+	returnHTTPError(w, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+	return true
 }
 
 func httpReturn(w http.ResponseWriter, statusCode int, body []byte) {
-	w.Header().Set("Content-Type", "application/json")
+	httpReturnAs(w, statusCode, mediaTypeJSON, body)
+}
+
+func httpReturnAs(w http.ResponseWriter, statusCode int, contentType string, body []byte) {
+	w.Header().Set(headerContentType, contentType)
 	w.WriteHeader(statusCode)
 	if _, err := w.Write(body); err != nil {
 		log.Error("Failed to write HTTP response")
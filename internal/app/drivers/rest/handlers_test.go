@@ -11,7 +11,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"microservice/mocks"
 
@@ -56,6 +58,16 @@ func TestAdapter_getDocument(t *testing.T) {
 		err:   errors.New("some-error").SetType(errors.ErrorTypeInternal),
 	}
 
+	forbidden := domainServiceGetDocumentMockData{
+		times: 1,
+		err:   errors.New("forbidden").SetType(errors.ErrorTypeForbidden),
+	}
+
+	databaseUnavailable := domainServiceGetDocumentMockData{
+		times: 1,
+		err:   errors.New("unavailable").SetType(errors.ErrorTypeUnavailable),
+	}
+
 	GetInvalidDocument := domainServiceGetDocumentMockData{
 		times: 1,
 		err:   nil,
@@ -65,6 +77,9 @@ func TestAdapter_getDocument(t *testing.T) {
 	tests := []struct {
 		name                       string
 		domainServiceGetDocumentMD domainServiceGetDocumentMockData
+		query                      string
+		headers                    map[string]string
+		wantOpts                   models.ReadOptions
 		wantedStatusCode           int
 		wantErr                    bool
 	}{
@@ -74,6 +89,30 @@ func TestAdapter_getDocument(t *testing.T) {
 			wantedStatusCode:           http.StatusOK,
 			wantErr:                    false,
 		},
+		{
+			name:                       "get selected fields of document expect fields passed and status OK (200)",
+			domainServiceGetDocumentMD: successfulGetValidDocument,
+			query:                      "?fields=name,%20doc.address.city",
+			wantOpts:                   models.ReadOptions{Fields: []string{"name", "doc.address.city"}},
+			wantedStatusCode:           http.StatusOK,
+			wantErr:                    false,
+		},
+		{
+			name:                       "get document excluding fields expect excluded fields passed and status OK (200)",
+			domainServiceGetDocumentMD: successfulGetValidDocument,
+			query:                      "?exclude=doc.secret",
+			wantOpts:                   models.ReadOptions{Exclude: []string{"doc.secret"}},
+			wantedStatusCode:           http.StatusOK,
+			wantErr:                    false,
+		},
+		{
+			name:                       "get document with no-cache expect fresh read and status OK (200)",
+			domainServiceGetDocumentMD: successfulGetValidDocument,
+			headers:                    map[string]string{headerCacheControl: "no-cache"},
+			wantOpts:                   models.ReadOptions{Fresh: true},
+			wantedStatusCode:           http.StatusOK,
+			wantErr:                    false,
+		},
 		{
 			name:                       "get bad id expect status bad request (400)",
 			domainServiceGetDocumentMD: badRequest,
@@ -86,12 +125,24 @@ func TestAdapter_getDocument(t *testing.T) {
 			wantedStatusCode:           http.StatusNotFound,
 			wantErr:                    true,
 		},
+		{
+			name:                       "principal not allowed to read documents expect status forbidden (403)",
+			domainServiceGetDocumentMD: forbidden,
+			wantedStatusCode:           http.StatusForbidden,
+			wantErr:                    true,
+		},
 		{
 			name:                       "failed to get document from db expect status internal server error (500)",
 			domainServiceGetDocumentMD: failedToGetDocument,
 			wantedStatusCode:           http.StatusInternalServerError,
 			wantErr:                    true,
 		},
+		{
+			name:                       "database not reachable expect status service unavailable (503)",
+			domainServiceGetDocumentMD: databaseUnavailable,
+			wantedStatusCode:           http.StatusServiceUnavailable,
+			wantErr:                    true,
+		},
 		{
 			name:                       "failed to marshal response document from db expect status internal server error (500)",
 			domainServiceGetDocumentMD: GetInvalidDocument,
@@ -107,7 +158,7 @@ func TestAdapter_getDocument(t *testing.T) {
 			id := uuid.New().String()
 
 			domainService := mocks.NewMockDomainService(c)
-			domainService.EXPECT().GetDocument(gomock.Any(), id).
+			domainService.EXPECT().GetDocument(gomock.Any(), id, tt.wantOpts).
 				Times(tt.domainServiceGetDocumentMD.times).
 				Return(tt.domainServiceGetDocumentMD.doc, tt.domainServiceGetDocumentMD.err)
 
@@ -123,7 +174,7 @@ func TestAdapter_getDocument(t *testing.T) {
 			ts := httptest.NewServer(r)
 			defer ts.Close()
 
-			res, body := testRequest(t, ts, http.MethodGet, fmt.Sprintf("/documents/%s", id), nil)
+			res, body := testRequestWithHeaders(t, ts, http.MethodGet, fmt.Sprintf("/documents/%s%s", id, tt.query), tt.headers, nil)
 			statusCodeCheck(t, res, tt.wantedStatusCode)
 
 			if tt.wantErr {
@@ -189,11 +240,37 @@ func TestAdapter_addDocument(t *testing.T) {
 		err:   errors.New("some-error"),
 	}
 
+	forbiddenToAddDocument := domainServiceAddDocumentMockData{
+		times: 1,
+		err:   errors.New("forbidden").SetType(errors.ErrorTypeForbidden),
+	}
+
+	reusedIdempotencyKey := domainServiceAddDocumentMockData{
+		times: 1,
+		err:   errors.New("reused key").SetType(errors.ErrorTypeUnprocessable),
+	}
+
+	inProgressIdempotencyKey := domainServiceAddDocumentMockData{
+		times: 1,
+		err:   errors.New("in progress").SetType(errors.ErrorTypeConflict),
+	}
+
+	documentTooLarge := domainServiceAddDocumentMockData{
+		times: 1,
+		err:   errors.New("too large").SetType(errors.ErrorTypeDocumentTooLarge),
+	}
+
+	documentTooDeep := domainServiceAddDocumentMockData{
+		times: 1,
+		err:   errors.New("too deep").SetType(errors.ErrorTypeDocumentTooDeep),
+	}
+
 	tests := []struct {
 		name                       string
 		jsonSchemaValidatorMD      jsonSchemaValidatorMockData
 		domainServiceAddDocumentMD domainServiceAddDocumentMockData
 		body                       models.Document
+		idempotencyKey             string
 		wantedStatusCode           int
 		wantErr                    bool
 	}{
@@ -224,6 +301,57 @@ func TestAdapter_addDocument(t *testing.T) {
 			wantedStatusCode:      http.StatusInternalServerError,
 			wantErr:               true,
 		},
+		{
+			name:                       "principal not allowed to add documents expect status forbidden (403)",
+			jsonSchemaValidatorMD:      validJSONSchema,
+			domainServiceAddDocumentMD: forbiddenToAddDocument,
+			body:                       validDoc,
+			wantedStatusCode:           http.StatusForbidden,
+			wantErr:                    true,
+		},
+		{
+			name:                       "add document with idempotency key successfully expect status OK (200)",
+			jsonSchemaValidatorMD:      validJSONSchema,
+			domainServiceAddDocumentMD: successfulAddDocument,
+			body:                       validDoc,
+			idempotencyKey:             "key-1",
+			wantedStatusCode:           http.StatusOK,
+			wantErr:                    false,
+		},
+		{
+			name:                       "idempotency key reused with different document expect status unprocessable entity (422)",
+			jsonSchemaValidatorMD:      validJSONSchema,
+			domainServiceAddDocumentMD: reusedIdempotencyKey,
+			body:                       validDoc,
+			idempotencyKey:             "key-1",
+			wantedStatusCode:           http.StatusUnprocessableEntity,
+			wantErr:                    true,
+		},
+		{
+			name:                       "idempotency key of request in progress expect status conflict (409)",
+			jsonSchemaValidatorMD:      validJSONSchema,
+			domainServiceAddDocumentMD: inProgressIdempotencyKey,
+			body:                       validDoc,
+			idempotencyKey:             "key-1",
+			wantedStatusCode:           http.StatusConflict,
+			wantErr:                    true,
+		},
+		{
+			name:                       "document larger than mongodb stores expect status request entity too large (413)",
+			jsonSchemaValidatorMD:      validJSONSchema,
+			domainServiceAddDocumentMD: documentTooLarge,
+			body:                       validDoc,
+			wantedStatusCode:           http.StatusRequestEntityTooLarge,
+			wantErr:                    true,
+		},
+		{
+			name:                       "document nested deeper than mongodb stores expect status unprocessable entity (422)",
+			jsonSchemaValidatorMD:      validJSONSchema,
+			domainServiceAddDocumentMD: documentTooDeep,
+			body:                       validDoc,
+			wantedStatusCode:           http.StatusUnprocessableEntity,
+			wantErr:                    true,
+		},
 		{
 			name:                       "failed to add reported document to db expect status internal server error (500)",
 			jsonSchemaValidatorMD:      validJSONSchema,
@@ -246,7 +374,7 @@ func TestAdapter_addDocument(t *testing.T) {
 			id := uuid.New().String()
 
 			domainService := mocks.NewMockDomainService(c)
-			domainService.EXPECT().AddDocument(gomock.Any(), tt.body).
+			domainService.EXPECT().AddDocument(gomock.Any(), tt.body, tt.idempotencyKey).
 				Times(tt.domainServiceAddDocumentMD.times).
 				Return(id, tt.domainServiceAddDocumentMD.err)
 
@@ -268,7 +396,12 @@ func TestAdapter_addDocument(t *testing.T) {
 			ts := httptest.NewServer(r)
 			defer ts.Close()
 
-			res, resBody := testRequest(t, ts, http.MethodPost, "/documents", bytes.NewReader(reportedDocumentInByte))
+			headers := map[string]string{}
+			if tt.idempotencyKey != "" {
+				headers[headerIdempotencyKey] = tt.idempotencyKey
+			}
+
+			res, resBody := testRequestWithHeaders(t, ts, http.MethodPost, "/documents", headers, bytes.NewReader(reportedDocumentInByte))
 			statusCodeCheck(t, res, tt.wantedStatusCode)
 
 			if tt.wantErr {
@@ -282,11 +415,1021 @@ func TestAdapter_addDocument(t *testing.T) {
 	}
 }
 
-func testRequest(t *testing.T, ts *httptest.Server, method string, path string, body io.Reader) (*http.Response, []byte) {
-	url := ts.URL + path
-	req, err := http.NewRequest(method, url, body)
-	if err != nil {
-		t.Fatal(err)
+func TestAdapter_patchDocument(t *testing.T) {
+	type domainServicePatchDocumentMockData struct {
+		times int
+		err   error
+		doc   models.Document
+	}
+
+	patchedDoc := models.Document{
+		Name: "tamir",
+		Doc: map[string]interface{}{
+			"lastName": "Aviv",
+		},
+	}
+
+	successfulPatchDocument := domainServicePatchDocumentMockData{
+		times: 1,
+		err:   nil,
+		doc:   patchedDoc,
+	}
+
+	badRequest := domainServicePatchDocumentMockData{
+		times: 1,
+		err:   errors.New("bad-request").SetType(errors.ErrorTypeBadRequest),
+	}
+
+	documentNotFound := domainServicePatchDocumentMockData{
+		times: 1,
+		err:   errors.New("not-found").SetType(errors.ErrorTypeNotFound),
+	}
+
+	unprocessablePatch := domainServicePatchDocumentMockData{
+		times: 1,
+		err:   errors.New("unprocessable").SetType(errors.ErrorTypeUnprocessable),
+	}
+
+	conflict := domainServicePatchDocumentMockData{
+		times: 1,
+		err:   errors.New("conflict").SetType(errors.ErrorTypeConflict),
+	}
+
+	failedToPatchDocument := domainServicePatchDocumentMockData{
+		times: 1,
+		err:   errors.New("some-error").SetType(errors.ErrorTypeInternal),
+	}
+
+	tests := []struct {
+		name                         string
+		contentType                  string
+		domainServicePatchDocumentMD domainServicePatchDocumentMockData
+		wantedStatusCode             int
+		wantErr                      bool
+	}{
+		{
+			name:                         "json patch document successfully expect status OK (200)",
+			contentType:                  string(models.PatchTypeJSONPatch),
+			domainServicePatchDocumentMD: successfulPatchDocument,
+			wantedStatusCode:             http.StatusOK,
+			wantErr:                      false,
+		},
+		{
+			name:                         "merge patch document successfully expect status OK (200)",
+			contentType:                  string(models.PatchTypeMergePatch) + "; charset=utf-8",
+			domainServicePatchDocumentMD: successfulPatchDocument,
+			wantedStatusCode:             http.StatusOK,
+			wantErr:                      false,
+		},
+		{
+			name:             "unsupported content type expect status unsupported media type (415)",
+			contentType:      "application/json",
+			wantedStatusCode: http.StatusUnsupportedMediaType,
+			wantErr:          true,
+		},
+		{
+			name:                         "malformed patch expect status bad request (400)",
+			contentType:                  string(models.PatchTypeJSONPatch),
+			domainServicePatchDocumentMD: badRequest,
+			wantedStatusCode:             http.StatusBadRequest,
+			wantErr:                      true,
+		},
+		{
+			name:                         "id doesn't exist in db expect status not found (404)",
+			contentType:                  string(models.PatchTypeJSONPatch),
+			domainServicePatchDocumentMD: documentNotFound,
+			wantedStatusCode:             http.StatusNotFound,
+			wantErr:                      true,
+		},
+		{
+			name:                         "patch can't be applied expect status unprocessable entity (422)",
+			contentType:                  string(models.PatchTypeJSONPatch),
+			domainServicePatchDocumentMD: unprocessablePatch,
+			wantedStatusCode:             http.StatusUnprocessableEntity,
+			wantErr:                      true,
+		},
+		{
+			name:                         "document modified concurrently expect status conflict (409)",
+			contentType:                  string(models.PatchTypeMergePatch),
+			domainServicePatchDocumentMD: conflict,
+			wantedStatusCode:             http.StatusConflict,
+			wantErr:                      true,
+		},
+		{
+			name:                         "failed to patch document expect status internal server error (500)",
+			contentType:                  string(models.PatchTypeJSONPatch),
+			domainServicePatchDocumentMD: failedToPatchDocument,
+			wantedStatusCode:             http.StatusInternalServerError,
+			wantErr:                      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			id := uuid.New().String()
+			patch := []byte(`[{"op": "replace", "path": "/doc/lastName", "value": "Aviv"}]`)
+
+			domainService := mocks.NewMockDomainService(c)
+			domainService.EXPECT().PatchDocument(gomock.Any(), id, gomock.AssignableToTypeOf(models.PatchType("")), patch).
+				Times(tt.domainServicePatchDocumentMD.times).
+				Return(tt.domainServicePatchDocumentMD.doc, tt.domainServicePatchDocumentMD.err)
+
+			s := &Adapter{
+				domainSvc: domainService,
+			}
+
+			r := chi.NewRouter()
+			r.Route("/documents", func(r chi.Router) {
+				r.Patch("/{id}", s.patchDocument)
+			})
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			headers := map[string]string{headerContentType: tt.contentType}
+			res, body := testRequestWithHeaders(t, ts, http.MethodPatch, fmt.Sprintf("/documents/%s", id), headers, bytes.NewReader(patch))
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+
+			if tt.wantErr {
+				return
+			}
+
+			var respDoc models.Document
+			if err := json.Unmarshal(body, &respDoc); err != nil {
+				t.Fatalf("Failed to unmarshal response body to 'Document'. Error: %s", err)
+			}
+
+			if !reflect.DeepEqual(respDoc, tt.domainServicePatchDocumentMD.doc) {
+				t.Fatalf("patchDocument() got = %v, want %v", respDoc, tt.domainServicePatchDocumentMD.doc)
+			}
+		})
+	}
+}
+
+func TestAdapter_putDocument(t *testing.T) {
+	type jsonSchemaValidatorMockData struct {
+		times int
+		err   error
+	}
+
+	type domainServicePutDocumentMockData struct {
+		times   int
+		created bool
+		err     error
+	}
+
+	validDoc := models.Document{
+		Name: "tamir",
+		Doc: map[string]interface{}{
+			"lastName": "Aviv",
+		},
+	}
+
+	validJSONSchema := jsonSchemaValidatorMockData{
+		times: 1,
+		err:   nil,
+	}
+
+	invalidJSONSchema := jsonSchemaValidatorMockData{
+		times: 1,
+		err:   errors.New("bad-request").SetType(errors.ErrorTypeBadRequest),
+	}
+
+	createdDocument := domainServicePutDocumentMockData{
+		times:   1,
+		created: true,
+	}
+
+	replacedDocument := domainServicePutDocumentMockData{
+		times:   1,
+		created: false,
+	}
+
+	invalidID := domainServicePutDocumentMockData{
+		times: 1,
+		err:   errors.New("bad-request").SetType(errors.ErrorTypeBadRequest),
+	}
+
+	conflict := domainServicePutDocumentMockData{
+		times: 1,
+		err:   errors.New("conflict").SetType(errors.ErrorTypeConflict),
+	}
+
+	forbidden := domainServicePutDocumentMockData{
+		times: 1,
+		err:   errors.New("forbidden").SetType(errors.ErrorTypeForbidden),
+	}
+
+	failedToPutDocument := domainServicePutDocumentMockData{
+		times: 1,
+		err:   errors.New("some-error").SetType(errors.ErrorTypeInternal),
+	}
+
+	tests := []struct {
+		name                       string
+		jsonSchemaValidatorMD      jsonSchemaValidatorMockData
+		domainServicePutDocumentMD domainServicePutDocumentMockData
+		wantedStatusCode           int
+		wantErr                    bool
+	}{
+		{
+			name:                       "put new document expect status created (201)",
+			jsonSchemaValidatorMD:      validJSONSchema,
+			domainServicePutDocumentMD: createdDocument,
+			wantedStatusCode:           http.StatusCreated,
+			wantErr:                    false,
+		},
+		{
+			name:                       "put existing document expect status OK (200)",
+			jsonSchemaValidatorMD:      validJSONSchema,
+			domainServicePutDocumentMD: replacedDocument,
+			wantedStatusCode:           http.StatusOK,
+			wantErr:                    false,
+		},
+		{
+			name:                  "invalid document reported expect status bad request (400)",
+			jsonSchemaValidatorMD: invalidJSONSchema,
+			wantedStatusCode:      http.StatusBadRequest,
+			wantErr:               true,
+		},
+		{
+			name:                       "id doesn't match the id strategy expect status bad request (400)",
+			jsonSchemaValidatorMD:      validJSONSchema,
+			domainServicePutDocumentMD: invalidID,
+			wantedStatusCode:           http.StatusBadRequest,
+			wantErr:                    true,
+		},
+		{
+			name:                       "document modified concurrently expect status conflict (409)",
+			jsonSchemaValidatorMD:      validJSONSchema,
+			domainServicePutDocumentMD: conflict,
+			wantedStatusCode:           http.StatusConflict,
+			wantErr:                    true,
+		},
+		{
+			name:                       "principal not allowed to put document expect status forbidden (403)",
+			jsonSchemaValidatorMD:      validJSONSchema,
+			domainServicePutDocumentMD: forbidden,
+			wantedStatusCode:           http.StatusForbidden,
+			wantErr:                    true,
+		},
+		{
+			name:                       "failed to put document expect status internal server error (500)",
+			jsonSchemaValidatorMD:      validJSONSchema,
+			domainServicePutDocumentMD: failedToPutDocument,
+			wantedStatusCode:           http.StatusInternalServerError,
+			wantErr:                    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			reportedDocumentInByte, err := json.Marshal(validDoc)
+			if err != nil {
+				t.Fatalf("Failed to marshal document (%+v) from request body. Error: %s", validDoc, err)
+			}
+
+			id := uuid.New().String()
+
+			domainService := mocks.NewMockDomainService(c)
+			domainService.EXPECT().PutDocument(gomock.Any(), id, validDoc).
+				Times(tt.domainServicePutDocumentMD.times).
+				Return(tt.domainServicePutDocumentMD.created, tt.domainServicePutDocumentMD.err)
+
+			jsonSchemaValidator := mocks.NewMockJSONSchemaValidator(c)
+			jsonSchemaValidator.EXPECT().ValidateSchemaFromBytes(postDocumentSchemaName, reportedDocumentInByte).
+				Times(tt.jsonSchemaValidatorMD.times).
+				Return(tt.jsonSchemaValidatorMD.err)
+
+			s := &Adapter{
+				domainSvc:  domainService,
+				jsonSchema: jsonSchemaValidator,
+			}
+
+			r := chi.NewRouter()
+			r.Route("/documents", func(r chi.Router) {
+				r.Put("/{id}", s.putDocument)
+			})
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, resBody := testRequest(t, ts, http.MethodPut, fmt.Sprintf("/documents/%s", id), bytes.NewReader(reportedDocumentInByte))
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+
+			if tt.wantErr {
+				return
+			}
+
+			if string(resBody) != id {
+				t.Fatalf("putDocument() got = %s, want %s", resBody, id)
+			}
+		})
+	}
+}
+
+func TestAdapter_getDocumentByName(t *testing.T) {
+	type domainServiceGetDocumentMockData struct {
+		times int
+		err   error
+		doc   models.Document
+	}
+
+	successfulGetDocument := domainServiceGetDocumentMockData{
+		times: 1,
+		err:   nil,
+		doc:   models.Document{Name: "tamir"},
+	}
+
+	documentNotFound := domainServiceGetDocumentMockData{
+		times: 1,
+		err:   errors.New("not-found").SetType(errors.ErrorTypeNotFound),
+	}
+
+	forbidden := domainServiceGetDocumentMockData{
+		times: 1,
+		err:   errors.New("forbidden").SetType(errors.ErrorTypeForbidden),
+	}
+
+	failedToGetDocument := domainServiceGetDocumentMockData{
+		times: 1,
+		err:   errors.New("some-error").SetType(errors.ErrorTypeInternal),
+	}
+
+	tests := []struct {
+		name                       string
+		domainServiceGetDocumentMD domainServiceGetDocumentMockData
+		wantedStatusCode           int
+		wantErr                    bool
+	}{
+		{
+			name:                       "get document by name successfully expect status OK (200)",
+			domainServiceGetDocumentMD: successfulGetDocument,
+			wantedStatusCode:           http.StatusOK,
+			wantErr:                    false,
+		},
+		{
+			name:                       "name doesn't exist in db expect status not found (404)",
+			domainServiceGetDocumentMD: documentNotFound,
+			wantedStatusCode:           http.StatusNotFound,
+			wantErr:                    true,
+		},
+		{
+			name:                       "principal not allowed to read documents expect status forbidden (403)",
+			domainServiceGetDocumentMD: forbidden,
+			wantedStatusCode:           http.StatusForbidden,
+			wantErr:                    true,
+		},
+		{
+			name:                       "failed to get document from db expect status internal server error (500)",
+			domainServiceGetDocumentMD: failedToGetDocument,
+			wantedStatusCode:           http.StatusInternalServerError,
+			wantErr:                    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			name := "tamir"
+
+			domainService := mocks.NewMockDomainService(c)
+			domainService.EXPECT().GetDocumentByName(gomock.Any(), name).
+				Times(tt.domainServiceGetDocumentMD.times).
+				Return(tt.domainServiceGetDocumentMD.doc, tt.domainServiceGetDocumentMD.err)
+
+			s := &Adapter{
+				domainSvc: domainService,
+			}
+
+			r := chi.NewRouter()
+			r.Route("/documents", func(r chi.Router) {
+				r.Get("/{id}", s.getDocument)
+				r.Get("/by-name/{name}", s.getDocumentByName)
+			})
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, body := testRequest(t, ts, http.MethodGet, fmt.Sprintf("/documents/by-name/%s", name), nil)
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+
+			if tt.wantErr {
+				return
+			}
+
+			var respDoc models.Document
+			if err := json.Unmarshal(body, &respDoc); err != nil {
+				t.Fatalf("Failed to unmarshal response body to 'Document'. Error: %s", err)
+			}
+
+			if !reflect.DeepEqual(respDoc, tt.domainServiceGetDocumentMD.doc) {
+				t.Fatalf("getDocumentByName() got = %v, want %v", respDoc, tt.domainServiceGetDocumentMD.doc)
+			}
+		})
+	}
+}
+
+func TestAdapter_upsertDocumentByName(t *testing.T) {
+	type domainServiceUpsertDocumentMockData struct {
+		times   int
+		created bool
+		err     error
+	}
+
+	validDoc := models.Document{
+		Name: "tamir",
+		Doc: map[string]interface{}{
+			"lastName": "Aviv",
+		},
+	}
+
+	createdDocument := domainServiceUpsertDocumentMockData{
+		times:   1,
+		created: true,
+	}
+
+	updatedDocument := domainServiceUpsertDocumentMockData{
+		times:   1,
+		created: false,
+	}
+
+	conflict := domainServiceUpsertDocumentMockData{
+		times: 1,
+		err:   errors.New("conflict").SetType(errors.ErrorTypeConflict),
+	}
+
+	quotaExceeded := domainServiceUpsertDocumentMockData{
+		times: 1,
+		err:   errors.New("quota").SetType(errors.ErrorTypeQuotaExceeded),
+	}
+
+	invalidDocument := domainServiceUpsertDocumentMockData{
+		times: 1,
+		err:   errors.New("invalid name").SetType(errors.ErrorTypeBadRequest),
+	}
+
+	notCalled := domainServiceUpsertDocumentMockData{
+		times: 0,
+	}
+
+	failedToUpsertDocument := domainServiceUpsertDocumentMockData{
+		times: 1,
+		err:   errors.New("some-error").SetType(errors.ErrorTypeInternal),
+	}
+
+	tests := []struct {
+		name                          string
+		urlName                       string
+		domainServiceUpsertDocumentMD domainServiceUpsertDocumentMockData
+		schemaErr                     error
+		wantedStatusCode              int
+		wantErr                       bool
+	}{
+		{
+			name:                          "upsert new document expect status created (201)",
+			urlName:                       validDoc.Name,
+			domainServiceUpsertDocumentMD: createdDocument,
+			wantedStatusCode:              http.StatusCreated,
+			wantErr:                       false,
+		},
+		{
+			name:                          "upsert existing document expect status OK (200)",
+			urlName:                       validDoc.Name,
+			domainServiceUpsertDocumentMD: updatedDocument,
+			wantedStatusCode:              http.StatusOK,
+			wantErr:                       false,
+		},
+		{
+			name:             "document name doesn't match url expect status bad request (400)",
+			urlName:          "someone-else",
+			wantedStatusCode: http.StatusBadRequest,
+			wantErr:          true,
+		},
+		{
+			name:                          "document modified concurrently expect status conflict (409)",
+			urlName:                       validDoc.Name,
+			domainServiceUpsertDocumentMD: conflict,
+			wantedStatusCode:              http.StatusConflict,
+			wantErr:                       true,
+		},
+		{
+			name:                          "tenant quota exceeded expect status forbidden (403)",
+			urlName:                       validDoc.Name,
+			domainServiceUpsertDocumentMD: quotaExceeded,
+			wantedStatusCode:              http.StatusForbidden,
+			wantErr:                       true,
+		},
+		{
+			name:                          "document rejected by domain expect status bad request (400)",
+			urlName:                       validDoc.Name,
+			domainServiceUpsertDocumentMD: invalidDocument,
+			wantedStatusCode:              http.StatusBadRequest,
+			wantErr:                       true,
+		},
+		{
+			name:                          "document invalid according to schema expect status bad request (400)",
+			urlName:                       validDoc.Name,
+			domainServiceUpsertDocumentMD: notCalled,
+			schemaErr:                     errors.New("invalid").SetType(errors.ErrorTypeBadRequest),
+			wantedStatusCode:              http.StatusBadRequest,
+			wantErr:                       true,
+		},
+		{
+			name:                          "failed to upsert document expect status internal server error (500)",
+			urlName:                       validDoc.Name,
+			domainServiceUpsertDocumentMD: failedToUpsertDocument,
+			wantedStatusCode:              http.StatusInternalServerError,
+			wantErr:                       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			reportedDocumentInByte, err := json.Marshal(validDoc)
+			if err != nil {
+				t.Fatalf("Failed to marshal document (%+v) from request body. Error: %s", validDoc, err)
+			}
+
+			id := uuid.New().String()
+
+			domainService := mocks.NewMockDomainService(c)
+			domainService.EXPECT().UpsertDocument(gomock.Any(), validDoc).
+				Times(tt.domainServiceUpsertDocumentMD.times).
+				Return(id, tt.domainServiceUpsertDocumentMD.created, tt.domainServiceUpsertDocumentMD.err)
+
+			jsonSchemaValidator := mocks.NewMockJSONSchemaValidator(c)
+			jsonSchemaValidator.EXPECT().ValidateSchemaFromBytes(postDocumentSchemaName, reportedDocumentInByte).
+				Times(1).
+				Return(tt.schemaErr)
+
+			s := &Adapter{
+				domainSvc:  domainService,
+				jsonSchema: jsonSchemaValidator,
+			}
+
+			r := chi.NewRouter()
+			r.Route("/documents", func(r chi.Router) {
+				r.Put("/by-name/{name}", s.upsertDocumentByName)
+			})
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, resBody := testRequest(t, ts, http.MethodPut, fmt.Sprintf("/documents/by-name/%s", tt.urlName), bytes.NewReader(reportedDocumentInByte))
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+
+			if tt.wantErr {
+				return
+			}
+
+			if string(resBody) != id {
+				t.Fatalf("upsertDocumentByName() got = %s, want %s", resBody, id)
+			}
+		})
+	}
+}
+
+func TestAdapter_getDocumentConditional(t *testing.T) {
+	modifiedAt := time.Date(2020, time.May, 1, 10, 30, 15, 500, time.UTC)
+	doc := models.Document{Name: "name", Doc: map[string]interface{}{"key": "value"}, ModifiedAt: modifiedAt}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Failed to marshal document. Error: %s", err)
+	}
+	tag := etag(body)
+
+	tests := []struct {
+		name             string
+		headers          map[string]string
+		wantedStatusCode int
+	}{
+		{
+			name:             "no conditional headers expect status OK (200)",
+			headers:          map[string]string{},
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "matching entity tag expect status not modified (304)",
+			headers:          map[string]string{headerIfNoneMatch: tag},
+			wantedStatusCode: http.StatusNotModified,
+		},
+		{
+			name:             "matching weak entity tag among others expect status not modified (304)",
+			headers:          map[string]string{headerIfNoneMatch: `"other", W/` + tag},
+			wantedStatusCode: http.StatusNotModified,
+		},
+		{
+			name:             "stale entity tag expect status OK (200)",
+			headers:          map[string]string{headerIfNoneMatch: `"stale"`},
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "stale entity tag takes precedence over modification time expect status OK (200)",
+			headers:          map[string]string{headerIfNoneMatch: `"stale"`, headerIfModifiedSince: modifiedAt.Add(time.Hour).Format(http.TimeFormat)},
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "not modified since expect status not modified (304)",
+			headers:          map[string]string{headerIfModifiedSince: modifiedAt.Format(http.TimeFormat)},
+			wantedStatusCode: http.StatusNotModified,
+		},
+		{
+			name:             "modified since expect status OK (200)",
+			headers:          map[string]string{headerIfModifiedSince: modifiedAt.Add(-time.Minute).Format(http.TimeFormat)},
+			wantedStatusCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			id := uuid.New().String()
+
+			domainService := mocks.NewMockDomainService(c)
+			domainService.EXPECT().GetDocument(gomock.Any(), id, models.ReadOptions{}).Times(1).Return(doc, nil)
+
+			s := &Adapter{
+				domainSvc:    domainService,
+				cacheControl: "private, no-cache",
+			}
+
+			r := chi.NewRouter()
+			r.Get("/documents/{id}", s.getDocument)
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, resBody := testRequestWithHeaders(t, ts, http.MethodGet, fmt.Sprintf("/documents/%s", id), tt.headers, nil)
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+
+			if got := res.Header.Get(headerETag); got != tag {
+				t.Fatalf("getDocument() ETag = %v, want %v", got, tag)
+			}
+
+			if got := res.Header.Get(headerLastModified); got != modifiedAt.Format(http.TimeFormat) {
+				t.Fatalf("getDocument() Last-Modified = %v, want %v", got, modifiedAt.Format(http.TimeFormat))
+			}
+
+			if got := res.Header.Get(headerCacheControl); got != s.cacheControl {
+				t.Fatalf("getDocument() Cache-Control = %v, want %v", got, s.cacheControl)
+			}
+
+			if tt.wantedStatusCode == http.StatusNotModified && len(resBody) != 0 {
+				t.Fatalf("getDocument() body = %s, want no body", resBody)
+			}
+		})
+	}
+}
+
+func TestAdapter_searchDocuments(t *testing.T) {
+	type domainServiceSearchDocumentsMockData struct {
+		times   int
+		results []models.SearchResult
+		err     error
+	}
+
+	results := []models.SearchResult{
+		{
+			ID:       "id",
+			Score:    1.5,
+			Document: models.Document{Name: "tamir", Doc: map[string]interface{}{"lastName": "Aviv"}},
+		},
+	}
+
+	successfulSearch := domainServiceSearchDocumentsMockData{
+		times:   1,
+		results: results,
+	}
+
+	invalidSearch := domainServiceSearchDocumentsMockData{
+		times: 1,
+		err:   errors.New("bad-request").SetType(errors.ErrorTypeBadRequest),
+	}
+
+	missingTextIndex := domainServiceSearchDocumentsMockData{
+		times: 1,
+		err:   errors.New("no text index").SetType(errors.ErrorTypeUnprocessable),
+	}
+
+	failedToSearch := domainServiceSearchDocumentsMockData{
+		times: 1,
+		err:   errors.New("some-error").SetType(errors.ErrorTypeInternal),
+	}
+
+	notCalled := domainServiceSearchDocumentsMockData{
+		times: 0,
+	}
+
+	tests := []struct {
+		name                           string
+		domainServiceSearchDocumentsMD domainServiceSearchDocumentsMockData
+		query                          string
+		wantQuery                      models.SearchQuery
+		wantedStatusCode               int
+		wantErr                        bool
+	}{
+		{
+			name:                           "search by text successfully expect results and status OK (200)",
+			domainServiceSearchDocumentsMD: successfulSearch,
+			query:                          "?q=aviv",
+			wantQuery:                      models.SearchQuery{Text: "aviv"},
+			wantedStatusCode:               http.StatusOK,
+			wantErr:                        false,
+		},
+		{
+			name:                           "search by filter with limit successfully expect results and status OK (200)",
+			domainServiceSearchDocumentsMD: successfulSearch,
+			query:                          `?filter={"or":[{"field":"doc.age","gte":18},{"field":"doc.tags","in":["a"]}]}&limit=5`,
+			wantQuery: models.SearchQuery{
+				Filter: &models.Filter{Or: []models.Filter{
+					{Field: "doc.age", Gte: json.Number("18")},
+					{Field: "doc.tags", In: []interface{}{"a"}},
+				}},
+				Limit: 5,
+			},
+			wantedStatusCode: http.StatusOK,
+			wantErr:          false,
+		},
+		{
+			name:                           "search selecting fields successfully expect fields passed to domain and status OK (200)",
+			domainServiceSearchDocumentsMD: successfulSearch,
+			query:                          "?q=aviv&fields=name, doc.lastName",
+			wantQuery:                      models.SearchQuery{Text: "aviv", ReadOptions: models.ReadOptions{Fields: []string{"name", "doc.lastName"}}},
+			wantedStatusCode:               http.StatusOK,
+			wantErr:                        false,
+		},
+		{
+			name:                           "search excluding fields successfully expect excluded fields passed to domain and status OK (200)",
+			domainServiceSearchDocumentsMD: successfulSearch,
+			query:                          "?q=aviv&exclude=doc.history",
+			wantQuery:                      models.SearchQuery{Text: "aviv", ReadOptions: models.ReadOptions{Exclude: []string{"doc.history"}}},
+			wantedStatusCode:               http.StatusOK,
+			wantErr:                        false,
+		},
+		{
+			name:                           "malformed filter expect status bad request (400)",
+			domainServiceSearchDocumentsMD: notCalled,
+			query:                          `?filter={"field":`,
+			wantedStatusCode:               http.StatusBadRequest,
+			wantErr:                        true,
+		},
+		{
+			name:                           "filter with raw mongodb operator expect status bad request (400)",
+			domainServiceSearchDocumentsMD: notCalled,
+			query:                          `?filter={"field":"doc.age","$where":"sleep(1000)"}`,
+			wantedStatusCode:               http.StatusBadRequest,
+			wantErr:                        true,
+		},
+		{
+			name:                           "limit that is not a number expect status bad request (400)",
+			domainServiceSearchDocumentsMD: notCalled,
+			query:                          "?q=aviv&limit=all",
+			wantedStatusCode:               http.StatusBadRequest,
+			wantErr:                        true,
+		},
+		{
+			name:                           "search rejected by domain expect status bad request (400)",
+			domainServiceSearchDocumentsMD: invalidSearch,
+			query:                          "?q=aviv",
+			wantQuery:                      models.SearchQuery{Text: "aviv"},
+			wantedStatusCode:               http.StatusBadRequest,
+			wantErr:                        true,
+		},
+		{
+			name:                           "search by text without text index expect status unprocessable entity (422)",
+			domainServiceSearchDocumentsMD: missingTextIndex,
+			query:                          "?q=aviv",
+			wantQuery:                      models.SearchQuery{Text: "aviv"},
+			wantedStatusCode:               http.StatusUnprocessableEntity,
+			wantErr:                        true,
+		},
+		{
+			name:                           "failed to search documents expect status internal server error (500)",
+			domainServiceSearchDocumentsMD: failedToSearch,
+			query:                          "?q=aviv",
+			wantQuery:                      models.SearchQuery{Text: "aviv"},
+			wantedStatusCode:               http.StatusInternalServerError,
+			wantErr:                        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			domainService := mocks.NewMockDomainService(c)
+			domainService.EXPECT().SearchDocuments(gomock.Any(), tt.wantQuery).
+				Times(tt.domainServiceSearchDocumentsMD.times).
+				Return(tt.domainServiceSearchDocumentsMD.results, tt.domainServiceSearchDocumentsMD.err)
+
+			s := &Adapter{
+				domainSvc: domainService,
+			}
+
+			r := chi.NewRouter()
+			r.Get("/documents:search", s.searchDocuments)
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, body := testRequest(t, ts, http.MethodGet, "/documents:search"+strings.NewReplacer(" ", "%20", `"`, "%22", "{", "%7B", "}", "%7D").Replace(tt.query), nil)
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+
+			if tt.wantErr {
+				return
+			}
+
+			var got searchResults
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("Failed to unmarshal response body to 'searchResults'. Error: %s", err)
+			}
+
+			if !reflect.DeepEqual(got.Results, tt.domainServiceSearchDocumentsMD.results) {
+				t.Fatalf("searchDocuments() got = %v, want %v", got.Results, tt.domainServiceSearchDocumentsMD.results)
+			}
+		})
+	}
+}
+
+func TestAdapter_ready(t *testing.T) {
+	type checkHealthMockData struct {
+		checks []models.HealthCheck
+	}
+
+	databaseReady := checkHealthMockData{
+		checks: []models.HealthCheck{{Name: "mongodb", Ready: true}},
+	}
+
+	databaseNotReady := checkHealthMockData{
+		checks: []models.HealthCheck{{Name: "mongodb", Ready: false, Details: map[string]interface{}{"state": "connecting"}}},
+	}
+
+	tests := []struct {
+		name             string
+		checkHealthMD    checkHealthMockData
+		wantedStatusCode int
+		wantReady        bool
+	}{
+		{
+			name:             "all dependencies ready expect status OK (200)",
+			checkHealthMD:    databaseReady,
+			wantedStatusCode: http.StatusOK,
+			wantReady:        true,
+		},
+		{
+			name:             "database not ready expect status service unavailable (503)",
+			checkHealthMD:    databaseNotReady,
+			wantedStatusCode: http.StatusServiceUnavailable,
+			wantReady:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			healthChecker := mocks.NewMockHealthChecker(c)
+			healthChecker.EXPECT().CheckHealth(gomock.Any()).Times(1).Return(tt.checkHealthMD.checks)
+
+			s := &Adapter{
+				healthChecker: healthChecker,
+			}
+
+			r := chi.NewRouter()
+			r.Get("/health/ready", s.ready)
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, body := testRequest(t, ts, http.MethodGet, "/health/ready", nil)
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+
+			var health models.Health
+			if err := json.Unmarshal(body, &health); err != nil {
+				t.Fatalf("Failed to unmarshal response body to 'Health'. Error: %s", err)
+			}
+
+			if health.Ready != tt.wantReady {
+				t.Fatalf("ready() ready = %v, want %v", health.Ready, tt.wantReady)
+			}
+
+			if !reflect.DeepEqual(health.Checks, tt.checkHealthMD.checks) {
+				t.Fatalf("ready() checks = %v, want %v", health.Checks, tt.checkHealthMD.checks)
+			}
+		})
+	}
+}
+
+func Test_noCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		want         bool
+	}{
+		{
+			name:         "no cache control header expect cache",
+			cacheControl: "",
+			want:         false,
+		},
+		{
+			name:         "no-cache directive expect no cache",
+			cacheControl: "no-cache",
+			want:         true,
+		},
+		{
+			name:         "no-cache among other directives expect no cache",
+			cacheControl: "max-age=0, No-Cache",
+			want:         true,
+		},
+		{
+			name:         "other directives only expect cache",
+			cacheControl: "max-age=60, no-transform",
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/documents/id", nil)
+			r.Header.Set(headerCacheControl, tt.cacheControl)
+
+			if got := noCache(r); got != tt.want {
+				t.Errorf("noCache() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_returnAuthorizationError(t *testing.T) {
+	tests := []struct {
+		name             string
+		err              error
+		want             bool
+		wantedStatusCode int
+		wantProblem      bool
+	}{
+		{
+			name:             "unauthenticated error expect problem response with status unauthorized (401)",
+			err:              errors.New("token signed by unknown key (kid-1)").SetType(errors.ErrorTypeUnauthorized),
+			want:             true,
+			wantedStatusCode: http.StatusUnauthorized,
+			wantProblem:      true,
+		},
+		{
+			name:             "forbidden error expect status forbidden (403)",
+			err:              errors.New("forbidden").SetType(errors.ErrorTypeForbidden),
+			want:             true,
+			wantedStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "other error expect nothing written",
+			err:  errors.New("some-error").SetType(errors.ErrorTypeInternal),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if got := returnAuthorizationError(w, tt.err); got != tt.want {
+				t.Fatalf("returnAuthorizationError() = %v, want %v", got, tt.want)
+			}
+
+			if !tt.want {
+				return
+			}
+
+			res := w.Result()
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+
+			if tt.wantProblem {
+				problemCheck(t, res, w.Body.Bytes(), tt.wantedStatusCode, tt.err)
+			}
+		})
+	}
+}
+
+func testRequest(t *testing.T, ts *httptest.Server, method string, path string, body io.Reader) (*http.Response, []byte) {
+	return testRequestWithHeaders(t, ts, method, path, nil, body)
+}
+
+func testRequestWithHeaders(t *testing.T, ts *httptest.Server, method string, path string, headers map[string]string, body io.Reader) (*http.Response, []byte) {
+	url := ts.URL + path
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
 	}
 
 	res, err := http.DefaultClient.Do(req)
@@ -0,0 +1,151 @@
+package rest
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"microservice/internal/pkg/auth"
+	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/tenancy"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	headerWWWAuthenticate = "WWW-Authenticate"
+	authenticateChallenge = `Bearer realm="microservice", ApiKey header="X-API-Key"`
+
+	headerRetryAfter         = "Retry-After"
+	headerRateLimitLimit     = "RateLimit-Limit"
+	headerRateLimitRemaining = "RateLimit-Remaining"
+	headerRateLimitReset     = "RateLimit-Reset"
+)
+
+// authenticate rejects requests without valid credentials and stores the principal in the request context
+func (s *Adapter) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := s.authenticator.Authenticate(r)
+		if err != nil {
+			if errors.IsType(err, errors.ErrorTypeUnauthorized) {
+				log.Debugf("Unauthenticated request to (%s). Error: %s", r.URL.Path, err)
+				w.Header().Set(headerWWWAuthenticate, authenticateChallenge)
+				returnProblem(w, err)
+				return
+			}
+
+			log.Errorf("Failed to authenticate request to (%s). Error: %s", r.URL.Path, err)
+			returnProblem(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), p)))
+	})
+}
+
+// resolveTenant stores the tenant of the request in the request context. It must run after authenticate
+func (s *Adapter) resolveTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := auth.FromContext(r.Context())
+		tenant, err := s.tenantResolver.ResolveTenant(r, p)
+		if err != nil {
+			switch {
+			case errors.IsType(err, errors.ErrorTypeForbidden):
+				log.Debugf("Principal (%s) is not allowed to use the requested tenant. Error: %s", p.Subject, err)
+				returnHTTPError(w, http.StatusForbidden, err.Error())
+			case errors.IsType(err, errors.ErrorTypeBadRequest):
+				log.Debugf("Failed to resolve tenant of request to (%s). Error: %s", r.URL.Path, err)
+				returnHTTPError(w, http.StatusBadRequest, err.Error())
+			default:
+				log.Errorf("Failed to resolve tenant of request to (%s). Error: %s", r.URL.Path, err)
+				returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+			}
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(tenancy.NewContext(r.Context(), tenant)))
+	})
+}
+
+// rateLimit rejects requests of clients that exceeded the limit of the route with too many requests (429).
+// Requests are let through if the limiter fails, so an unavailable limiter store does not take the service down
+func (s *Adapter) rateLimit(route string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, applied, err := s.rateLimiter.Allow(r, route)
+			if err != nil {
+				log.Errorf("Failed to apply rate limit of route (%s). Error: %s", route, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !applied {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(headerRateLimitLimit, strconv.Itoa(res.Limit))
+			w.Header().Set(headerRateLimitRemaining, strconv.Itoa(res.Remaining))
+			w.Header().Set(headerRateLimitReset, ceilSeconds(res.Reset))
+
+			if !res.Allowed {
+				log.Debugf("Rate limit of route (%s) exceeded", route)
+				w.Header().Set(headerRetryAfter, ceilSeconds(res.RetryAfter))
+				returnHTTPError(w, http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitBody rejects request bodies larger than the maximum body size of the route with payload too large (413).
+// Bodies of unknown length fail with a TooLarge error once read past the limit, so they are never read whole
+func (s *Adapter) limitBody(route string) func(http.Handler) http.Handler {
+	max := int64(s.routes.Get(route).MaxBodySize)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > max {
+				log.Debugf("Request body of route (%s) of (%d) bytes exceeds (%d) bytes", route, r.ContentLength, max)
+				returnHTTPError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds (%d) bytes", max))
+				return
+			}
+
+			r.Body = &limitedBody{ReadCloser: r.Body, max: max, name: "Request body"}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitedBody is a request body that fails with a TooLarge error once more than max bytes are read from it
+type limitedBody struct {
+	io.ReadCloser
+	read int64
+	max  int64
+	// name describes the body in errors
+	name string
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	// read a byte past the limit at most, to tell bodies of exactly max bytes from larger ones
+	if remaining := b.max - b.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.read > b.max {
+		return n, errors.Errorf("%s exceeds (%d) bytes", b.name, b.max).SetType(errors.ErrorTypeTooLarge)
+	}
+
+	return n, err
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
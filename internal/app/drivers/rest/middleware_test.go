@@ -0,0 +1,508 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"microservice/internal/pkg/auth"
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/ratelimit"
+	"microservice/internal/pkg/tenancy"
+	"microservice/mocks"
+	"microservice/models"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+)
+
+func TestAdapter_authenticate(t *testing.T) {
+	type authenticateMockData struct {
+		times     int
+		principal models.Principal
+		err       error
+	}
+
+	principal := models.Principal{
+		Subject: "tamir",
+		Roles:   []string{"reader"},
+	}
+
+	successfulAuthenticate := authenticateMockData{
+		times:     1,
+		principal: principal,
+		err:       nil,
+	}
+
+	unauthorized := authenticateMockData{
+		times: 1,
+		err:   errors.New("invalid api key").SetType(errors.ErrorTypeUnauthorized),
+	}
+
+	failedToAuthenticate := authenticateMockData{
+		times: 1,
+		err:   errors.New("some-error").SetType(errors.ErrorTypeInternal),
+	}
+
+	tests := []struct {
+		name             string
+		authenticateMD   authenticateMockData
+		wantedStatusCode int
+		wantErr          bool
+	}{
+		{
+			name:             "authenticated request expect principal in context and status OK (200)",
+			authenticateMD:   successfulAuthenticate,
+			wantedStatusCode: http.StatusOK,
+			wantErr:          false,
+		},
+		{
+			name:             "invalid credentials expect status unauthorized (401)",
+			authenticateMD:   unauthorized,
+			wantedStatusCode: http.StatusUnauthorized,
+			wantErr:          true,
+		},
+		{
+			name:             "failed to authenticate expect status internal server error (500)",
+			authenticateMD:   failedToAuthenticate,
+			wantedStatusCode: http.StatusInternalServerError,
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			authenticator := mocks.NewMockAuthenticator(c)
+			authenticator.EXPECT().Authenticate(gomock.Any()).
+				Times(tt.authenticateMD.times).
+				Return(tt.authenticateMD.principal, tt.authenticateMD.err)
+
+			s := &Adapter{
+				authenticator: authenticator,
+			}
+
+			var got models.Principal
+			r := chi.NewRouter()
+			r.Use(s.authenticate)
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				got, _ = auth.FromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, body := testRequest(t, ts, http.MethodGet, "/", nil)
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+
+			if tt.wantErr {
+				if tt.wantedStatusCode == http.StatusUnauthorized && res.Header.Get(headerWWWAuthenticate) == "" {
+					t.Fatalf("authenticate() missing %s header", headerWWWAuthenticate)
+				}
+				problemCheck(t, res, body, tt.wantedStatusCode, tt.authenticateMD.err)
+				return
+			}
+
+			if !reflect.DeepEqual(got, principal) {
+				t.Fatalf("authenticate() principal = %v, want %v", got, principal)
+			}
+		})
+	}
+}
+
+func TestAdapter_resolveTenant(t *testing.T) {
+	type resolveTenantMockData struct {
+		times  int
+		tenant string
+		err    error
+	}
+
+	successfulResolveTenant := resolveTenantMockData{
+		times:  1,
+		tenant: "tenant-a",
+		err:    nil,
+	}
+
+	missingTenant := resolveTenantMockData{
+		times: 1,
+		err:   errors.New("missing tenant").SetType(errors.ErrorTypeBadRequest),
+	}
+
+	foreignTenant := resolveTenantMockData{
+		times: 1,
+		err:   errors.New("foreign tenant").SetType(errors.ErrorTypeForbidden),
+	}
+
+	tests := []struct {
+		name             string
+		resolveTenantMD  resolveTenantMockData
+		wantedStatusCode int
+		wantErr          bool
+	}{
+		{
+			name:             "resolved tenant expect tenant in context and status OK (200)",
+			resolveTenantMD:  successfulResolveTenant,
+			wantedStatusCode: http.StatusOK,
+			wantErr:          false,
+		},
+		{
+			name:             "missing tenant expect status bad request (400)",
+			resolveTenantMD:  missingTenant,
+			wantedStatusCode: http.StatusBadRequest,
+			wantErr:          true,
+		},
+		{
+			name:             "principal requests tenant it is not bound to expect status forbidden (403)",
+			resolveTenantMD:  foreignTenant,
+			wantedStatusCode: http.StatusForbidden,
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			tenantResolver := mocks.NewMockTenantResolver(c)
+			tenantResolver.EXPECT().ResolveTenant(gomock.Any(), gomock.AssignableToTypeOf(models.Principal{})).
+				Times(tt.resolveTenantMD.times).
+				Return(tt.resolveTenantMD.tenant, tt.resolveTenantMD.err)
+
+			s := &Adapter{
+				tenantResolver: tenantResolver,
+			}
+
+			var got string
+			r := chi.NewRouter()
+			r.Use(s.resolveTenant)
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				got, _ = tenancy.FromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, _ := testRequest(t, ts, http.MethodGet, "/", nil)
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+
+			if tt.wantErr {
+				return
+			}
+
+			if got != tt.resolveTenantMD.tenant {
+				t.Fatalf("resolveTenant() tenant = %s, want %s", got, tt.resolveTenantMD.tenant)
+			}
+		})
+	}
+}
+
+func TestAdapter_authenticated(t *testing.T) {
+	type allowMockData struct {
+		times  int
+		result ratelimit.Result
+	}
+
+	type authenticateMockData struct {
+		times int
+		err   error
+	}
+
+	type resolveTenantMockData struct {
+		times int
+	}
+
+	allowed := allowMockData{
+		times:  1,
+		result: ratelimit.Result{Allowed: true, Limit: 10, Remaining: 9},
+	}
+
+	limited := allowMockData{
+		times:  1,
+		result: ratelimit.Result{Allowed: false, Limit: 10, RetryAfter: time.Second},
+	}
+
+	successfulAuthenticate := authenticateMockData{
+		times: 1,
+	}
+
+	failedToAuthenticate := authenticateMockData{
+		times: 1,
+		err:   errors.New("invalid api key").SetType(errors.ErrorTypeUnauthorized),
+	}
+
+	notAuthenticated := authenticateMockData{
+		times: 0,
+	}
+
+	tests := []struct {
+		name             string
+		allowMD          allowMockData
+		authenticateMD   authenticateMockData
+		resolveTenantMD  resolveTenantMockData
+		wantedStatusCode int
+	}{
+		{
+			name:             "client within limit with valid credentials expect status OK (200)",
+			allowMD:          allowed,
+			authenticateMD:   successfulAuthenticate,
+			resolveTenantMD:  resolveTenantMockData{times: 1},
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "client within limit with invalid credentials expect limit taken and status unauthorized (401)",
+			allowMD:          allowed,
+			authenticateMD:   failedToAuthenticate,
+			resolveTenantMD:  resolveTenantMockData{times: 0},
+			wantedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "client over limit expect credentials not checked and status too many requests (429)",
+			allowMD:          limited,
+			authenticateMD:   notAuthenticated,
+			resolveTenantMD:  resolveTenantMockData{times: 0},
+			wantedStatusCode: http.StatusTooManyRequests,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rateLimiter := mocks.NewMockRateLimiter(c)
+			rateLimiter.EXPECT().Allow(gomock.Any(), routeAuthenticate).
+				Times(tt.allowMD.times).
+				Return(tt.allowMD.result, true, nil)
+
+			authenticator := mocks.NewMockAuthenticator(c)
+			authenticator.EXPECT().Authenticate(gomock.Any()).
+				Times(tt.authenticateMD.times).
+				Return(models.Principal{Subject: "alice", Tenant: "tenant-a"}, tt.authenticateMD.err)
+
+			tenantResolver := mocks.NewMockTenantResolver(c)
+			tenantResolver.EXPECT().ResolveTenant(gomock.Any(), gomock.Any()).
+				Times(tt.resolveTenantMD.times).
+				Return("tenant-a", nil)
+
+			s := &Adapter{
+				rateLimiter:    rateLimiter,
+				authenticator:  authenticator,
+				tenantResolver: tenantResolver,
+			}
+
+			r := chi.NewRouter()
+			r.With(s.authenticated()...).Get("/", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, _ := testRequest(t, ts, http.MethodGet, "/", nil)
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+		})
+	}
+}
+
+func TestAdapter_rateLimit(t *testing.T) {
+	type allowMockData struct {
+		times   int
+		result  ratelimit.Result
+		applied bool
+		err     error
+	}
+
+	allowed := allowMockData{
+		times:   1,
+		result:  ratelimit.Result{Allowed: true, Limit: 10, Remaining: 9, Reset: 100 * time.Millisecond},
+		applied: true,
+	}
+
+	limited := allowMockData{
+		times:   1,
+		result:  ratelimit.Result{Allowed: false, Limit: 10, Remaining: 0, RetryAfter: 1500 * time.Millisecond, Reset: 10 * time.Second},
+		applied: true,
+	}
+
+	disabled := allowMockData{
+		times:   1,
+		applied: false,
+	}
+
+	failedToAllow := allowMockData{
+		times: 1,
+		err:   errors.New("some-error"),
+	}
+
+	tests := []struct {
+		name             string
+		allowMD          allowMockData
+		wantedStatusCode int
+		wantedHeaders    map[string]string
+	}{
+		{
+			name:             "request within limit expect rate limit headers and status OK (200)",
+			allowMD:          allowed,
+			wantedStatusCode: http.StatusOK,
+			wantedHeaders: map[string]string{
+				headerRateLimitLimit:     "10",
+				headerRateLimitRemaining: "9",
+				headerRateLimitReset:     "1",
+			},
+		},
+		{
+			name:             "request over limit expect retry after header and status too many requests (429)",
+			allowMD:          limited,
+			wantedStatusCode: http.StatusTooManyRequests,
+			wantedHeaders: map[string]string{
+				headerRateLimitRemaining: "0",
+				headerRateLimitReset:     "10",
+				headerRetryAfter:         "2",
+			},
+		},
+		{
+			name:             "rate limiting disabled expect no rate limit headers and status OK (200)",
+			allowMD:          disabled,
+			wantedStatusCode: http.StatusOK,
+			wantedHeaders: map[string]string{
+				headerRateLimitLimit: "",
+			},
+		},
+		{
+			name:             "failed to apply rate limit expect request let through with status OK (200)",
+			allowMD:          failedToAllow,
+			wantedStatusCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rateLimiter := mocks.NewMockRateLimiter(c)
+			rateLimiter.EXPECT().Allow(gomock.Any(), routeAddDocument).
+				Times(tt.allowMD.times).
+				Return(tt.allowMD.result, tt.allowMD.applied, tt.allowMD.err)
+
+			s := &Adapter{
+				rateLimiter: rateLimiter,
+			}
+
+			r := chi.NewRouter()
+			r.With(s.rateLimit(routeAddDocument)).Post("/", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, _ := testRequest(t, ts, http.MethodPost, "/", nil)
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+
+			for k, v := range tt.wantedHeaders {
+				if got := res.Header.Get(k); got != v {
+					t.Fatalf("rateLimit() header %s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestAdapter_limitBody(t *testing.T) {
+	tests := []struct {
+		name             string
+		bodySize         int
+		unknownLength    bool
+		wantedStatusCode int
+	}{
+		{
+			name:             "body within limit expect status OK (200)",
+			bodySize:         1024,
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "body of unknown length within limit expect status OK (200)",
+			bodySize:         1024,
+			unknownLength:    true,
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "body length over limit expect status request entity too large (413)",
+			bodySize:         1025,
+			wantedStatusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:             "body of unknown length over limit expect status request entity too large (413)",
+			bodySize:         4096,
+			unknownLength:    true,
+			wantedStatusCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Adapter{
+				routes: config.Routes{config.DefaultRoute: config.Route{MaxBodySize: 1024}},
+			}
+
+			read := false
+			r := chi.NewRouter()
+			r.With(s.limitBody(routeAddDocument)).Post("/", func(w http.ResponseWriter, r *http.Request) {
+				read = true
+				if _, err := ioutil.ReadAll(r.Body); err != nil {
+					returnReadBodyError(w, err)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			// a reader of unknown length is sent chunked, without Content-Length
+			var body io.Reader = bytes.NewReader(make([]byte, tt.bodySize))
+			if tt.unknownLength {
+				body = io.MultiReader(body)
+			}
+
+			res, _ := testRequest(t, ts, http.MethodPost, "/", body)
+			statusCodeCheck(t, res, tt.wantedStatusCode)
+
+			// bodies known to be over the limit are rejected before the handler
+			if wantRead := tt.unknownLength || tt.wantedStatusCode == http.StatusOK; read != wantRead {
+				t.Fatalf("limitBody() handler called = %v, want %v", read, wantRead)
+			}
+		})
+	}
+}
+
+// problemCheck verifies that res is the problem response of status, which doesn't reveal the message of err
+func problemCheck(t *testing.T, res *http.Response, body []byte, status int, err error) {
+	t.Helper()
+
+	if got := res.Header.Get(headerContentType); got != mediaTypeProblemJSON {
+		t.Fatalf("Content-Type = %v, want %v", got, mediaTypeProblemJSON)
+	}
+
+	var got problem
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("Failed to decode problem response (%s). Error: %s", body, err)
+	}
+
+	if got.Type == "" || got.Title != http.StatusText(status) || got.Status != status {
+		t.Errorf("problem = %+v, want type, title (%s) and status (%d)", got, http.StatusText(status), status)
+	}
+
+	if strings.Contains(string(body), err.Error()) {
+		t.Errorf("problem (%s) reveals the error message (%s)", body, err)
+	}
+}
@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"microservice/internal/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	mediaTypeProblemJSON = "application/problem+json"
+
+	// problemTypeBlank is the type of problems that mean no more than their status
+	problemTypeBlank = "about:blank"
+)
+
+// problem is the body of an error response, as described by RFC 7807
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// problems lists the problem responses of error types. Their details are all clients learn about an error, the
+// error messages may reveal internals and are only logged
+var problems = map[errors.ErrorType]problem{
+	errors.ErrorTypeUnauthorized: {
+		Type:   problemTypeBlank,
+		Title:  http.StatusText(http.StatusUnauthorized),
+		Status: http.StatusUnauthorized,
+		Detail: "Valid credentials are required, as a bearer token or an api key",
+	},
+}
+
+// returnProblem writes the problem response of the type of err, errors of other types are internal server errors
+func returnProblem(w http.ResponseWriter, err error) {
+	p := problem{
+		Type:   problemTypeBlank,
+		Title:  http.StatusText(http.StatusInternalServerError),
+		Status: http.StatusInternalServerError,
+	}
+
+	for errorType, candidate := range problems {
+		if errors.IsType(err, errorType) {
+			p = candidate
+			break
+		}
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		log.Errorf("Failed to marshal problem response. Error: %s", err)
+		returnHTTPError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	httpReturnAs(w, p.Status, mediaTypeProblemJSON, body)
+}
@@ -1,18 +1,54 @@
 package rest
 
 import (
+	"expvar"
 	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 )
 
+const (
+	routeGetDocument   = "getDocument"
+	routeAddDocument   = "addDocument"
+	routePatchDocument = "patchDocument"
+	routePutDocument   = "putDocument"
+
+	routeGetDocumentByName    = "getDocumentByName"
+	routeUpsertDocumentByName = "upsertDocumentByName"
+
+	routeSearchDocuments = "searchDocuments"
+
+	// routeAuthenticate limits the requests of every client to the document routes before they are authenticated
+	routeAuthenticate = "authenticate"
+)
+
 func (s *Adapter) newRouter(timeout time.Duration) *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(middleware.Timeout(timeout))
+	r.Get("/health/live", s.live)
+	r.Get("/health/ready", s.ready)
+	r.Handle("/metrics", expvar.Handler())
+	r.With(s.authenticated()...).With(s.routeMiddlewares(routeSearchDocuments)...).Get("/documents:search", s.searchDocuments)
 	r.Route("/documents", func(r chi.Router) {
-		r.Get("/{id}", s.getDocument)
-		r.Post("/", s.addDocument)
+		r.Use(s.authenticated()...)
+		r.With(s.routeMiddlewares(routeGetDocument)...).Get("/{id}", s.getDocument)
+		r.With(s.routeMiddlewares(routeAddDocument)...).Post("/", s.addDocument)
+		r.With(s.routeMiddlewares(routePatchDocument)...).Patch("/{id}", s.patchDocument)
+		r.With(s.routeMiddlewares(routePutDocument)...).Put("/{id}", s.putDocument)
+		r.With(s.routeMiddlewares(routeGetDocumentByName)...).Get("/by-name/{name}", s.getDocumentByName)
+		r.With(s.routeMiddlewares(routeUpsertDocumentByName)...).Put("/by-name/{name}", s.upsertDocumentByName)
 	})
 	return r
 }
+
+// authenticated are the middlewares that identify the principal and the tenant of document requests.
+// Clients are rate limited by address before they are authenticated, so credentials can't be guessed at any rate
+func (s *Adapter) authenticated() chi.Middlewares {
+	return chi.Middlewares{s.rateLimit(routeAuthenticate), s.authenticate, s.resolveTenant}
+}
+
+// routeMiddlewares are the middlewares of a document route, configured by the name of the route
+func (s *Adapter) routeMiddlewares(route string) chi.Middlewares {
+	return chi.Middlewares{s.rateLimit(route), s.limitBody(route), s.compress(route)}
+}
@@ -12,33 +12,29 @@ import (
 	"strings"
 	"time"
 
+	"microservice/internal/pkg/config"
 	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/ratelimit"
 	"microservice/models"
 
 	"github.com/prometheus/common/log"
 )
 
 const (
-	serverBaseKey    = "server"
-	serverPortKey    = serverBaseKey + ".port"
-	serverTimeoutKey = serverBaseKey + ".timeout"
-
 	apiFolder              = "api"
 	postDocumentSchemaName = "PostDocument"
 	postDocumentSchemaFile = apiFolder + "/" + "postDocumentSchema.json"
 )
 
-// Configuration expose an interface of configuration related actions
-type Configuration interface {
-	GetString(key string) (string, error)
-	GetInt(key string) (int, error)
-	GetDuration(key string) (time.Duration, error)
-}
-
 // DomainSvc exposes an interface of document related actions
 type DomainSvc interface {
-	GetDocument(ctx context.Context, id string) (models.Document, error)
-	AddDocument(ctx context.Context, doc models.Document) (string, error)
+	GetDocument(ctx context.Context, id string, opts models.ReadOptions) (models.Document, error)
+	AddDocument(ctx context.Context, doc models.Document, idempotencyKey string) (string, error)
+	PutDocument(ctx context.Context, id string, doc models.Document) (bool, error)
+	GetDocumentByName(ctx context.Context, name string) (models.Document, error)
+	UpsertDocument(ctx context.Context, doc models.Document) (string, bool, error)
+	PatchDocument(ctx context.Context, id string, patchType models.PatchType, patch []byte) (models.Document, error)
+	SearchDocuments(ctx context.Context, query models.SearchQuery) ([]models.SearchResult, error)
 	Teardown(ctx context.Context) error
 }
 
@@ -54,26 +50,44 @@ type JSONSchemaValidator interface {
 	ValidateSchemaFromBytes(name string, inputJSON []byte) error
 }
 
+// Authenticator identifies the principal that issued a request
+type Authenticator interface {
+	Authenticate(r *http.Request) (models.Principal, error)
+}
+
+// TenantResolver identifies the tenant on behalf of which a request is made
+type TenantResolver interface {
+	ResolveTenant(r *http.Request, p models.Principal) (string, error)
+}
+
+// RateLimiter decides whether the client of a request may call a route again
+type RateLimiter interface {
+	Allow(r *http.Request, route string) (ratelimit.Result, bool, error)
+}
+
+// HealthChecker reports the readiness of the dependencies of the service
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) []models.HealthCheck
+}
+
 // Adapter defines the server struct
 type Adapter struct {
-	port       int
-	timeout    time.Duration
-	server     Server
-	domainSvc  DomainSvc
-	jsonSchema JSONSchemaValidator
+	port           int
+	timeout        time.Duration
+	cacheControl   string
+	routes         config.Routes
+	server         Server
+	domainSvc      DomainSvc
+	jsonSchema     JSONSchemaValidator
+	authenticator  Authenticator
+	tenantResolver TenantResolver
+	rateLimiter    RateLimiter
+	healthChecker  HealthChecker
 }
 
 // NewServer returns a new instance of the Adapter struct
-func NewServer(conf Configuration, dsv DomainSvc, js JSONSchemaValidator) (*Adapter, error) {
-	port, err := conf.GetInt(serverPortKey)
-	if err != nil {
-		return nil, err
-	}
-
-	timeout, err := conf.GetDuration(serverTimeoutKey)
-	if err != nil {
-		return nil, err
-	}
+func NewServer(conf config.Server, dsv DomainSvc, js JSONSchemaValidator, authn Authenticator, tr TenantResolver, rl RateLimiter, hc HealthChecker) (*Adapter, error) {
+	port, timeout := conf.Port, conf.Timeout
 
 	server := &http.Server{
 		Addr: ":" + strconv.Itoa(port),
@@ -84,11 +98,17 @@ func NewServer(conf Configuration, dsv DomainSvc, js JSONSchemaValidator) (*Adap
 	}
 
 	a := &Adapter{
-		port:       port,
-		timeout:    timeout,
-		server:     server,
-		domainSvc:  dsv,
-		jsonSchema: js,
+		port:           port,
+		timeout:        timeout,
+		cacheControl:   conf.CacheControl,
+		routes:         conf.Routes,
+		server:         server,
+		domainSvc:      dsv,
+		jsonSchema:     js,
+		authenticator:  authn,
+		tenantResolver: tr,
+		rateLimiter:    rl,
+		healthChecker:  hc,
 	}
 
 	server.Handler = a.newRouter(timeout)
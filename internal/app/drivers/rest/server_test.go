@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"microservice/internal/pkg/config"
 	"microservice/internal/pkg/errors"
 	"microservice/mocks"
 
@@ -16,41 +17,11 @@ import (
 )
 
 func TestNewServer(t *testing.T) {
-	type getServerPortMockData struct {
-		times int
-		err   error
-	}
-
-	type getServerTimeoutMockData struct {
-		times int
-		err   error
-	}
-
 	type jsonSchemaMockData struct {
 		times int
 		err   error
 	}
 
-	successfulGetServerPort := getServerPortMockData{
-		times: 1,
-		err:   nil,
-	}
-
-	failedToGetServerPort := getServerPortMockData{
-		times: 1,
-		err:   errors.New("some-error"),
-	}
-
-	successfulGetServerTimeout := getServerTimeoutMockData{
-		times: 1,
-		err:   nil,
-	}
-
-	failedToGetServerTimeout := getServerTimeoutMockData{
-		times: 1,
-		err:   errors.New("some-error"),
-	}
-
 	successfulSetJSONSchema := jsonSchemaMockData{
 		times: 1,
 		err:   nil,
@@ -62,36 +33,19 @@ func TestNewServer(t *testing.T) {
 	}
 
 	tests := []struct {
-		name               string
-		getServerPortMD    getServerPortMockData
-		getServerTimeoutMD getServerTimeoutMockData
-		setJSONSchema      jsonSchemaMockData
-		wantErr            bool
+		name          string
+		setJSONSchema jsonSchemaMockData
+		wantErr       bool
 	}{
 		{
-			name:               "valid creation expect no error",
-			getServerPortMD:    successfulGetServerPort,
-			getServerTimeoutMD: successfulGetServerTimeout,
-			setJSONSchema:      successfulSetJSONSchema,
-			wantErr:            false,
-		},
-		{
-			name:            "failed to get server port from configuration expect error",
-			getServerPortMD: failedToGetServerPort,
-			wantErr:         true,
+			name:          "valid creation expect no error",
+			setJSONSchema: successfulSetJSONSchema,
+			wantErr:       false,
 		},
 		{
-			name:               "failed to get server timeout from configuration expect error",
-			getServerPortMD:    successfulGetServerPort,
-			getServerTimeoutMD: failedToGetServerTimeout,
-			wantErr:            true,
-		},
-		{
-			name:               "failed to set JSON Schema expect error",
-			getServerPortMD:    successfulGetServerPort,
-			getServerTimeoutMD: successfulGetServerTimeout,
-			setJSONSchema:      failedToSetJSONSchema,
-			wantErr:            true,
+			name:          "failed to set JSON Schema expect error",
+			setJSONSchema: failedToSetJSONSchema,
+			wantErr:       true,
 		},
 	}
 	for _, tt := range tests {
@@ -107,15 +61,21 @@ func TestNewServer(t *testing.T) {
 			js := mocks.NewMockJSONSchemaValidator(c)
 			js.EXPECT().SetSchemaFromBytes(postDocumentSchemaName, gomock.AssignableToTypeOf([]byte{})).Times(tt.setJSONSchema.times).Return(tt.setJSONSchema.err)
 
-			conf := mocks.NewMockConfigurationService(c)
-			conf.EXPECT().GetInt(serverPortKey).Times(tt.getServerPortMD.times).Return(port, tt.getServerPortMD.err)
-			conf.EXPECT().GetDuration(serverTimeoutKey).Times(tt.getServerTimeoutMD.times).Return(timeout, tt.getServerTimeoutMD.err)
+			conf := config.Server{
+				Port:    port,
+				Timeout: timeout,
+			}
 
 			_, filename, _, _ := runtime.Caller(0)
 			dir := path.Join(path.Dir(filename), "../../../../")
 			_ = os.Chdir(dir)
 
-			got, err := NewServer(conf, domainService, js)
+			authenticator := mocks.NewMockAuthenticator(c)
+			tenantResolver := mocks.NewMockTenantResolver(c)
+			rateLimiter := mocks.NewMockRateLimiter(c)
+			healthChecker := mocks.NewMockHealthChecker(c)
+
+			got, err := NewServer(conf, domainService, js, authenticator, tenantResolver, rateLimiter, healthChecker)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewServer() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// IndexManager reconciles the database indexes with their declaration
+type IndexManager interface {
+	ReconcileIndexes(ctx context.Context) ([]models.IndexReport, error)
+}
+
+// Migrator applies and reverts the data migrations
+type Migrator interface {
+	MigrateUp(ctx context.Context, dryRun bool) ([]models.MigrationResult, error)
+	MigrateDown(ctx context.Context, dryRun bool) ([]models.MigrationResult, error)
+	MigrationStatus(ctx context.Context) ([]models.MigrationStatus, error)
+}
+
+// ReconcileIndexes reconciles the database indexes and logs the created, drifted and undeclared indexes
+func ReconcileIndexes(ctx context.Context, indexes IndexManager) error {
+	reports, err := indexes.ReconcileIndexes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Failed to reconcile indexes")
+	}
+
+	for _, r := range reports {
+		l := log.WithFields(log.Fields{"database": r.Database, "collection": r.Collection})
+		for _, name := range r.Created {
+			l.Infof("Created index (%s)", name)
+		}
+
+		for _, drift := range r.Drifted {
+			l.Warnf("Index drifted from its declaration: %s", drift)
+		}
+
+		for _, name := range r.Undeclared {
+			l.Warnf("Index (%s) is not declared", name)
+		}
+
+		for _, name := range r.Dropped {
+			l.Infof("Dropped undeclared index (%s)", name)
+		}
+	}
+
+	return nil
+}
+
+// MigrateUp applies the pending data migrations and logs the affected documents of every migration
+func MigrateUp(ctx context.Context, m Migrator, dryRun bool) error {
+	results, err := m.MigrateUp(ctx, dryRun)
+	logMigrationResults(results)
+	if err != nil {
+		return errors.Wrap(err, "Failed to apply migrations")
+	}
+
+	if len(results) == 0 {
+		log.Info("No pending migrations")
+	}
+
+	return nil
+}
+
+// MigrateDown reverts the latest applied data migration and logs the affected documents
+func MigrateDown(ctx context.Context, m Migrator, dryRun bool) error {
+	results, err := m.MigrateDown(ctx, dryRun)
+	logMigrationResults(results)
+	if err != nil {
+		return errors.Wrap(err, "Failed to revert migration")
+	}
+
+	if len(results) == 0 {
+		log.Info("No applied migrations to revert")
+	}
+
+	return nil
+}
+
+// MigrationStatus logs whether every data migration was applied
+func MigrationStatus(ctx context.Context, m Migrator) error {
+	statuses, err := m.MigrationStatus(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get migrations status")
+	}
+
+	for _, s := range statuses {
+		l := log.WithFields(log.Fields{"database": s.Database, "version": s.Version})
+		if s.Applied {
+			l.Infof("Applied at %s: %s", s.AppliedAt.Format(time.RFC3339), s.Description)
+		} else {
+			l.Infof("Pending: %s", s.Description)
+		}
+	}
+
+	return nil
+}
+
+// WarnPendingMigrations logs the data migrations that were not applied, documents they change may not be reachable
+// until they are
+func WarnPendingMigrations(ctx context.Context, m Migrator) {
+	statuses, err := m.MigrationStatus(ctx)
+	if err != nil {
+		log.Warnf("Failed to check for pending migrations: %s", err)
+		return
+	}
+
+	for _, s := range statuses {
+		if !s.Applied {
+			log.WithFields(log.Fields{"database": s.Database, "version": s.Version}).
+				Warnf("Pending migration, apply it with (migrate up): %s", s.Description)
+		}
+	}
+}
+
+func logMigrationResults(results []models.MigrationResult) {
+	for _, r := range results {
+		action := "Applied"
+		if r.Down {
+			action = "Reverted"
+		}
+
+		if r.DryRun {
+			action = "Would have " + strings.ToLower(action)
+		}
+
+		log.WithFields(log.Fields{"database": r.Database, "version": r.Version}).
+			Infof("%s migration affecting %d documents: %s", action, r.Affected, r.Description)
+	}
+}
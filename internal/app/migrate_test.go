@@ -0,0 +1,180 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"microservice/internal/pkg/errors"
+	"microservice/mocks"
+	"microservice/models"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestReconcileIndexes(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports []models.IndexReport
+		err     error
+		wantErr bool
+	}{
+		{
+			name: "reconciled indexes with drift expect no error",
+			reports: []models.IndexReport{
+				{
+					Database:   "myDatabase",
+					Collection: "myCollection",
+					Created:    []string{"doc_email"},
+					Drifted:    []string{"doc_age: unique false, declared true"},
+					Undeclared: []string{"legacy"},
+					Dropped:    []string{"legacy"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "failed to reconcile indexes expect error",
+			err:     errors.New("some-error"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			indexes := mocks.NewMockIndexManager(c)
+			indexes.EXPECT().ReconcileIndexes(gomock.Any()).Times(1).Return(tt.reports, tt.err)
+
+			if err := ReconcileIndexes(context.TODO(), indexes); (err != nil) != tt.wantErr {
+				t.Errorf("ReconcileIndexes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigrateUp(t *testing.T) {
+	tests := []struct {
+		name    string
+		dryRun  bool
+		results []models.MigrationResult
+		err     error
+		wantErr bool
+	}{
+		{
+			name: "applied pending migrations expect no error",
+			results: []models.MigrationResult{
+				{Database: "myDatabase", Version: 1, Description: "some-migration", Affected: 3},
+			},
+			wantErr: false,
+		},
+		{
+			name:   "dry run of pending migrations expect no error",
+			dryRun: true,
+			results: []models.MigrationResult{
+				{Database: "myDatabase", Version: 1, Description: "some-migration", Affected: 3, DryRun: true},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "no pending migrations expect no error",
+			wantErr: false,
+		},
+		{
+			name:    "failed to apply migrations expect error",
+			err:     errors.New("some-error"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			m := mocks.NewMockMigrator(c)
+			m.EXPECT().MigrateUp(gomock.Any(), tt.dryRun).Times(1).Return(tt.results, tt.err)
+
+			if err := MigrateUp(context.TODO(), m, tt.dryRun); (err != nil) != tt.wantErr {
+				t.Errorf("MigrateUp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	tests := []struct {
+		name    string
+		dryRun  bool
+		results []models.MigrationResult
+		err     error
+		wantErr bool
+	}{
+		{
+			name: "reverted latest migration expect no error",
+			results: []models.MigrationResult{
+				{Database: "myDatabase", Version: 1, Description: "some-migration", Down: true, Affected: 3},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "no applied migrations expect no error",
+			dryRun:  true,
+			wantErr: false,
+		},
+		{
+			name:    "failed to revert migration expect error",
+			err:     errors.New("some-error"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			m := mocks.NewMockMigrator(c)
+			m.EXPECT().MigrateDown(gomock.Any(), tt.dryRun).Times(1).Return(tt.results, tt.err)
+
+			if err := MigrateDown(context.TODO(), m, tt.dryRun); (err != nil) != tt.wantErr {
+				t.Errorf("MigrateDown() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigrationStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []models.MigrationStatus
+		err      error
+		wantErr  bool
+	}{
+		{
+			name: "applied and pending migrations expect no error",
+			statuses: []models.MigrationStatus{
+				{Database: "myDatabase", Version: 1, Description: "some-migration", Applied: true, AppliedAt: time.Now()},
+				{Database: "myDatabase", Version: 2, Description: "other-migration"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "failed to get migrations status expect error",
+			err:     errors.New("some-error"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			m := mocks.NewMockMigrator(c)
+			m.EXPECT().MigrationStatus(gomock.Any()).Times(1).Return(tt.statuses, tt.err)
+
+			if err := MigrationStatus(context.TODO(), m); (err != nil) != tt.wantErr {
+				t.Errorf("MigrationStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"net/http"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+
+	apiKeyField        = "key"
+	apiKeySubjectField = "subject"
+	apiKeyRolesField   = "roles"
+	apiKeyTenantField  = "tenant"
+
+	// developmentAPIKey is the api key of the development configuration overlay, which is committed to the
+	// repository and so is accepted only in the development environment
+	developmentAPIKey      = "local-development-key"
+	developmentEnvironment = "development"
+)
+
+// apiKeyAuthenticator authenticates requests by static api keys from configuration
+type apiKeyAuthenticator struct {
+	// principals is keyed by the sha256 of the api key, so lookups do not depend on the key content
+	principals map[[sha256.Size]byte]models.Principal
+}
+
+// newAPIKeyAuthenticator returns nil if no api keys are configured
+func newAPIKeyAuthenticator(conf Configuration) (*apiKeyAuthenticator, error) {
+	if !conf.IsSet(authAPIKeysKey) {
+		return nil, nil
+	}
+
+	entries, err := cast.ToSliceE(conf.Get(authAPIKeysKey))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid api keys in configuration key (%s)", authAPIKeysKey)
+	}
+
+	a := &apiKeyAuthenticator{
+		principals: make(map[[sha256.Size]byte]models.Principal, len(entries)),
+	}
+
+	for i, entry := range entries {
+		fields, err := cast.ToStringMapE(entry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid api key entry (%d) in configuration key (%s)", i, authAPIKeysKey)
+		}
+
+		key := cast.ToString(fields[apiKeyField])
+		subject := cast.ToString(fields[apiKeySubjectField])
+		if key == "" || subject == "" {
+			return nil, errors.Errorf("Api key entry (%d) in configuration key (%s) must have a key and a subject", i, authAPIKeysKey)
+		}
+
+		if key == developmentAPIKey && conf.Environment() != developmentEnvironment {
+			return nil, errors.Errorf("Api key entry (%d) in configuration key (%s) is the development api key, which is only accepted in the (%s) environment", i, authAPIKeysKey, developmentEnvironment)
+		}
+
+		roles, err := cast.ToStringSliceE(fields[apiKeyRolesField])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid roles of api key entry (%d) in configuration key (%s)", i, authAPIKeysKey)
+		}
+
+		a.principals[sha256.Sum256([]byte(key))] = models.Principal{
+			Subject: subject,
+			Roles:   roles,
+			Tenant:  cast.ToString(fields[apiKeyTenantField]),
+		}
+	}
+
+	return a, nil
+}
+
+func (a *apiKeyAuthenticator) authenticate(r *http.Request) (models.Principal, bool, error) {
+	key := r.Header.Get(apiKeyHeader)
+	if key == "" {
+		return models.Principal{}, false, nil
+	}
+
+	p, ok := a.principals[sha256.Sum256([]byte(key))]
+	if !ok {
+		return models.Principal{}, false, errors.New("Invalid api key").SetType(errors.ErrorTypeUnauthorized)
+	}
+
+	return p, true, nil
+}
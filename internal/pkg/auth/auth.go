@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	authBaseKey           = "auth"
+	authEnabledKey        = authBaseKey + ".enabled"
+	authAnonymousRolesKey = authBaseKey + ".anonymousRoles"
+	authAPIKeysKey        = authBaseKey + ".apiKeys"
+	authJWTBaseKey        = authBaseKey + ".jwt"
+	authJWTSecretKey      = authJWTBaseKey + ".hmacSecret"
+	authJWTJWKSFileKey    = authJWTBaseKey + ".jwksFile"
+	authJWTJWKSURLKey     = authJWTBaseKey + ".jwksURL"
+	authJWTJWKSTimeoutKey = authJWTBaseKey + ".jwksTimeout"
+	authJWTIssuerKey      = authJWTBaseKey + ".issuer"
+	authJWTAudienceKey    = authJWTBaseKey + ".audience"
+	authJWTRolesClaimKey  = authJWTBaseKey + ".rolesClaim"
+	authJWTTenantClaimKey = authJWTBaseKey + ".tenantClaim"
+
+	anonymousSubject = "anonymous"
+)
+
+// Configuration expose an interface of configuration related actions
+type Configuration interface {
+	Get(key string) interface{}
+	GetString(key string) (string, error)
+	GetBool(key string) (bool, error)
+	GetDuration(key string) (time.Duration, error)
+	IsSet(key string) bool
+	Environment() string
+}
+
+// authenticator is a single authentication scheme.
+// ok is false when the request carries no credentials of that scheme
+type authenticator interface {
+	authenticate(r *http.Request) (p models.Principal, ok bool, err error)
+}
+
+// Service authenticates requests using the configured schemes
+type Service struct {
+	enabled        bool
+	anonymous      models.Principal
+	authenticators []authenticator
+}
+
+type contextKey struct{}
+
+// NewService returns a new instance of the Service struct
+func NewService(conf Configuration) (*Service, error) {
+	enabled, err := conf.GetBool(authEnabledKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fail to get auth enabled flag from configuration key (%s)", authEnabledKey)
+	}
+
+	s := &Service{
+		enabled: enabled,
+	}
+
+	if !enabled {
+		roles, err := cast.ToStringSliceE(conf.Get(authAnonymousRolesKey))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid anonymous roles in configuration key (%s)", authAnonymousRolesKey)
+		}
+
+		s.anonymous = models.Principal{
+			Subject: anonymousSubject,
+			Roles:   roles,
+		}
+		return s, nil
+	}
+
+	apiKeys, err := newAPIKeyAuthenticator(conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to init api key authentication")
+	}
+
+	if apiKeys != nil {
+		s.authenticators = append(s.authenticators, apiKeys)
+	}
+
+	jwt, err := newJWTAuthenticator(conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to init jwt authentication")
+	}
+
+	if jwt != nil {
+		s.authenticators = append(s.authenticators, jwt)
+	}
+
+	if len(s.authenticators) == 0 {
+		return nil, errors.New("Authentication is enabled but neither api keys nor jwt are configured")
+	}
+
+	return s, nil
+}
+
+// Authenticate returns the principal of the request.
+// When authentication is disabled every request is made by an anonymous principal with the configured roles
+func (s *Service) Authenticate(r *http.Request) (models.Principal, error) {
+	if !s.enabled {
+		return s.anonymous, nil
+	}
+
+	for _, a := range s.authenticators {
+		p, ok, err := a.authenticate(r)
+		if err != nil {
+			return models.Principal{}, err
+		}
+
+		if ok {
+			return p, nil
+		}
+	}
+
+	return models.Principal{}, errors.New("Missing credentials").SetType(errors.ErrorTypeUnauthorized)
+}
+
+// NewContext returns a copy of ctx which carries the principal p
+func NewContext(ctx context.Context, p models.Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, if any
+func FromContext(ctx context.Context) (models.Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(models.Principal)
+	return p, ok
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"github.com/spf13/cast"
+)
+
+// testConfiguration is a Configuration of fixed values
+type testConfiguration struct {
+	values      map[string]interface{}
+	environment string
+}
+
+func (c testConfiguration) Get(key string) interface{} {
+	return c.values[key]
+}
+
+func (c testConfiguration) GetString(key string) (string, error) {
+	return cast.ToStringE(c.values[key])
+}
+
+func (c testConfiguration) GetBool(key string) (bool, error) {
+	return cast.ToBoolE(c.values[key])
+}
+
+func (c testConfiguration) GetDuration(key string) (time.Duration, error) {
+	return cast.ToDurationE(c.values[key])
+}
+
+func (c testConfiguration) IsSet(key string) bool {
+	_, ok := c.values[key]
+	return ok
+}
+
+func (c testConfiguration) Environment() string {
+	return c.environment
+}
+
+func TestNewService(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    testConfiguration
+		wantErr bool
+	}{
+		{
+			name: "api keys configured expect no error",
+			conf: testConfiguration{values: map[string]interface{}{
+				authEnabledKey: true,
+				authAPIKeysKey: []interface{}{map[string]interface{}{"key": "secret-key", "subject": "alice", "roles": []interface{}{"reader"}, "tenant": "tenant-a"}},
+			}},
+			wantErr: false,
+		},
+		{
+			name:    "authentication disabled expect no error",
+			conf:    testConfiguration{values: map[string]interface{}{authEnabledKey: false, authAnonymousRolesKey: []string{"reader"}}},
+			wantErr: false,
+		},
+		{
+			name:    "authentication enabled without schemes expect error",
+			conf:    testConfiguration{values: map[string]interface{}{authEnabledKey: true}},
+			wantErr: true,
+		},
+		{
+			name: "api key without subject expect error",
+			conf: testConfiguration{values: map[string]interface{}{
+				authEnabledKey: true,
+				authAPIKeysKey: []interface{}{map[string]interface{}{"key": "secret-key"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "development api key in development environment expect no error",
+			conf: testConfiguration{values: map[string]interface{}{
+				authEnabledKey: true,
+				authAPIKeysKey: []interface{}{map[string]interface{}{"key": developmentAPIKey, "subject": "dev", "roles": []interface{}{"admin"}, "tenant": "default"}},
+			}, environment: developmentEnvironment},
+			wantErr: false,
+		},
+		{
+			name: "development api key outside development environment expect error",
+			conf: testConfiguration{values: map[string]interface{}{
+				authEnabledKey: true,
+				authAPIKeysKey: []interface{}{map[string]interface{}{"key": developmentAPIKey, "subject": "dev", "roles": []interface{}{"admin"}, "tenant": "default"}},
+			}, environment: "production"},
+			wantErr: true,
+		},
+		{
+			name: "development api key without environment expect error",
+			conf: testConfiguration{values: map[string]interface{}{
+				authEnabledKey: true,
+				authAPIKeysKey: []interface{}{map[string]interface{}{"key": developmentAPIKey, "subject": "dev", "roles": []interface{}{"admin"}, "tenant": "default"}},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewService(tt.conf); (err != nil) != tt.wantErr {
+				t.Errorf("NewService() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_Authenticate(t *testing.T) {
+	conf := testConfiguration{values: map[string]interface{}{
+		authEnabledKey: true,
+		authAPIKeysKey: []interface{}{
+			map[string]interface{}{"key": "alice-key", "subject": "alice", "roles": []interface{}{"writer"}, "tenant": "tenant-a"},
+			map[string]interface{}{"key": "bob-key", "subject": "bob", "roles": []interface{}{"reader"}, "tenant": "tenant-b"},
+		},
+	}}
+
+	s, err := NewService(conf)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		apiKey   string
+		want     models.Principal
+		wantErr  bool
+		wantType errors.ErrorType
+	}{
+		{
+			name:    "known api key expect principal of the key",
+			apiKey:  "alice-key",
+			want:    models.Principal{Subject: "alice", Roles: []string{"writer"}, Tenant: "tenant-a"},
+			wantErr: false,
+		},
+		{
+			name:    "other known api key expect principal of that key",
+			apiKey:  "bob-key",
+			want:    models.Principal{Subject: "bob", Roles: []string{"reader"}, Tenant: "tenant-b"},
+			wantErr: false,
+		},
+		{
+			name:     "unknown api key expect unauthorized error",
+			apiKey:   "alice-key ",
+			wantErr:  true,
+			wantType: errors.ErrorTypeUnauthorized,
+		},
+		{
+			name:     "missing credentials expect unauthorized error",
+			wantErr:  true,
+			wantType: errors.ErrorTypeUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/documents", nil)
+			if tt.apiKey != "" {
+				r.Header.Set(apiKeyHeader, tt.apiKey)
+			}
+
+			got, err := s.Authenticate(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !errors.IsType(err, tt.wantType) {
+					t.Errorf("Authenticate() error = %v, want error of type %v", err, tt.wantType)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authenticate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
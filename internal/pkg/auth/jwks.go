@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"microservice/internal/pkg/errors"
+)
+
+const (
+	// jwksMinRefreshInterval bounds how often an unknown key id may trigger a reload of the key set
+	jwksMinRefreshInterval = time.Minute
+
+	jwkTypeRSA = "RSA"
+)
+
+// jwks is a json web key set of RSA public keys, loaded from a file or a URL
+type jwks struct {
+	fetch func() ([]byte, error)
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	lastRefresh time.Time
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// newJWKSFromConfiguration returns nil if no jwks source is configured
+func newJWKSFromConfiguration(conf Configuration) (*jwks, error) {
+	if conf.IsSet(authJWTJWKSFileKey) && conf.IsSet(authJWTJWKSURLKey) {
+		return nil, errors.Errorf("Only one of configuration keys (%s) and (%s) may be set", authJWTJWKSFileKey, authJWTJWKSURLKey)
+	}
+
+	var fetch func() ([]byte, error)
+	switch {
+	case conf.IsSet(authJWTJWKSFileKey):
+		file, err := conf.GetString(authJWTJWKSFileKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to get jwks file from configuration key (%s)", authJWTJWKSFileKey)
+		}
+
+		fetch = func() ([]byte, error) {
+			return ioutil.ReadFile(file)
+		}
+	case conf.IsSet(authJWTJWKSURLKey):
+		url, err := conf.GetString(authJWTJWKSURLKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to get jwks url from configuration key (%s)", authJWTJWKSURLKey)
+		}
+
+		timeout := defaultJWKSTimeout
+		if conf.IsSet(authJWTJWKSTimeoutKey) {
+			if timeout, err = conf.GetDuration(authJWTJWKSTimeoutKey); err != nil {
+				return nil, errors.Wrapf(err, "Fail to get jwks timeout from configuration key (%s)", authJWTJWKSTimeoutKey)
+			}
+		}
+
+		client := &http.Client{Timeout: timeout}
+		fetch = func() ([]byte, error) {
+			return fetchURL(client, url)
+		}
+	default:
+		return nil, nil
+	}
+
+	k := &jwks{
+		fetch: fetch,
+	}
+
+	if err := k.refresh(); err != nil {
+		return nil, errors.Wrap(err, "Failed to load jwks")
+	}
+
+	return k, nil
+}
+
+// key returns the public key of the given key id.
+// A token without key id is accepted only if the set has exactly one key
+func (k *jwks) key(kid string) (*rsa.PublicKey, error) {
+	if key, ok := k.lookup(kid); ok {
+		return key, nil
+	}
+
+	k.mu.RLock()
+	stale := time.Since(k.lastRefresh) > jwksMinRefreshInterval
+	k.mu.RUnlock()
+
+	if stale {
+		if err := k.refresh(); err != nil {
+			return nil, errors.Wrap(err, "Failed to reload jwks")
+		}
+
+		if key, ok := k.lookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, errors.Errorf("unknown key id (%s)", kid)
+}
+
+func (k *jwks) lookup(kid string) (*rsa.PublicKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+func (k *jwks) refresh() error {
+	b, err := k.fetch()
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch jwks")
+	}
+
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.lastRefresh = time.Now()
+	k.mu.Unlock()
+
+	return nil
+}
+
+func parseJWKS(b []byte) (map[string]*rsa.PublicKey, error) {
+	var set jwkSet
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal jwks")
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Kty != jwkTypeRSA || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid modulus of jwk (%s)", key.Kid)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid exponent of jwk (%s)", key.Kid)
+		}
+
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwks contains no RSA signing keys")
+	}
+
+	return keys, nil
+}
+
+func fetchURL(client *http.Client, url string) ([]byte, error) {
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status (%s) from (%s)", res.Status, url)
+	}
+
+	return ioutil.ReadAll(res.Body)
+}
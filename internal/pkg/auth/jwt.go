@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	authorizationHeader = "Authorization"
+	bearerScheme        = "bearer"
+
+	defaultRolesClaim  = "roles"
+	defaultTenantClaim = "tenant"
+	defaultJWKSTimeout = 10 * time.Second
+)
+
+// jwtAuthenticator authenticates requests by HS256 or RS256 signed bearer tokens
+type jwtAuthenticator struct {
+	hmacSecret  []byte
+	keys        *jwks
+	issuer      string
+	audience    string
+	rolesClaim  string
+	tenantClaim string
+	methods     []string
+}
+
+// newJWTAuthenticator returns nil if neither a hmac secret nor a jwks source is configured
+func newJWTAuthenticator(conf Configuration) (*jwtAuthenticator, error) {
+	a := &jwtAuthenticator{
+		rolesClaim:  defaultRolesClaim,
+		tenantClaim: defaultTenantClaim,
+	}
+
+	if conf.IsSet(authJWTSecretKey) {
+		secret, err := conf.GetString(authJWTSecretKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to get jwt hmac secret from configuration key (%s)", authJWTSecretKey)
+		}
+
+		if secret != "" {
+			a.hmacSecret = []byte(secret)
+			a.methods = append(a.methods, jwt.SigningMethodHS256.Alg())
+		}
+	}
+
+	keys, err := newJWKSFromConfiguration(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if keys != nil {
+		a.keys = keys
+		a.methods = append(a.methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	if len(a.methods) == 0 {
+		return nil, nil
+	}
+
+	for key, target := range map[string]*string{
+		authJWTIssuerKey:      &a.issuer,
+		authJWTAudienceKey:    &a.audience,
+		authJWTRolesClaimKey:  &a.rolesClaim,
+		authJWTTenantClaimKey: &a.tenantClaim,
+	} {
+		if !conf.IsSet(key) {
+			continue
+		}
+
+		v, err := conf.GetString(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to get jwt setting from configuration key (%s)", key)
+		}
+		*target = v
+	}
+
+	return a, nil
+}
+
+func (a *jwtAuthenticator) authenticate(r *http.Request) (models.Principal, bool, error) {
+	header := r.Header.Get(authorizationHeader)
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != bearerScheme {
+		return models.Principal{}, false, nil
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(parts[1], claims, a.keyFunc, jwt.WithValidMethods(a.methods)); err != nil {
+		return models.Principal{}, false, errors.Errorf("Invalid bearer token: %s", err).SetType(errors.ErrorTypeUnauthorized)
+	}
+
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		return models.Principal{}, false, errors.New("Invalid bearer token: unexpected issuer").SetType(errors.ErrorTypeUnauthorized)
+	}
+
+	if a.audience != "" && !claims.VerifyAudience(a.audience, true) {
+		return models.Principal{}, false, errors.New("Invalid bearer token: unexpected audience").SetType(errors.ErrorTypeUnauthorized)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return models.Principal{}, false, errors.New("Invalid bearer token: missing subject").SetType(errors.ErrorTypeUnauthorized)
+	}
+
+	tenant, _ := claims[a.tenantClaim].(string)
+
+	return models.Principal{
+		Subject: subject,
+		Roles:   rolesFromClaim(claims[a.rolesClaim]),
+		Tenant:  tenant,
+	}, true, nil
+}
+
+func (a *jwtAuthenticator) keyFunc(t *jwt.Token) (interface{}, error) {
+	switch t.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return a.hmacSecret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := t.Header["kid"].(string)
+		return a.keys.key(kid)
+	default:
+		return nil, errors.Errorf("unexpected signing method (%s)", t.Method.Alg())
+	}
+}
+
+// rolesFromClaim accepts either a list of roles or a space separated string, like the oauth2 scope claim
+func rolesFromClaim(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		roles := make([]string, 0, len(v))
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+		return roles
+	default:
+		return nil
+	}
+}
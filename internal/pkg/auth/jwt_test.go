@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	testHMACSecret = "test-secret"
+	testIssuer     = "https://issuer.example.com"
+	testAudience   = "microservice"
+	testKeyID      = "key-1"
+)
+
+func TestJWTAuthenticator_authenticate(t *testing.T) {
+	rsaKey := newTestRSAKey(t)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := ioutil.WriteFile(jwksFile, testJWKS(t, map[string]*rsa.PublicKey{testKeyID: &rsaKey.PublicKey}), 0600); err != nil {
+		t.Fatalf("Failed to write jwks file. Error: %s", err)
+	}
+
+	a, err := newJWTAuthenticator(testConfiguration{values: map[string]interface{}{
+		authJWTSecretKey:   testHMACSecret,
+		authJWTJWKSFileKey: jwksFile,
+		authJWTIssuerKey:   testIssuer,
+		authJWTAudienceKey: testAudience,
+	}})
+	if err != nil {
+		t.Fatalf("newJWTAuthenticator() error = %v", err)
+	}
+
+	now := time.Now()
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":    "alice",
+			"iss":    testIssuer,
+			"aud":    testAudience,
+			"exp":    now.Add(time.Hour).Unix(),
+			"roles":  []string{"reader", "writer"},
+			"tenant": "tenant-a",
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+
+	hs256 := func(c jwt.MapClaims) string {
+		return signToken(t, jwt.NewWithClaims(jwt.SigningMethodHS256, c), []byte(testHMACSecret))
+	}
+
+	rs256 := func(kid string, c jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+		token.Header["kid"] = kid
+		return signToken(t, token, rsaKey)
+	}
+
+	alice := models.Principal{Subject: "alice", Roles: []string{"reader", "writer"}, Tenant: "tenant-a"}
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          models.Principal
+		wantOK        bool
+		wantErr       bool
+	}{
+		{
+			name:          "valid HS256 token expect principal of the claims",
+			authorization: "Bearer " + hs256(claims(nil)),
+			want:          alice,
+			wantOK:        true,
+			wantErr:       false,
+		},
+		{
+			name:          "valid RS256 token signed by a key of the jwks expect principal of the claims",
+			authorization: "Bearer " + rs256(testKeyID, claims(nil)),
+			want:          alice,
+			wantOK:        true,
+			wantErr:       false,
+		},
+		{
+			name:          "roles as a space separated string expect roles split",
+			authorization: "bearer " + hs256(claims(jwt.MapClaims{"roles": "reader writer"})),
+			want:          alice,
+			wantOK:        true,
+			wantErr:       false,
+		},
+		{
+			name:    "no authorization header expect no principal and no error",
+			wantOK:  false,
+			wantErr: false,
+		},
+		{
+			name:          "other authorization scheme expect no principal and no error",
+			authorization: "Basic YWxpY2U6c2VjcmV0",
+			wantOK:        false,
+			wantErr:       false,
+		},
+		{
+			name:          "token signed with HS384 expect error",
+			authorization: "Bearer " + signToken(t, jwt.NewWithClaims(jwt.SigningMethodHS384, claims(nil)), []byte(testHMACSecret)),
+			wantErr:       true,
+		},
+		{
+			name:          "unsigned token of the none algorithm expect error",
+			authorization: "Bearer " + signToken(t, jwt.NewWithClaims(jwt.SigningMethodNone, claims(nil)), jwt.UnsafeAllowNoneSignatureType),
+			wantErr:       true,
+		},
+		{
+			name:          "HS256 token signed with another secret expect error",
+			authorization: "Bearer " + signToken(t, jwt.NewWithClaims(jwt.SigningMethodHS256, claims(nil)), []byte("other-secret")),
+			wantErr:       true,
+		},
+		{
+			name:          "RS256 token of an unknown key id expect error",
+			authorization: "Bearer " + rs256("unknown", claims(nil)),
+			wantErr:       true,
+		},
+		{
+			name:          "expired token expect error",
+			authorization: "Bearer " + hs256(claims(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()})),
+			wantErr:       true,
+		},
+		{
+			name:          "token not valid yet expect error",
+			authorization: "Bearer " + hs256(claims(jwt.MapClaims{"nbf": now.Add(time.Hour).Unix()})),
+			wantErr:       true,
+		},
+		{
+			name:          "token of another issuer expect error",
+			authorization: "Bearer " + hs256(claims(jwt.MapClaims{"iss": "https://other.example.com"})),
+			wantErr:       true,
+		},
+		{
+			name:          "token without issuer expect error",
+			authorization: "Bearer " + hs256(claims(jwt.MapClaims{"iss": nil})),
+			wantErr:       true,
+		},
+		{
+			name:          "token of another audience expect error",
+			authorization: "Bearer " + hs256(claims(jwt.MapClaims{"aud": []string{"other"}})),
+			wantErr:       true,
+		},
+		{
+			name:          "token without subject expect error",
+			authorization: "Bearer " + hs256(claims(jwt.MapClaims{"sub": nil})),
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/documents", nil)
+			if tt.authorization != "" {
+				r.Header.Set(authorizationHeader, tt.authorization)
+			}
+
+			got, ok, err := a.authenticate(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !errors.IsType(err, errors.ErrorTypeUnauthorized) {
+					t.Errorf("authenticate() error = %v, want unauthorized error", err)
+				}
+				return
+			}
+
+			if ok != tt.wantOK {
+				t.Fatalf("authenticate() ok = %v, want %v", ok, tt.wantOK)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("authenticate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWKS_key(t *testing.T) {
+	oldKey, newKey := newTestRSAKey(t), newTestRSAKey(t)
+
+	tests := []struct {
+		name        string
+		kid         string
+		sinceLoad   time.Duration
+		rotated     bool
+		wantFetches int
+		wantErr     bool
+	}{
+		{
+			name:        "known key id expect key without refresh",
+			kid:         "old",
+			sinceLoad:   time.Hour,
+			wantFetches: 0,
+			wantErr:     false,
+		},
+		{
+			name:        "unknown key id after rotation expect key after one refresh",
+			kid:         "new",
+			sinceLoad:   2 * jwksMinRefreshInterval,
+			rotated:     true,
+			wantFetches: 1,
+			wantErr:     false,
+		},
+		{
+			name:        "unknown key id within a minute of the last refresh expect error without refresh",
+			kid:         "new",
+			sinceLoad:   jwksMinRefreshInterval / 2,
+			rotated:     true,
+			wantFetches: 0,
+			wantErr:     true,
+		},
+		{
+			name:        "unknown key id missing after refresh expect error",
+			kid:         "unknown",
+			sinceLoad:   2 * jwksMinRefreshInterval,
+			wantFetches: 1,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			published := map[string]*rsa.PublicKey{"old": &oldKey.PublicKey}
+			if tt.rotated {
+				published["new"] = &newKey.PublicKey
+			}
+
+			fetches := 0
+			k := &jwks{
+				fetch: func() ([]byte, error) {
+					fetches++
+					return testJWKS(t, published), nil
+				},
+				keys:        map[string]*rsa.PublicKey{"old": &oldKey.PublicKey},
+				lastRefresh: time.Now().Add(-tt.sinceLoad),
+			}
+
+			_, err := k.key(tt.kid)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("key() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// a second lookup of the same key id is within a minute of any refresh of the first
+			_, _ = k.key(tt.kid)
+
+			if fetches != tt.wantFetches {
+				t.Errorf("key() fetched jwks %d times, want %d", fetches, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func newTestRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate rsa key. Error: %s", err)
+	}
+
+	return key
+}
+
+func testJWKS(t *testing.T, keys map[string]*rsa.PublicKey) []byte {
+	set := jwkSet{}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: jwkTypeRSA,
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+
+	b, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("Failed to marshal jwks. Error: %s", err)
+	}
+
+	return b
+}
+
+func signToken(t *testing.T, token *jwt.Token, key interface{}) string {
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token. Error: %s", err)
+	}
+
+	return s
+}
@@ -0,0 +1,44 @@
+package backoff
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Policy is an exponential backoff with jitter. The delay before retry attempt n is Initial * Multiplier^n,
+// bounded by Max and randomized by up to Jitter of itself in both directions
+type Policy struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// Delay returns the delay before the retry following attempt, where the first attempt is 0
+func (p Policy) Delay(attempt int) time.Duration {
+	d := float64(p.Initial) * math.Pow(p.Multiplier, float64(attempt))
+	if p.Max > 0 && d > float64(p.Max) {
+		d = float64(p.Max)
+	}
+
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(d)
+}
+
+// Wait sleeps for the delay following attempt. It returns the error of ctx if ctx is done first
+func (p Policy) Wait(ctx context.Context, attempt int) error {
+	t := time.NewTimer(p.Delay(attempt))
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Backend stores cached values by key until their ttl expires
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// LRU is an in-process Backend bounded by a number of entries and their total size in bytes.
+// The least recently used entries are evicted first
+type LRU struct {
+	maxEntries int
+	maxBytes   int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	size    int
+
+	// onEvict is called with the number of entries evicted to make room
+	onEvict func(n int)
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRU returns a new instance of the LRU struct
+func NewLRU(maxEntries int, maxBytes int, onEvict func(n int)) *LRU {
+	if onEvict == nil {
+		onEvict = func(int) {}
+	}
+
+	return &LRU{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		onEvict:    onEvict,
+	}
+}
+
+// Get returns the value of key, unless it is missing or expired
+func (c *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expires) {
+		c.remove(el)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(el)
+	return e.value, true, nil
+}
+
+// Set stores value under key for ttl. Values larger than the cache are not stored
+func (c *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+
+	if len(value) > c.maxBytes {
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: time.Now().Add(ttl)})
+	c.size += len(value)
+
+	evicted := 0
+	for len(c.entries) > c.maxEntries || c.size > c.maxBytes {
+		c.remove(c.order.Back())
+		evicted++
+	}
+
+	if evicted > 0 {
+		c.onEvict(evicted)
+	}
+
+	return nil
+}
+
+// Delete removes key
+func (c *LRU) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+
+	return nil
+}
+
+func (c *LRU) remove(el *list.Element) {
+	e := c.order.Remove(el).(*lruEntry)
+	delete(c.entries, e.key)
+	c.size -= len(e.value)
+}
+
+// Tiered is a Backend that keeps the values of a shared, distributed backend in a local backend.
+// Reads go to the local backend first, writes and deletes go to both. The deletes of other instances only reach the
+// remote backend, so values are kept locally for localTTL at most
+type Tiered struct {
+	local    Backend
+	remote   Backend
+	localTTL time.Duration
+}
+
+// NewTiered returns a new instance of the Tiered struct
+func NewTiered(local Backend, remote Backend, localTTL time.Duration) *Tiered {
+	return &Tiered{local: local, remote: remote, localTTL: localTTL}
+}
+
+// Get returns the value of key from the local backend, or from the remote backend when it isn't kept locally.
+// A value read from the remote backend is kept locally
+func (t *Tiered) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if value, ok, err := t.local.Get(ctx, key); err != nil || ok {
+		return value, ok, err
+	}
+
+	value, ok, err := t.remote.Get(ctx, key)
+	if err != nil || !ok {
+		return value, ok, err
+	}
+
+	// the value was read, failing to keep it locally only costs the next read a round trip
+	_ = t.local.Set(ctx, key, value, t.localTTL)
+
+	return value, true, nil
+}
+
+// Set stores value under key in both backends
+func (t *Tiered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := t.remote.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	if ttl > t.localTTL {
+		ttl = t.localTTL
+	}
+
+	return t.local.Set(ctx, key, value, ttl)
+}
+
+// Delete removes key from both backends
+func (t *Tiered) Delete(ctx context.Context, key string) error {
+	if err := t.remote.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	return t.local.Delete(ctx, key)
+}
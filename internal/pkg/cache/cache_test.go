@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"microservice/internal/pkg/errors"
+)
+
+// failingBackend is a Backend whose every operation fails
+type failingBackend struct{}
+
+func (failingBackend) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("Backend is down")
+}
+
+func (failingBackend) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("Backend is down")
+}
+
+func (failingBackend) Delete(context.Context, string) error {
+	return errors.New("Backend is down")
+}
+
+// keys returns the keys of the entries of c, most recently used first
+func keys(c *LRU) []string {
+	var keys []string
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		keys = append(keys, el.Value.(*lruEntry).key)
+	}
+
+	return keys
+}
+
+// lruOp sets key to a value of size bytes, or reads key when read is set
+type lruOp struct {
+	key  string
+	size int
+	read bool
+}
+
+func TestLRU_eviction(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		maxEntries  int
+		maxBytes    int
+		ops         []lruOp
+		wantKeys    []string
+		wantEvicted int
+	}{
+		{
+			name:        "more entries than the limit expect least recently set evicted",
+			maxEntries:  2,
+			maxBytes:    100,
+			ops:         []lruOp{{key: "a", size: 1}, {key: "b", size: 1}, {key: "c", size: 1}},
+			wantKeys:    []string{"c", "b"},
+			wantEvicted: 1,
+		},
+		{
+			name:        "read entry expect least recently read evicted instead",
+			maxEntries:  2,
+			maxBytes:    100,
+			ops:         []lruOp{{key: "a", size: 1}, {key: "b", size: 1}, {key: "a", read: true}, {key: "c", size: 1}},
+			wantKeys:    []string{"c", "a"},
+			wantEvicted: 1,
+		},
+		{
+			name:        "more bytes than the limit expect entries evicted until they fit",
+			maxEntries:  10,
+			maxBytes:    4,
+			ops:         []lruOp{{key: "a", size: 1}, {key: "b", size: 1}, {key: "c", size: 1}, {key: "d", size: 1}, {key: "e", size: 2}},
+			wantKeys:    []string{"e", "d", "c"},
+			wantEvicted: 2,
+		},
+		{
+			name:       "value larger than the cache expect value not stored",
+			maxEntries: 10,
+			maxBytes:   4,
+			ops:        []lruOp{{key: "a", size: 1}, {key: "b", size: 5}},
+			wantKeys:   []string{"a"},
+		},
+		{
+			name:       "key set again expect value replaced without eviction",
+			maxEntries: 2,
+			maxBytes:   100,
+			ops:        []lruOp{{key: "a", size: 1}, {key: "b", size: 1}, {key: "a", size: 1}},
+			wantKeys:   []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evicted := 0
+			c := NewLRU(tt.maxEntries, tt.maxBytes, func(n int) { evicted += n })
+
+			for _, op := range tt.ops {
+				if op.read {
+					if _, ok, _ := c.Get(ctx, op.key); !ok {
+						t.Fatalf("Get() of (%s) missed", op.key)
+					}
+					continue
+				}
+
+				_ = c.Set(ctx, op.key, make([]byte, op.size), time.Minute)
+			}
+
+			if got := keys(c); !reflect.DeepEqual(got, tt.wantKeys) {
+				t.Errorf("LRU keys got = %v, want %v", got, tt.wantKeys)
+			}
+
+			if evicted != tt.wantEvicted {
+				t.Errorf("LRU evicted = %v, want %v", evicted, tt.wantEvicted)
+			}
+		})
+	}
+}
+
+func TestLRU_expiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10, 100, nil)
+
+	_ = c.Set(ctx, "expired", []byte("x"), -time.Second)
+	_ = c.Set(ctx, "live", []byte("x"), time.Minute)
+
+	if _, ok, _ := c.Get(ctx, "expired"); ok {
+		t.Error("Get() of expired entry hit, want miss")
+	}
+
+	if value, ok, _ := c.Get(ctx, "live"); !ok || string(value) != "x" {
+		t.Errorf("Get() of live entry got = %s, %v, want x, true", value, ok)
+	}
+
+	_ = c.Delete(ctx, "live")
+	if _, ok, _ := c.Get(ctx, "live"); ok {
+		t.Error("Get() of deleted entry hit, want miss")
+	}
+
+	if c.size != 0 {
+		t.Errorf("LRU size = %v, want 0 after every entry was removed", c.size)
+	}
+}
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("value only in remote backend expect read from remote and kept locally", func(t *testing.T) {
+		local, remote := NewLRU(10, 100, nil), NewLRU(10, 100, nil)
+		_ = remote.Set(ctx, "key", []byte("remote"), time.Minute)
+
+		value, ok, err := NewTiered(local, remote, time.Minute).Get(ctx, "key")
+		if err != nil || !ok || string(value) != "remote" {
+			t.Errorf("Get() got = %s, %v, %v, want remote, true, nil", value, ok, err)
+		}
+
+		if value, ok, _ := local.Get(ctx, "key"); !ok || string(value) != "remote" {
+			t.Errorf("Get() kept %s, %v locally, want remote, true", value, ok)
+		}
+	})
+
+	t.Run("value missing from both backends expect miss and nothing kept locally", func(t *testing.T) {
+		local, remote := NewLRU(10, 100, nil), NewLRU(10, 100, nil)
+
+		if _, ok, err := NewTiered(local, remote, time.Minute).Get(ctx, "key"); err != nil || ok {
+			t.Errorf("Get() got = %v, %v, want false, nil", ok, err)
+		}
+
+		if _, ok, _ := local.Get(ctx, "key"); ok {
+			t.Error("Get() kept a missing value locally")
+		}
+	})
+
+	t.Run("value set for longer than the local ttl expect local copy expired first", func(t *testing.T) {
+		local, remote := NewLRU(10, 100, nil), NewLRU(10, 100, nil)
+		tiered := NewTiered(local, remote, time.Millisecond)
+
+		if err := tiered.Set(ctx, "key", []byte("value"), time.Minute); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		if _, ok, _ := local.Get(ctx, "key"); ok {
+			t.Error("Set() kept the value locally for longer than the local ttl")
+		}
+
+		if _, ok, _ := remote.Get(ctx, "key"); !ok {
+			t.Error("Set() didn't keep the value in the remote backend for its ttl")
+		}
+	})
+
+	t.Run("value in both backends expect read from local", func(t *testing.T) {
+		local, remote := NewLRU(10, 100, nil), NewLRU(10, 100, nil)
+		_ = local.Set(ctx, "key", []byte("local"), time.Minute)
+		_ = remote.Set(ctx, "key", []byte("remote"), time.Minute)
+
+		value, ok, err := NewTiered(local, remote, time.Minute).Get(ctx, "key")
+		if err != nil || !ok || string(value) != "local" {
+			t.Errorf("Get() got = %s, %v, %v, want local, true, nil", value, ok, err)
+		}
+	})
+
+	t.Run("set and delete expect both backends changed", func(t *testing.T) {
+		local, remote := NewLRU(10, 100, nil), NewLRU(10, 100, nil)
+		tiered := NewTiered(local, remote, time.Minute)
+
+		if err := tiered.Set(ctx, "key", []byte("value"), time.Minute); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+
+		for name, backend := range map[string]*LRU{"local": local, "remote": remote} {
+			if _, ok, _ := backend.Get(ctx, "key"); !ok {
+				t.Errorf("Set() didn't store the value in the %s backend", name)
+			}
+		}
+
+		if err := tiered.Delete(ctx, "key"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+
+		for name, backend := range map[string]*LRU{"local": local, "remote": remote} {
+			if _, ok, _ := backend.Get(ctx, "key"); ok {
+				t.Errorf("Delete() didn't remove the value from the %s backend", name)
+			}
+		}
+	})
+
+	t.Run("failing remote backend expect errors and local backend not written", func(t *testing.T) {
+		local := NewLRU(10, 100, nil)
+		tiered := NewTiered(local, failingBackend{}, time.Minute)
+
+		if _, _, err := tiered.Get(ctx, "key"); err == nil {
+			t.Error("Get() error = nil, want error of the remote backend")
+		}
+
+		if err := tiered.Set(ctx, "key", []byte("value"), time.Minute); err == nil {
+			t.Error("Set() error = nil, want error of the remote backend")
+		}
+
+		if _, ok, _ := local.Get(ctx, "key"); ok {
+			t.Error("Set() stored the value locally although the remote backend failed")
+		}
+	})
+}
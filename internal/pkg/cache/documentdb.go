@@ -0,0 +1,253 @@
+package cache
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/tenancy"
+	"microservice/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/sync/singleflight"
+)
+
+// metrics of the document cache, exposed with the other expvar variables
+var metrics = expvar.NewMap("documentCache")
+
+// Store is the document database decorated by DocumentDB
+type Store interface {
+	GetDocumentByID(ctx context.Context, id string, opts models.ReadOptions, result interface{}) error
+	GetDocumentByName(ctx context.Context, name string, result interface{}) error
+	SaveDocument(ctx context.Context, doc models.Document) (string, error)
+	SaveDocumentWithID(ctx context.Context, id string, doc models.Document) error
+	UpdateDocument(ctx context.Context, id string, doc models.Document) error
+	UpsertDocumentByName(ctx context.Context, doc models.Document) (string, bool, error)
+	CountDocuments(ctx context.Context) (int64, error)
+	SearchDocuments(ctx context.Context, query models.SearchQuery) ([]models.SearchResult, error)
+	Teardown(ctx context.Context) error
+}
+
+// DocumentDB decorates a Store with a read-through cache of the documents read by id.
+// Concurrent misses of a document are coalesced into a single read of the store, and writes invalidate the
+// cached document. Failures of the backend are counted and fall back to the store, they don't fail operations
+type DocumentDB struct {
+	Store
+
+	enabled     bool
+	backend     Backend
+	ttl         time.Duration
+	loadTimeout time.Duration
+	loads       singleflight.Group
+
+	// mu guards loading, the documents being read from the store, and orders caching them with their invalidation
+	mu      sync.Mutex
+	loading map[string]*load
+}
+
+// load is the state of the reads of a document in flight. generation counts the invalidations of the document
+// since the first of them started, so a read that an invalidation overtook doesn't cache the document it read
+type load struct {
+	readers    int
+	generation int
+}
+
+// NewLocalBackend returns the in-process LRU backend sized by configuration
+func NewLocalBackend(conf config.Cache) (*LRU, error) {
+	return NewLRU(conf.MaxEntries, conf.MaxBytes, func(n int) {
+		metrics.Add("evictions", int64(n))
+	}), nil
+}
+
+// NewDocumentDB returns a new instance of the DocumentDB struct
+func NewDocumentDB(conf config.Cache, store Store, backend Backend) (*DocumentDB, error) {
+	return &DocumentDB{
+		Store:       store,
+		enabled:     conf.Enabled,
+		backend:     backend,
+		ttl:         conf.TTL,
+		loadTimeout: conf.LoadTimeout,
+		loading:     make(map[string]*load),
+	}, nil
+}
+
+// GetDocumentByID reads the document of id into result, from the cache unless opts asks for a fresh read.
+// Only whole documents are cached, reads of selected fields go to the store
+func (d *DocumentDB) GetDocumentByID(ctx context.Context, id string, opts models.ReadOptions, result interface{}) error {
+	tenant, ok := tenancy.FromContext(ctx)
+	if !d.enabled || !ok {
+		return d.Store.GetDocumentByID(ctx, id, opts, result)
+	}
+
+	if len(opts.Fields) > 0 || len(opts.Exclude) > 0 {
+		metrics.Add("projected", 1)
+		return d.Store.GetDocumentByID(ctx, id, opts, result)
+	}
+
+	key := documentKey(tenant, id)
+	if opts.Fresh {
+		metrics.Add("bypassed", 1)
+	} else if raw, ok := d.lookup(ctx, key); ok {
+		metrics.Add("hits", 1)
+		return decode(raw, result)
+	} else {
+		metrics.Add("misses", 1)
+	}
+
+	// The read is shared by the requests waiting for the document, so it isn't canceled with the request that started it
+	v, err, shared := d.loads.Do(key, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(detached{ctx}, d.loadTimeout)
+		defer cancel()
+
+		generation := d.startLoad(key)
+
+		var raw bson.Raw
+		err := d.Store.GetDocumentByID(loadCtx, id, opts, &raw)
+		d.finishLoad(loadCtx, key, generation, raw, err == nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return raw, nil
+	})
+	if shared {
+		metrics.Add("coalesced", 1)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return decode(v.(bson.Raw), result)
+}
+
+// SaveDocumentWithID saves doc under id and invalidates the cached document of id
+func (d *DocumentDB) SaveDocumentWithID(ctx context.Context, id string, doc models.Document) error {
+	defer d.invalidate(ctx, id)
+
+	return d.Store.SaveDocumentWithID(ctx, id, doc)
+}
+
+// UpdateDocument replaces the document of id by doc and invalidates the cached document of id
+func (d *DocumentDB) UpdateDocument(ctx context.Context, id string, doc models.Document) error {
+	defer d.invalidate(ctx, id)
+
+	return d.Store.UpdateDocument(ctx, id, doc)
+}
+
+// UpsertDocumentByName creates or replaces the document of the name of doc and invalidates the cached document
+func (d *DocumentDB) UpsertDocumentByName(ctx context.Context, doc models.Document) (string, bool, error) {
+	id, created, err := d.Store.UpsertDocumentByName(ctx, doc)
+	if id != "" {
+		d.invalidate(ctx, id)
+	}
+
+	return id, created, err
+}
+
+// startLoad registers a read of the document of key and returns the generation of the document
+func (d *DocumentDB) startLoad(key string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	l, ok := d.loading[key]
+	if !ok {
+		l = &load{}
+		d.loading[key] = l
+	}
+	l.readers++
+
+	return l.generation
+}
+
+// finishLoad caches raw, the document of key read at generation, unless the read failed or the document was
+// invalidated since
+func (d *DocumentDB) finishLoad(ctx context.Context, key string, generation int, raw bson.Raw, succeeded bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	l := d.loading[key]
+	l.readers--
+	if l.readers == 0 {
+		delete(d.loading, key)
+	}
+
+	if !succeeded {
+		return
+	}
+
+	if l.generation != generation {
+		metrics.Add("staleLoads", 1)
+		return
+	}
+
+	if err := d.backend.Set(ctx, key, raw, d.ttl); err != nil {
+		metrics.Add("errors", 1)
+	}
+}
+
+func (d *DocumentDB) lookup(ctx context.Context, key string) (bson.Raw, bool) {
+	raw, ok, err := d.backend.Get(ctx, key)
+	if err != nil {
+		metrics.Add("errors", 1)
+		return nil, false
+	}
+
+	return raw, ok
+}
+
+// invalidate removes the cached document of id, and keeps a read in flight from being joined by later reads
+func (d *DocumentDB) invalidate(ctx context.Context, id string) {
+	tenant, ok := tenancy.FromContext(ctx)
+	if !d.enabled || !ok {
+		return
+	}
+
+	key := documentKey(tenant, id)
+	d.loads.Forget(key)
+
+	d.mu.Lock()
+	if l, ok := d.loading[key]; ok {
+		l.generation++
+	}
+	d.mu.Unlock()
+
+	if err := d.backend.Delete(ctx, key); err != nil {
+		metrics.Add("errors", 1)
+		return
+	}
+	metrics.Add("invalidations", 1)
+}
+
+// detached keeps the values of a context, such as its tenant, without its deadline and cancellation
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
+
+// documentKey separates the documents of tenants, whose ids may collide when each has a database of its own
+func documentKey(tenant string, id string) string {
+	return "document:" + tenant + ":" + id
+}
+
+func decode(raw bson.Raw, result interface{}) error {
+	if err := bson.Unmarshal(raw, result); err != nil {
+		return errors.Wrap(err, "Failed to decode cached document").SetType(errors.ErrorTypeInternal)
+	}
+
+	return nil
+}
@@ -0,0 +1,336 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/tenancy"
+	"microservice/mocks"
+	"microservice/models"
+
+	"github.com/golang/mock/gomock"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var testCacheConfig = config.Cache{Enabled: true, TTL: time.Minute, MaxEntries: 100, MaxBytes: 1 << 20, LoadTimeout: time.Second}
+
+func newTestDocumentDB(t *testing.T, store Store) *DocumentDB {
+	d, err := NewDocumentDB(testCacheConfig, store, NewLRU(testCacheConfig.MaxEntries, testCacheConfig.MaxBytes, nil))
+	if err != nil {
+		t.Fatalf("NewDocumentDB() error = %v", err)
+	}
+
+	return d
+}
+
+// readDocument returns a store read that decodes a document of name into its result
+func readDocument(t *testing.T, name string) func(context.Context, string, models.ReadOptions, interface{}) error {
+	return func(_ context.Context, _ string, _ models.ReadOptions, result interface{}) error {
+		raw, err := bson.Marshal(models.Document{Name: name})
+		if err != nil {
+			t.Fatalf("Failed to marshal document. Error: %s", err)
+		}
+
+		return bson.Unmarshal(raw, result)
+	}
+}
+
+// getName reads the document of id through d and returns its name
+func getName(t *testing.T, ctx context.Context, d *DocumentDB, id string) string {
+	t.Helper()
+
+	var doc models.Document
+	if err := d.GetDocumentByID(ctx, id, models.ReadOptions{}, &doc); err != nil {
+		t.Fatalf("GetDocumentByID() error = %v", err)
+	}
+
+	return doc.Name
+}
+
+func TestDocumentDB_GetDocumentByID(t *testing.T) {
+	tenantCtx := tenancy.NewContext(context.Background(), "tenant-a")
+
+	tests := []struct {
+		name          string
+		conf          config.Cache
+		ctx           context.Context
+		opts          models.ReadOptions
+		wantStoreHits int
+	}{
+		{
+			name:          "repeated reads expect the store read once",
+			conf:          testCacheConfig,
+			ctx:           tenantCtx,
+			wantStoreHits: 1,
+		},
+		{
+			name:          "repeated bypassed reads expect the store read every time",
+			conf:          testCacheConfig,
+			ctx:           tenantCtx,
+			opts:          models.ReadOptions{Fresh: true},
+			wantStoreHits: 3,
+		},
+		{
+			name:          "repeated reads of selected fields expect the store read every time",
+			conf:          testCacheConfig,
+			ctx:           tenantCtx,
+			opts:          models.ReadOptions{Fields: []string{"name"}},
+			wantStoreHits: 3,
+		},
+		{
+			name:          "repeated reads without a tenant expect the store read every time",
+			conf:          testCacheConfig,
+			ctx:           context.Background(),
+			wantStoreHits: 3,
+		},
+		{
+			name:          "repeated reads with the cache disabled expect the store read every time",
+			conf:          config.Cache{Enabled: false},
+			ctx:           tenantCtx,
+			wantStoreHits: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mocks.NewMockCacheStore(ctrl)
+			store.EXPECT().GetDocumentByID(gomock.Any(), "id", tt.opts, gomock.Any()).
+				DoAndReturn(readDocument(t, "doc")).Times(tt.wantStoreHits)
+
+			d, err := NewDocumentDB(tt.conf, store, NewLRU(100, 1<<20, nil))
+			if err != nil {
+				t.Fatalf("NewDocumentDB() error = %v", err)
+			}
+
+			for i := 0; i < 3; i++ {
+				var doc models.Document
+				if err := d.GetDocumentByID(tt.ctx, "id", tt.opts, &doc); err != nil {
+					t.Fatalf("GetDocumentByID() error = %v", err)
+				}
+
+				if doc.Name != "doc" {
+					t.Errorf("GetDocumentByID() got = %v, want doc", doc.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestDocumentDB_GetDocumentByID_tenants(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockCacheStore(ctrl)
+	store.EXPECT().GetDocumentByID(gomock.Any(), "id", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, id string, opts models.ReadOptions, result interface{}) error {
+			tenant, _ := tenancy.FromContext(ctx)
+			return readDocument(t, tenant)(ctx, id, opts, result)
+		}).Times(2)
+
+	d := newTestDocumentDB(t, store)
+	for _, tenant := range []string{"tenant-a", "tenant-b", "tenant-a", "tenant-b"} {
+		if got := getName(t, tenancy.NewContext(context.Background(), tenant), d, "id"); got != tenant {
+			t.Errorf("GetDocumentByID() of tenant (%s) got = %v, want the document of the tenant", tenant, got)
+		}
+	}
+}
+
+func TestDocumentDB_GetDocumentByID_coalesced(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := tenancy.NewContext(context.Background(), "tenant-a")
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	store := mocks.NewMockCacheStore(ctrl)
+	store.EXPECT().GetDocumentByID(gomock.Any(), "id", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, id string, opts models.ReadOptions, result interface{}) error {
+			close(started)
+			<-release
+			return readDocument(t, "doc")(ctx, id, opts, result)
+		}).Times(1)
+
+	d := newTestDocumentDB(t, store)
+
+	var wg sync.WaitGroup
+	names := make(chan string, 10)
+	for i := 0; i < cap(names); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var doc models.Document
+			if err := d.GetDocumentByID(ctx, "id", models.ReadOptions{}, &doc); err == nil {
+				names <- doc.Name
+			}
+		}()
+
+		if i == 0 {
+			<-started
+		}
+	}
+
+	// Readers that didn't join the read in flight before it finished hit the cache, either way the store is read once
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(names)
+
+	count := 0
+	for name := range names {
+		count++
+		if name != "doc" {
+			t.Errorf("GetDocumentByID() got = %v, want doc", name)
+		}
+	}
+
+	if count != cap(names) {
+		t.Errorf("GetDocumentByID() succeeded (%d) times, want %d", count, cap(names))
+	}
+}
+
+func TestDocumentDB_invalidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(d *DocumentDB, store *mocks.MockCacheStore, ctx context.Context)
+	}{
+		{
+			name: "save with id expect cached document invalidated",
+			write: func(d *DocumentDB, store *mocks.MockCacheStore, ctx context.Context) {
+				store.EXPECT().SaveDocumentWithID(gomock.Any(), "id", gomock.Any()).Return(nil)
+				_ = d.SaveDocumentWithID(ctx, "id", models.Document{})
+			},
+		},
+		{
+			name: "update expect cached document invalidated",
+			write: func(d *DocumentDB, store *mocks.MockCacheStore, ctx context.Context) {
+				store.EXPECT().UpdateDocument(gomock.Any(), "id", gomock.Any()).Return(nil)
+				_ = d.UpdateDocument(ctx, "id", models.Document{})
+			},
+		},
+		{
+			name: "failed update expect cached document invalidated",
+			write: func(d *DocumentDB, store *mocks.MockCacheStore, ctx context.Context) {
+				store.EXPECT().UpdateDocument(gomock.Any(), "id", gomock.Any()).Return(context.DeadlineExceeded)
+				_ = d.UpdateDocument(ctx, "id", models.Document{})
+			},
+		},
+		{
+			name: "upsert by name expect cached document of the upserted id invalidated",
+			write: func(d *DocumentDB, store *mocks.MockCacheStore, ctx context.Context) {
+				store.EXPECT().UpsertDocumentByName(gomock.Any(), gomock.Any()).Return("id", false, nil)
+				_, _, _ = d.UpsertDocumentByName(ctx, models.Document{Name: "doc"})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ctx := tenancy.NewContext(context.Background(), "tenant-a")
+			store := mocks.NewMockCacheStore(ctrl)
+			d := newTestDocumentDB(t, store)
+
+			gomock.InOrder(
+				store.EXPECT().GetDocumentByID(gomock.Any(), "id", gomock.Any(), gomock.Any()).DoAndReturn(readDocument(t, "before")),
+				store.EXPECT().GetDocumentByID(gomock.Any(), "id", gomock.Any(), gomock.Any()).DoAndReturn(readDocument(t, "after")),
+			)
+
+			getName(t, ctx, d, "id")
+			tt.write(d, store, ctx)
+
+			if got := getName(t, ctx, d, "id"); got != "after" {
+				t.Errorf("GetDocumentByID() after write got = %v, want after", got)
+			}
+		})
+	}
+}
+
+func TestDocumentDB_invalidationDuringLoad(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := tenancy.NewContext(context.Background(), "tenant-a")
+	read := make(chan struct{})
+	release := make(chan struct{})
+
+	store := mocks.NewMockCacheStore(ctrl)
+	d := newTestDocumentDB(t, store)
+
+	gomock.InOrder(
+		// The first read gets the document before the update, and returns it after the update invalidated it
+		store.EXPECT().GetDocumentByID(gomock.Any(), "id", gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, id string, opts models.ReadOptions, result interface{}) error {
+				err := readDocument(t, "before")(ctx, id, opts, result)
+				close(read)
+				<-release
+				return err
+			}),
+		store.EXPECT().GetDocumentByID(gomock.Any(), "id", gomock.Any(), gomock.Any()).DoAndReturn(readDocument(t, "after")),
+	)
+	store.EXPECT().UpdateDocument(gomock.Any(), "id", gomock.Any()).Return(nil)
+
+	done := make(chan string)
+	go func() {
+		var doc models.Document
+		_ = d.GetDocumentByID(ctx, "id", models.ReadOptions{}, &doc)
+		done <- doc.Name
+	}()
+
+	<-read
+	if err := d.UpdateDocument(ctx, "id", models.Document{}); err != nil {
+		t.Fatalf("UpdateDocument() error = %v", err)
+	}
+	close(release)
+	<-done
+
+	if got := getName(t, ctx, d, "id"); got != "after" {
+		t.Errorf("GetDocumentByID() after update got = %v, want after, the document read before the update was cached", got)
+	}
+
+	if len(d.loading) != 0 {
+		t.Errorf("DocumentDB loading = %v, want no reads in flight", d.loading)
+	}
+}
+
+func TestDocumentDB_loadOutlivesCanceledReader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(tenancy.NewContext(context.Background(), "tenant-a"))
+	store := mocks.NewMockCacheStore(ctrl)
+	store.EXPECT().GetDocumentByID(gomock.Any(), "id", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(loadCtx context.Context, id string, opts models.ReadOptions, result interface{}) error {
+			cancel()
+
+			if err := loadCtx.Err(); err != nil {
+				return err
+			}
+
+			if _, ok := loadCtx.Deadline(); !ok {
+				t.Error("GetDocumentByID() read without a deadline, want the load timeout")
+			}
+
+			if tenant, _ := tenancy.FromContext(loadCtx); tenant != "tenant-a" {
+				t.Errorf("GetDocumentByID() read tenant = %v, want tenant-a", tenant)
+			}
+
+			return readDocument(t, "doc")(loadCtx, id, opts, result)
+		}).Times(1)
+
+	d := newTestDocumentDB(t, store)
+	if got := getName(t, ctx, d, "id"); got != "doc" {
+		t.Errorf("GetDocumentByID() got = %v, want doc", got)
+	}
+
+	if got := getName(t, tenancy.NewContext(context.Background(), "tenant-a"), d, "id"); got != "doc" {
+		t.Errorf("GetDocumentByID() from cache got = %v, want doc", got)
+	}
+}
@@ -0,0 +1,309 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"microservice/internal/pkg/errors"
+)
+
+// Values reads a configuration
+type Values interface {
+	Get(key string) interface{}
+	GetString(key string) (string, error)
+	GetInt(key string) (int, error)
+	GetBool(key string) (bool, error)
+	GetDuration(key string) (time.Duration, error)
+	IsSet(key string) bool
+	Unmarshal(target interface{}) error
+}
+
+// ChangeHandler prepares a change of the keys it subscribed to from the candidate configuration of a reload.
+// It returns an error to reject the candidate, otherwise a function applying the change, which is called
+// only once every handler accepted the candidate
+type ChangeHandler func(candidate Values) (apply func(), err error)
+
+// Source is the configuration of the application, which can be reloaded
+type Source interface {
+	Values
+	Subscribe(keys []string, handler ChangeHandler)
+}
+
+// typedSections are the configuration sections decoded into Config
+var typedSections = []string{"server", "log", "startup", "mongo", "cache"}
+
+// Config is the typed configuration of the application
+type Config struct {
+	Server  Server
+	Log     Log
+	Startup Startup
+	Mongo   Mongo
+	Cache   Cache
+}
+
+// Server configures the rest server
+type Server struct {
+	Port    int
+	Timeout time.Duration
+	// CacheControl is the Cache-Control header of the responses of documents, none is sent when empty
+	CacheControl string
+	Routes       Routes
+}
+
+// DefaultRoute names the configuration of the routes without a configuration of their own
+const DefaultRoute = "default"
+
+// Routes configures the routes of the rest server by route name
+type Routes map[string]Route
+
+// Get returns the configuration of a route, or of the default route if it has none
+func (r Routes) Get(name string) Route {
+	if route, ok := r[strings.ToLower(name)]; ok {
+		return route
+	}
+
+	return r[DefaultRoute]
+}
+
+// Route configures the handling of the requests of a route
+type Route struct {
+	// MaxBodySize is the size in bytes of the largest request body accepted, before it is decompressed
+	MaxBodySize int
+	Compression Compression
+}
+
+// Compression configures the compression of responses and the decompression of request bodies
+type Compression struct {
+	Enabled bool
+	// MinSize is the size in bytes from which responses are compressed
+	MinSize int
+	// Encodings are the response encodings offered, in order of preference
+	Encodings []string
+	// DecompressRequests accepts gzip request bodies, up to MaxDecompressedSize bytes once decompressed
+	DecompressRequests  bool
+	MaxDecompressedSize int
+}
+
+// Log configures logging
+type Log struct {
+	Level string
+}
+
+// Startup configures the work done before the application starts serving
+type Startup struct {
+	ReconcileIndexes bool
+}
+
+// Cache configures the read-through cache of documents
+type Cache struct {
+	Enabled bool
+	TTL     time.Duration
+	// MaxEntries and MaxBytes bound the in-process cache, the least recently used documents are evicted first
+	MaxEntries int
+	MaxBytes   int
+	// LoadTimeout bounds a read of a missing document, which is shared by the requests waiting for it
+	LoadTimeout time.Duration
+}
+
+// Mongo configures the mongodb client
+type Mongo struct {
+	// URI is a mongodb connection string, Hosts is ignored when it is given
+	URI string
+	// Hosts is a comma separated list of host:port addresses
+	Hosts          string
+	Username       string
+	Password       string
+	AuthSource     string
+	AuthMechanism  string
+	ReplicaSet     string
+	ReadPreference string
+	ReadConcern    string
+	WriteConcern   MongoWriteConcern
+	TLS            MongoTLS
+	Pool           MongoPool
+	Timeouts       MongoTimeouts
+	Startup        MongoStartup
+	Resilience     MongoResilience
+
+	Database    string
+	Collection  string
+	Tenancy     string
+	IDStrategy  string
+	UniqueNames bool
+	Indexes     MongoIndexes
+	Migrations  MongoMigrations
+	Idempotency MongoIdempotency
+}
+
+// MongoWriteConcern configures the acknowledgement of writes. W is a number of nodes or "majority"
+type MongoWriteConcern struct {
+	W       string
+	Journal bool
+	Timeout time.Duration
+}
+
+// MongoTLS configures TLS connections. CertFile and KeyFile hold the client certificate
+type MongoTLS struct {
+	Enabled            bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// MongoPool configures the connection pool, a MaxSize of 0 leaves the pool unbounded
+type MongoPool struct {
+	MinSize     int
+	MaxSize     int
+	MaxIdleTime time.Duration
+}
+
+// MongoTimeouts configures the timeouts of the client, a timeout of 0 keeps the driver default
+type MongoTimeouts struct {
+	ServerSelection time.Duration
+	Connect         time.Duration
+	Socket          time.Duration
+}
+
+// MongoStartup configures the initial connection to mongodb
+type MongoStartup struct {
+	// WaitForDatabase fails the startup if mongodb can't be reached before the initialization times out.
+	// Otherwise the service starts not ready and keeps connecting in the background
+	WaitForDatabase bool
+	AttemptTimeout  time.Duration
+	Backoff         Backoff
+}
+
+// MongoResilience configures how document operations behave while mongodb degrades
+type MongoResilience struct {
+	Breaker  Breaker
+	Retry    Retry
+	Timeouts MongoOperationTimeouts
+}
+
+// Breaker configures a circuit breaker, see resilience.BreakerPolicy
+type Breaker struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before it lets trial calls through
+	OpenTimeout time.Duration
+	// HalfOpenMaxCalls is the number of concurrent trial calls while half-open
+	HalfOpenMaxCalls int
+}
+
+// Retry configures the retries of idempotent operations on transient errors
+type Retry struct {
+	// Attempts is the total number of attempts, including the first
+	Attempts int
+	Backoff  Backoff
+}
+
+// MongoOperationTimeouts bound each attempt of a document operation
+type MongoOperationTimeouts struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+// Backoff configures the delays between retries, see backoff.Policy
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// MongoIndexes configures the indexes managed by the service
+type MongoIndexes struct {
+	DropUndeclared bool
+	Declared       []MongoIndex
+}
+
+// MongoIndex is an index declared in configuration
+type MongoIndex struct {
+	Name   string
+	Keys   []MongoIndexKey
+	Unique bool
+	TTL    time.Duration
+}
+
+// MongoIndexKey is a field of a declared index. Order is 1, -1 or "text"
+type MongoIndexKey struct {
+	Field string
+	Order interface{}
+}
+
+// MongoMigrations configures data migrations
+type MongoMigrations struct {
+	LockTTL time.Duration
+}
+
+// MongoIdempotency configures the store of idempotency keys
+type MongoIdempotency struct {
+	Collection string
+	TTL        time.Duration
+	// ReservationTimeout is how long a key stays reserved for a request in progress, after which the request is
+	// considered abandoned and the key can be taken over
+	ReservationTimeout time.Duration
+}
+
+// NewConfig returns the configuration of src. Every problem in the configuration is reported in the returned error.
+// Reloads of src changing the typed sections are rejected unless they are valid
+func NewConfig(src Source) (*Config, error) {
+	c, err := decode(src)
+	if err != nil {
+		return nil, err
+	}
+
+	src.Subscribe(typedSections, func(candidate Values) (func(), error) {
+		if _, err := decode(candidate); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	})
+
+	return c, nil
+}
+
+func decode(values Values) (*Config, error) {
+	c := &Config{}
+	if err := values.Unmarshal(c); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode configuration")
+	}
+
+	if problems := c.Validate(); len(problems) > 0 {
+		return nil, errors.Errorf("Invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return c, nil
+}
+
+// LogFields returns the connection options of m for logging, with the password and the password in the uri redacted
+func (m Mongo) LogFields() map[string]interface{} {
+	return map[string]interface{}{
+		"uri":                      RedactURI(m.URI),
+		"hosts":                    m.Hosts,
+		"username":                 m.Username,
+		"password":                 redactSecret(m.Password),
+		"authSource":               m.AuthSource,
+		"authMechanism":            m.AuthMechanism,
+		"replicaSet":               m.ReplicaSet,
+		"readPreference":           m.ReadPreference,
+		"readConcern":              m.ReadConcern,
+		"writeConcern.w":           m.WriteConcern.W,
+		"writeConcern.journal":     m.WriteConcern.Journal,
+		"writeConcern.timeout":     m.WriteConcern.Timeout.String(),
+		"tls.enabled":              m.TLS.Enabled,
+		"tls.caFile":               m.TLS.CAFile,
+		"tls.certFile":             m.TLS.CertFile,
+		"tls.keyFile":              m.TLS.KeyFile,
+		"tls.insecureSkipVerify":   m.TLS.InsecureSkipVerify,
+		"pool.minSize":             m.Pool.MinSize,
+		"pool.maxSize":             m.Pool.MaxSize,
+		"pool.maxIdleTime":         m.Pool.MaxIdleTime.String(),
+		"timeouts.serverSelection": m.Timeouts.ServerSelection.String(),
+		"timeouts.connect":         m.Timeouts.Connect.String(),
+		"timeouts.socket":          m.Timeouts.Socket.String(),
+		"startup.waitForDatabase":  m.Startup.WaitForDatabase,
+	}
+}
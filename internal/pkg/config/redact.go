@@ -0,0 +1,52 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// redactedValue replaces secrets in logged configuration
+const redactedValue = "[REDACTED]"
+
+// secretSchemes prefix references to secrets, which are resolved on connection and aren't secrets themselves
+var secretSchemes = []string{"file://", "env:", "vault:"}
+
+func isSecretReference(value string) bool {
+	for _, scheme := range secretSchemes {
+		if strings.HasPrefix(value, scheme) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactSecret keeps secret references and replaces literal secrets
+func redactSecret(value string) string {
+	if value == "" || isSecretReference(value) {
+		return value
+	}
+
+	return redactedValue
+}
+
+// RedactURI replaces the password of a connection string, so it can be logged or printed
+func RedactURI(uri string) string {
+	if uri == "" || isSecretReference(uri) {
+		return uri
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return redactedValue
+	}
+
+	if _, ok := u.User.Password(); !ok {
+		return u.String()
+	}
+
+	user := url.User(u.User.Username()).String()
+	u.User = nil
+
+	return strings.Replace(u.String(), "://", "://"+user+":"+redactedValue+"@", 1)
+}
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	minPort = 1
+	maxPort = 65535
+
+	mongoWriteConcernMajority = "majority"
+)
+
+var (
+	compressionEncodings = []string{"gzip", "deflate", "zstd"}
+	mongoTenancies       = []string{"field", "database"}
+	mongoIDStrategies    = []string{"objectID", "uuidv4", "uuidv7", "ulid"}
+	mongoURISchemes      = []string{"mongodb", "mongodb+srv"}
+	mongoAuthMechanisms  = []string{"SCRAM-SHA-1", "SCRAM-SHA-256", "MONGODB-X509", "MONGODB-AWS", "PLAIN", "GSSAPI"}
+	mongoReadPreferences = []string{"primary", "primaryPreferred", "secondary", "secondaryPreferred", "nearest"}
+	mongoReadConcerns    = []string{"local", "available", "majority", "linearizable", "snapshot"}
+)
+
+// validator collects the problems found in a configuration
+type validator struct {
+	problems []string
+}
+
+// Validate returns every problem found in the configuration, it returns nothing if the configuration is valid
+func (c *Config) Validate() []string {
+	v := &validator{}
+
+	v.port("server.port", c.Server.Port)
+	v.duration("server.timeout", c.Server.Timeout, time.Second, 10*time.Minute)
+	c.Server.Routes.validate(v)
+
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		v.addf("log.level", "unknown level (%s)", c.Log.Level)
+	}
+
+	c.Mongo.validateConnection(v)
+	c.Mongo.validateResilience(v)
+	if c.Startup.ReconcileIndexes && !c.Mongo.Startup.WaitForDatabase {
+		v.addf("startup.reconcileIndexes", "needs (mongo.startup.waitForDatabase)")
+	}
+	v.required("mongo.database", c.Mongo.Database)
+	v.required("mongo.collection", c.Mongo.Collection)
+	v.oneOf("mongo.tenancy", c.Mongo.Tenancy, mongoTenancies)
+	v.oneOf("mongo.idStrategy", c.Mongo.IDStrategy, mongoIDStrategies)
+
+	v.duration("mongo.migrations.lockTTL", c.Mongo.Migrations.LockTTL, time.Second, 24*time.Hour)
+	v.required("mongo.idempotency.collection", c.Mongo.Idempotency.Collection)
+	v.duration("mongo.idempotency.ttl", c.Mongo.Idempotency.TTL, time.Second, 30*24*time.Hour)
+	v.duration("mongo.idempotency.reservationTimeout", c.Mongo.Idempotency.ReservationTimeout, time.Second, time.Hour)
+
+	if c.Cache.Enabled {
+		v.duration("cache.ttl", c.Cache.TTL, time.Second, 24*time.Hour)
+		v.atLeast("cache.maxEntries", c.Cache.MaxEntries, 1)
+		v.atLeast("cache.maxBytes", c.Cache.MaxBytes, 1)
+		v.duration("cache.loadTimeout", c.Cache.LoadTimeout, time.Millisecond, time.Minute)
+	}
+
+	return v.problems
+}
+
+func (r Routes) validate(v *validator) {
+	if _, ok := r[DefaultRoute]; !ok {
+		v.addf("server.routes", "misses the (%s) route", DefaultRoute)
+	}
+
+	for name, route := range r {
+		v.atLeast("server.routes."+name+".maxBodySize", route.MaxBodySize, 1)
+
+		key := "server.routes." + name + ".compression"
+		c := route.Compression
+
+		if c.Enabled {
+			v.atLeast(key+".minSize", c.MinSize, 0)
+			if len(c.Encodings) == 0 {
+				v.addf(key+".encodings", "is required")
+			}
+			for _, encoding := range c.Encodings {
+				v.oneOf(key+".encodings", encoding, compressionEncodings)
+			}
+		}
+
+		if c.DecompressRequests {
+			v.atLeast(key+".maxDecompressedSize", c.MaxDecompressedSize, 1)
+		}
+	}
+}
+
+// validateConnection checks the options of the connection to mongodb
+func (m Mongo) validateConnection(v *validator) {
+	if m.URI != "" {
+		v.uri("mongo.uri", m.URI, mongoURISchemes)
+	} else {
+		v.hosts("mongo.hosts", m.Hosts)
+	}
+
+	if m.Password != "" && m.AuthMechanism != "MONGODB-X509" {
+		v.required("mongo.username", m.Username)
+	}
+
+	v.optionalOneOf("mongo.authMechanism", m.AuthMechanism, mongoAuthMechanisms)
+	v.optionalOneOf("mongo.readPreference", m.ReadPreference, mongoReadPreferences)
+	v.optionalOneOf("mongo.readConcern", m.ReadConcern, mongoReadConcerns)
+
+	if w := m.WriteConcern.W; w != "" && w != mongoWriteConcernMajority {
+		if n, err := strconv.Atoi(w); err != nil || n < 0 {
+			v.addf("mongo.writeConcern.w", "is (%s), expected a number of nodes or (%s)", w, mongoWriteConcernMajority)
+		}
+	}
+	v.optionalDuration("mongo.writeConcern.timeout", m.WriteConcern.Timeout, time.Millisecond, time.Hour)
+
+	if !m.TLS.Enabled && (m.TLS.CAFile != "" || m.TLS.CertFile != "" || m.TLS.KeyFile != "") {
+		v.addf("mongo.tls", "has certificate files but isn't enabled")
+	}
+	if (m.TLS.CertFile == "") != (m.TLS.KeyFile == "") {
+		v.addf("mongo.tls", "needs both (certFile) and (keyFile) for a client certificate")
+	}
+	v.file("mongo.tls.caFile", m.TLS.CAFile)
+	v.file("mongo.tls.certFile", m.TLS.CertFile)
+	v.file("mongo.tls.keyFile", m.TLS.KeyFile)
+	if m.AuthMechanism == "MONGODB-X509" && m.TLS.CertFile == "" {
+		v.addf("mongo.authMechanism", "(MONGODB-X509) needs a client certificate in (mongo.tls.certFile)")
+	}
+
+	if m.Pool.MinSize < 0 || m.Pool.MaxSize < 0 {
+		v.addf("mongo.pool", "sizes can't be negative")
+	}
+	if m.Pool.MaxSize > 0 && m.Pool.MinSize > m.Pool.MaxSize {
+		v.addf("mongo.pool", "minSize (%d) is larger than maxSize (%d)", m.Pool.MinSize, m.Pool.MaxSize)
+	}
+	v.optionalDuration("mongo.pool.maxIdleTime", m.Pool.MaxIdleTime, time.Second, 24*time.Hour)
+
+	v.optionalDuration("mongo.timeouts.serverSelection", m.Timeouts.ServerSelection, 100*time.Millisecond, 10*time.Minute)
+	v.optionalDuration("mongo.timeouts.connect", m.Timeouts.Connect, 100*time.Millisecond, 10*time.Minute)
+	v.optionalDuration("mongo.timeouts.socket", m.Timeouts.Socket, 100*time.Millisecond, time.Hour)
+
+	v.duration("mongo.startup.attemptTimeout", m.Startup.AttemptTimeout, 100*time.Millisecond, 5*time.Minute)
+	v.backoff("mongo.startup.backoff", m.Startup.Backoff)
+}
+
+func (m Mongo) validateResilience(v *validator) {
+	v.atLeast("mongo.resilience.breaker.failureThreshold", m.Resilience.Breaker.FailureThreshold, 1)
+	v.duration("mongo.resilience.breaker.openTimeout", m.Resilience.Breaker.OpenTimeout, 100*time.Millisecond, 10*time.Minute)
+	v.atLeast("mongo.resilience.breaker.halfOpenMaxCalls", m.Resilience.Breaker.HalfOpenMaxCalls, 1)
+	v.atLeast("mongo.resilience.retry.attempts", m.Resilience.Retry.Attempts, 1)
+	v.backoff("mongo.resilience.retry.backoff", m.Resilience.Retry.Backoff)
+	v.duration("mongo.resilience.timeouts.read", m.Resilience.Timeouts.Read, 10*time.Millisecond, 10*time.Minute)
+	v.duration("mongo.resilience.timeouts.write", m.Resilience.Timeouts.Write, 10*time.Millisecond, 10*time.Minute)
+}
+
+func (v *validator) addf(key string, format string, a ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf("(%s) %s", key, fmt.Sprintf(format, a...)))
+}
+
+func (v *validator) required(key string, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf(key, "is required")
+	}
+}
+
+func (v *validator) oneOf(key string, value string, allowed []string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+
+	v.addf(key, "is (%s), expected one of (%s)", value, strings.Join(allowed, ", "))
+}
+
+// optionalOneOf checks a value whose empty value keeps a default
+func (v *validator) optionalOneOf(key string, value string, allowed []string) {
+	if value != "" {
+		v.oneOf(key, value, allowed)
+	}
+}
+
+func (v *validator) port(key string, port int) {
+	if port < minPort || port > maxPort {
+		v.addf(key, "is (%d), expected a port between %d and %d", port, minPort, maxPort)
+	}
+}
+
+func (v *validator) duration(key string, d time.Duration, min time.Duration, max time.Duration) {
+	if d < min || d > max {
+		v.addf(key, "is (%s), expected a duration between %s and %s", d, min, max)
+	}
+}
+
+// optionalDuration checks a duration whose zero value keeps a default
+func (v *validator) optionalDuration(key string, d time.Duration, min time.Duration, max time.Duration) {
+	if d != 0 {
+		v.duration(key, d, min, max)
+	}
+}
+
+// file checks that an optional file exists
+func (v *validator) file(key string, file string) {
+	if file == "" {
+		return
+	}
+
+	if info, err := os.Stat(file); err != nil || info.IsDir() {
+		v.addf(key, "is (%s), expected an existing file", file)
+	}
+}
+
+// uri checks a connection string with one of schemes. Secret references are checked once resolved
+func (v *validator) uri(key string, uri string, schemes []string) {
+	if isSecretReference(uri) {
+		return
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		v.addf(key, "is not a valid uri")
+		return
+	}
+
+	v.oneOf(key+" scheme", u.Scheme, schemes)
+	if u.Host == "" {
+		v.addf(key, "has no host")
+	}
+}
+
+func (v *validator) atLeast(key string, value int, min int) {
+	if value < min {
+		v.addf(key, "is (%d), expected at least %d", value, min)
+	}
+}
+
+func (v *validator) backoff(key string, b Backoff) {
+	v.duration(key+".initial", b.Initial, time.Millisecond, time.Minute)
+	v.duration(key+".max", b.Max, b.Initial, 10*time.Minute)
+
+	if b.Multiplier < 1 {
+		v.addf(key+".multiplier", "is (%g), expected at least 1", b.Multiplier)
+	}
+
+	if b.Jitter < 0 || b.Jitter > 1 {
+		v.addf(key+".jitter", "is (%g), expected a fraction between 0 and 1", b.Jitter)
+	}
+}
+
+// hosts checks a comma separated list of host:port addresses
+func (v *validator) hosts(key string, hosts string) {
+	if strings.TrimSpace(hosts) == "" {
+		v.addf(key, "is required")
+		return
+	}
+
+	for _, h := range strings.Split(hosts, ",") {
+		host, port, err := net.SplitHostPort(strings.TrimSpace(h))
+		if err != nil || host == "" {
+			v.addf(key, "has invalid address (%s), expected host:port", h)
+			continue
+		}
+
+		if p, err := strconv.Atoi(port); err != nil || p < minPort || p > maxPort {
+			v.addf(key, "has invalid port in address (%s)", h)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns a configuration without problems
+func validConfig() Config {
+	backoff := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2, Jitter: 0.2}
+
+	return Config{
+		Server: Server{
+			Port:    8080,
+			Timeout: 15 * time.Second,
+			Routes: Routes{
+				DefaultRoute: {
+					MaxBodySize: 1 << 20,
+					Compression: Compression{Enabled: true, MinSize: 1024, Encodings: []string{"gzip"}},
+				},
+			},
+		},
+		Log: Log{Level: "info"},
+		Mongo: Mongo{
+			Hosts:    "localhost:27017",
+			Username: "admin",
+			Password: "vault:database/mongo#password",
+			Startup: MongoStartup{
+				AttemptTimeout: 5 * time.Second,
+				Backoff:        backoff,
+			},
+			Resilience: MongoResilience{
+				Breaker:  Breaker{FailureThreshold: 5, OpenTimeout: 30 * time.Second, HalfOpenMaxCalls: 1},
+				Retry:    Retry{Attempts: 3, Backoff: backoff},
+				Timeouts: MongoOperationTimeouts{Read: 5 * time.Second, Write: 10 * time.Second},
+			},
+			Database:    "myDatabase",
+			Collection:  "myCollection",
+			Tenancy:     "field",
+			IDStrategy:  "objectID",
+			Migrations:  MongoMigrations{LockTTL: 10 * time.Minute},
+			Idempotency: MongoIdempotency{Collection: "idempotencyKeys", TTL: 24 * time.Hour, ReservationTimeout: time.Minute},
+		},
+		Cache: Cache{Enabled: true, TTL: time.Minute, MaxEntries: 100, MaxBytes: 1 << 20, LoadTimeout: time.Second},
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name         string
+		change       func(c *Config)
+		wantProblems []string
+	}{
+		{
+			name:         "valid configuration expect no problems",
+			change:       func(c *Config) {},
+			wantProblems: nil,
+		},
+		{
+			name: "several invalid sections expect every problem reported",
+			change: func(c *Config) {
+				c.Server.Port = 70000
+				c.Server.Timeout = 0
+				c.Log.Level = "loud"
+				c.Mongo.Database = ""
+				c.Mongo.Tenancy = "schema"
+				c.Cache.MaxEntries = 0
+			},
+			wantProblems: []string{"cache.maxEntries", "log.level", "mongo.database", "mongo.tenancy", "server.port", "server.timeout"},
+		},
+		{
+			name: "missing default route and invalid encoding expect both reported",
+			change: func(c *Config) {
+				c.Server.Routes = Routes{"adddocument": {MaxBodySize: 1, Compression: Compression{Enabled: true, Encodings: []string{"br"}}}}
+			},
+			wantProblems: []string{"server.routes", "server.routes.adddocument.compression.encodings"},
+		},
+		{
+			name: "uri with unknown scheme and no host expect both reported",
+			change: func(c *Config) {
+				c.Mongo.URI = "http://"
+			},
+			wantProblems: []string{"mongo.uri", "mongo.uri scheme"},
+		},
+		{
+			name: "invalid hosts and pool sizes expect every problem reported",
+			change: func(c *Config) {
+				c.Mongo.Hosts = "localhost"
+				c.Mongo.Pool = MongoPool{MinSize: 10, MaxSize: 5}
+			},
+			wantProblems: []string{"mongo.hosts", "mongo.pool"},
+		},
+		{
+			name: "client certificate without key and tls disabled expect every problem reported",
+			change: func(c *Config) {
+				c.Mongo.TLS = MongoTLS{CertFile: "/does/not/exist.pem"}
+			},
+			wantProblems: []string{"mongo.tls", "mongo.tls", "mongo.tls.certFile"},
+		},
+		{
+			name: "invalid backoff and resilience expect every problem reported",
+			change: func(c *Config) {
+				c.Mongo.Resilience.Retry.Backoff.Multiplier = 0.5
+				c.Mongo.Resilience.Retry.Backoff.Jitter = 2
+				c.Mongo.Resilience.Breaker.FailureThreshold = 0
+			},
+			wantProblems: []string{"mongo.resilience.breaker.failureThreshold", "mongo.resilience.retry.backoff.jitter", "mongo.resilience.retry.backoff.multiplier"},
+		},
+		{
+			name: "password without username expect username required",
+			change: func(c *Config) {
+				c.Mongo.Username = ""
+			},
+			wantProblems: []string{"mongo.username"},
+		},
+		{
+			name: "password without username authenticating by certificate expect no problems",
+			change: func(c *Config) {
+				c.Mongo.Username = ""
+				c.Mongo.AuthMechanism = "MONGODB-X509"
+				c.Mongo.TLS = MongoTLS{Enabled: true, CertFile: "validate_test.go", KeyFile: "validate_test.go"}
+			},
+			wantProblems: nil,
+		},
+		{
+			name: "index reconciliation without waiting for the database expect problem reported",
+			change: func(c *Config) {
+				c.Startup.ReconcileIndexes = true
+			},
+			wantProblems: []string{"startup.reconcileIndexes"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.change(&c)
+
+			problems := c.Validate()
+
+			var got []string
+			for _, p := range problems {
+				got = append(got, p[1:strings.Index(p, ")")])
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tt.wantProblems) {
+				t.Errorf("Validate() problems of keys = %v, want %v. Problems: %v", got, tt.wantProblems, problems)
+			}
+		})
+	}
+}
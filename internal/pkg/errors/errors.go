@@ -20,6 +20,33 @@ const (
 
 	// ErrorTypeInternal for internal error
 	ErrorTypeInternal
+
+	// ErrorTypeConflict for changes that conflict with the current state of a resource
+	ErrorTypeConflict
+
+	// ErrorTypeUnprocessable for well-formed input that cannot be applied
+	ErrorTypeUnprocessable
+
+	// ErrorTypeUnauthorized for requests without valid credentials
+	ErrorTypeUnauthorized
+
+	// ErrorTypeForbidden for principals that are not allowed to perform an action
+	ErrorTypeForbidden
+
+	// ErrorTypeQuotaExceeded for requests that would exceed a tenant quota
+	ErrorTypeQuotaExceeded
+
+	// ErrorTypeUnavailable for requests that can't be served while a dependency is unreachable
+	ErrorTypeUnavailable
+
+	// ErrorTypeTooLarge for requests whose content exceeds a size limit
+	ErrorTypeTooLarge
+
+	// ErrorTypeDocumentTooLarge for documents larger than a stored document can be
+	ErrorTypeDocumentTooLarge
+
+	// ErrorTypeDocumentTooDeep for documents nested deeper than a stored document can be
+	ErrorTypeDocumentTooDeep
 )
 
 // Err represents a single error
@@ -48,6 +75,11 @@ func (e *Err) Error() string {
 	return e.msg
 }
 
+// Unwrap returns the wrapped error, so the errors of libraries can be told through the context added to them
+func (e *Err) Unwrap() error {
+	return e.err
+}
+
 // Format the errors according to the verbs
 func (e *Err) Format(s fmt.State, verb rune) {
 	switch verb {
@@ -0,0 +1,121 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// rotationTimeout bounds connecting with rotated credentials, and draining the connections of the replaced client
+	rotationTimeout = 30 * time.Second
+)
+
+// Secrets resolves secret references and reports their rotation
+type Secrets interface {
+	Resolve(ctx context.Context, value string) (string, error)
+	Watch(values []string, current []string, onRotate func(secrets []string) error, onRefresh func(err error)) (stop func())
+}
+
+func resolveCredential(ctx context.Context, conf config.Mongo, secrets Secrets) (options.Credential, error) {
+	username, err := secrets.Resolve(ctx, conf.Username)
+	if err != nil {
+		return options.Credential{}, errors.Wrap(err, "Fail to resolve mongo username")
+	}
+
+	password, err := secrets.Resolve(ctx, conf.Password)
+	if err != nil {
+		return options.Credential{}, errors.Wrap(err, "Fail to resolve mongo password")
+	}
+
+	return options.Credential{
+		AuthSource:    conf.AuthSource,
+		AuthMechanism: conf.AuthMechanism,
+		Username:      username,
+		Password:      password,
+	}, nil
+}
+
+// connect returns a client of the server in o authenticated with credential, once the server answered a ping
+func connect(ctx context.Context, o *options.ClientOptions, credential options.Credential) (*mongo.Client, error) {
+	client, err := dial(ctx, o, credential)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, errors.Wrap(err, "Fail to ping to mongodb server")
+	}
+
+	return client, nil
+}
+
+// currentClient returns the client authenticated with the current credentials
+func (m *MongoDB) currentClient() *mongo.Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.client
+}
+
+// watchCredential re-authenticates when the secrets of the username or password rotate. Both are watched as one unit,
+// so a rotation that changes both connects once with the new pair
+func (m *MongoDB) watchCredential(conf config.Mongo, secrets Secrets) {
+	m.stopWatches = append(m.stopWatches, secrets.Watch(
+		[]string{conf.Username, conf.Password},
+		[]string{m.credential.Username, m.credential.Password},
+		func(rotated []string) error {
+			return m.rotateCredential(rotated[0], rotated[1])
+		},
+		m.setRotationErr,
+	))
+}
+
+// setRotationErr keeps the error of the last refresh of the credentials, nil once a refresh succeeded
+func (m *MongoDB) setRotationErr(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rotationErr = err
+}
+
+// rotateCredential connects a new client with the changed credentials and replaces the current client by it.
+// Operations in flight finish on the replaced client, which is disconnected in the background.
+// If the new client can't connect the current client is kept
+func (m *MongoDB) rotateCredential(username string, password string) error {
+	m.rotating.Lock()
+	defer m.rotating.Unlock()
+
+	credential := m.credential
+	credential.Username = username
+	credential.Password = password
+
+	ctx, cancel := context.WithTimeout(context.Background(), rotationTimeout)
+	defer cancel()
+
+	client, err := connect(ctx, m.clientOptions, credential)
+	if err != nil {
+		return errors.Wrap(err, "Fail to re-authenticate to mongodb with rotated credentials")
+	}
+
+	m.mu.Lock()
+	replaced := m.client
+	m.client = client
+	m.mu.Unlock()
+	m.credential = credential
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), rotationTimeout)
+		defer cancel()
+
+		_ = replaced.Disconnect(ctx)
+	}()
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	idempotencyCreatedAtField = "createdAt"
+
+	// reserveAttempts bounds retries when a conflicting record expires between the insert and the lookup
+	reserveAttempts = 2
+)
+
+// idempotencyRecord is stored with the idempotency key as _id, so the _id unique index rejects concurrent duplicates
+type idempotencyRecord struct {
+	ID          string    `bson:"_id"`
+	Tenant      string    `bson:"tenant,omitempty"`
+	RequestHash string    `bson:"requestHash"`
+	DocumentID  string    `bson:"documentId"`
+	CreatedAt   time.Time `bson:"createdAt"`
+	// ReservedUntil is when a record without a document is abandoned and may be taken over
+	ReservedUntil time.Time `bson:"reservedUntil"`
+}
+
+// ReserveIdempotencyKey records the key for a new request. It returns false with the existing record
+// if the key was already used, unless the request that used it never completed and its reservation expired
+func (m *MongoDB) ReserveIdempotencyKey(ctx context.Context, key string, requestHash string) (models.IdempotencyRecord, bool, error) {
+	scope, err := m.scope(ctx)
+	if err != nil {
+		return models.IdempotencyRecord{}, false, err
+	}
+
+	now := time.Now().UTC()
+	rec := idempotencyRecord{
+		ID:            scope.recordID(key),
+		RequestHash:   requestHash,
+		CreatedAt:     now,
+		ReservedUntil: now.Add(m.idempotencyReservation),
+	}
+	if scope.field {
+		rec.Tenant = scope.tenant
+	}
+
+	for i := 0; i < reserveAttempts; i++ {
+		_, err := scope.idempotency.InsertOne(ctx, rec)
+		if err == nil {
+			return models.IdempotencyRecord{Key: key, RequestHash: requestHash, CreatedAt: rec.CreatedAt, ReservedUntil: rec.ReservedUntil}, true, nil
+		}
+
+		if !mongo.IsDuplicateKeyError(err) {
+			return models.IdempotencyRecord{}, false, errors.Wrapf(err, "Failed to insert idempotency key (%s) to mongodb", key).SetType(errors.ErrorTypeInternal)
+		}
+
+		abandoned := scope.filter(map[string]interface{}{
+			"_id":           rec.ID,
+			"documentId":    "",
+			"reservedUntil": map[string]interface{}{"$lt": now},
+		})
+		update := map[string]interface{}{"$set": map[string]interface{}{
+			"requestHash":   rec.RequestHash,
+			"createdAt":     rec.CreatedAt,
+			"reservedUntil": rec.ReservedUntil,
+		}}
+		res, err := scope.idempotency.UpdateOne(ctx, abandoned, update)
+		if err != nil {
+			return models.IdempotencyRecord{}, false, errors.Wrapf(err, "Failed to take over idempotency key (%s) in mongodb", key).SetType(errors.ErrorTypeInternal)
+		}
+
+		if res.MatchedCount > 0 {
+			return models.IdempotencyRecord{Key: key, RequestHash: requestHash, CreatedAt: rec.CreatedAt, ReservedUntil: rec.ReservedUntil}, true, nil
+		}
+
+		var existing idempotencyRecord
+		err = scope.idempotency.FindOne(ctx, scope.filter(map[string]interface{}{"_id": rec.ID})).Decode(&existing)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+
+		if err != nil {
+			return models.IdempotencyRecord{}, false, errors.Wrapf(err, "Failed to find idempotency key (%s) in mongodb", key).SetType(errors.ErrorTypeInternal)
+		}
+
+		return models.IdempotencyRecord{
+			Key:           key,
+			RequestHash:   existing.RequestHash,
+			DocumentID:    existing.DocumentID,
+			CreatedAt:     existing.CreatedAt,
+			ReservedUntil: existing.ReservedUntil,
+		}, false, nil
+	}
+
+	return models.IdempotencyRecord{}, false, errors.Errorf("Failed to reserve idempotency key (%s)", key).SetType(errors.ErrorTypeConflict)
+}
+
+// CompleteIdempotencyKey stores the id of the document created by the request of the key
+func (m *MongoDB) CompleteIdempotencyKey(ctx context.Context, key string, documentID string) error {
+	scope, err := m.scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	filter := scope.filter(map[string]interface{}{"_id": scope.recordID(key)})
+	update := map[string]interface{}{"$set": map[string]interface{}{"documentId": documentID}}
+	if _, err := scope.idempotency.UpdateOne(ctx, filter, update); err != nil {
+		return errors.Wrapf(err, "Failed to complete idempotency key (%s) in mongodb", key).SetType(errors.ErrorTypeInternal)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey removes the key of a request that failed, so it can be retried
+func (m *MongoDB) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	scope, err := m.scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := scope.idempotency.DeleteOne(ctx, scope.filter(map[string]interface{}{"_id": scope.recordID(key)})); err != nil {
+		return errors.Wrapf(err, "Failed to release idempotency key (%s) in mongodb", key).SetType(errors.ErrorTypeInternal)
+	}
+
+	return nil
+}
+
+// recordID prefixes the key with the tenant when tenants share a collection
+func (s tenantScope) recordID(key string) string {
+	if s.field {
+		return s.tenant + "/" + key
+	}
+
+	return key
+}
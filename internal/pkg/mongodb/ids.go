@@ -0,0 +1,100 @@
+package mongodb
+
+import (
+	"strings"
+
+	"microservice/internal/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	idStrategyObjectID = "objectID"
+	idStrategyUUIDv4   = "uuidv4"
+	idStrategyUUIDv7   = "uuidv7"
+	idStrategyULID     = "ulid"
+)
+
+// idStrategy generates the ids of new documents and validates the ids given by clients
+type idStrategy interface {
+	// newID returns a new id in its string representation
+	newID() (string, error)
+
+	// parseID validates id and returns the value stored as the _id of the document
+	parseID(id string) (interface{}, error)
+}
+
+// newIDStrategy returns the id strategy of the given name
+func newIDStrategy(name string) (idStrategy, error) {
+	switch name {
+	case idStrategyObjectID:
+		return objectIDStrategy{}, nil
+	case idStrategyUUIDv4:
+		return uuidStrategy{version: 4, generate: uuid.NewRandom}, nil
+	case idStrategyUUIDv7:
+		return uuidStrategy{version: 7, generate: uuid.NewV7}, nil
+	case idStrategyULID:
+		return ulidStrategy{}, nil
+	}
+
+	return nil, errors.Errorf("Invalid mongo id strategy (%s), expected one of (%s)",
+		name, strings.Join([]string{idStrategyObjectID, idStrategyUUIDv4, idStrategyUUIDv7, idStrategyULID}, ", "))
+}
+
+// objectIDStrategy stores ids as mongodb ObjectIDs, represented as hex strings
+type objectIDStrategy struct{}
+
+func (objectIDStrategy) newID() (string, error) {
+	return primitive.NewObjectID().Hex(), nil
+}
+
+func (objectIDStrategy) parseID(id string) (interface{}, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.Errorf("id (%s) is not a valid ObjectID", id).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	return objID, nil
+}
+
+// uuidStrategy stores ids as canonical uuid strings of a single version
+type uuidStrategy struct {
+	version  uuid.Version
+	generate func() (uuid.UUID, error)
+}
+
+func (s uuidStrategy) newID() (string, error) {
+	u, err := s.generate()
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to generate uuid v%d", s.version).SetType(errors.ErrorTypeInternal)
+	}
+
+	return u.String(), nil
+}
+
+func (s uuidStrategy) parseID(id string) (interface{}, error) {
+	u, err := uuid.Parse(id)
+	if err != nil || u.Version() != s.version || len(id) != len(u.String()) {
+		return nil, errors.Errorf("id (%s) is not a valid uuid v%d", id, s.version).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	return u.String(), nil
+}
+
+// ulidStrategy stores ids as canonical ulid strings
+type ulidStrategy struct{}
+
+func (ulidStrategy) newID() (string, error) {
+	return ulid.Make().String(), nil
+}
+
+func (ulidStrategy) parseID(id string) (interface{}, error) {
+	u, err := ulid.ParseStrict(id)
+	if err != nil {
+		return nil, errors.Errorf("id (%s) is not a valid ULID", id).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	return u.String(), nil
+}
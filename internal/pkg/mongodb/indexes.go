@@ -0,0 +1,335 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"github.com/spf13/cast"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	mongoIndexesDeclaredKey = "mongo.indexes.declared"
+
+	indexOrderText = "text"
+
+	// idIndexName is the name of the default _id index of every collection
+	idIndexName = "_id_"
+
+	// textIndexKeyField is the key under which mongodb stores the fields of a text index
+	textIndexKeyField = "_fts"
+)
+
+// indexFieldPattern accepts the top level fields of a document and field paths inside its content
+var indexFieldPattern = regexp.MustCompile(`^(name|version|createdby|doc(\.[^.$\s][^.\s]*)+)$`)
+
+// indexSpec is an index declared in configuration
+type indexSpec struct {
+	name   string
+	keys   bson.D
+	unique bool
+	ttl    time.Duration
+}
+
+// existingIndex is an index as listed by mongodb
+type existingIndex struct {
+	Name               string      `bson:"name"`
+	Key                bson.D      `bson:"key"`
+	Unique             bool        `bson:"unique"`
+	ExpireAfterSeconds interface{} `bson:"expireAfterSeconds"`
+	Weights            bson.M      `bson:"weights"`
+}
+
+// parseIndexSpecs reads the indexes declared in configuration
+func parseIndexSpecs(declared []config.MongoIndex) ([]indexSpec, error) {
+	specs := make([]indexSpec, 0, len(declared))
+	names := make(map[string]bool, len(declared))
+	for i, index := range declared {
+		spec, err := parseIndexSpec(index)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid index entry (%d) in configuration key (%s)", i, mongoIndexesDeclaredKey)
+		}
+
+		if names[spec.name] {
+			return nil, errors.Errorf("Index (%s) is declared more than once in configuration key (%s)", spec.name, mongoIndexesDeclaredKey)
+		}
+
+		names[spec.name] = true
+		specs = append(specs, spec)
+	}
+
+	return specs, nil
+}
+
+func parseIndexSpec(index config.MongoIndex) (indexSpec, error) {
+	spec := indexSpec{name: index.Name, unique: index.Unique, ttl: index.TTL}
+	if spec.name == "" || spec.name == idIndexName {
+		return indexSpec{}, errors.Errorf("Index must have a name other than (%s)", idIndexName)
+	}
+
+	if spec.ttl != 0 && spec.ttl < time.Second {
+		return indexSpec{}, errors.Errorf("Invalid ttl of index (%s), expected a duration of at least 1s", spec.name)
+	}
+
+	if len(index.Keys) == 0 {
+		return indexSpec{}, errors.Errorf("Index (%s) must have at least one key", spec.name)
+	}
+
+	text := false
+	for _, key := range index.Keys {
+		if !indexFieldPattern.MatchString(key.Field) {
+			return indexSpec{}, errors.Errorf("Invalid field (%s) of index (%s), expected a document field or a path inside doc", key.Field, spec.name)
+		}
+
+		order, err := parseIndexOrder(key.Order)
+		if err != nil {
+			return indexSpec{}, errors.Wrapf(err, "Invalid order of field (%s) of index (%s)", key.Field, spec.name)
+		}
+
+		text = text || order == indexOrderText
+		spec.keys = append(spec.keys, bson.E{Key: key.Field, Value: order})
+	}
+
+	if spec.ttl > 0 && (len(spec.keys) > 1 || text) {
+		return indexSpec{}, errors.Errorf("TTL index (%s) must have a single non text key", spec.name)
+	}
+
+	if spec.unique && text {
+		return indexSpec{}, errors.Errorf("Text index (%s) can't be unique", spec.name)
+	}
+
+	return spec, nil
+}
+
+// parseIndexOrder accepts 1 and -1 for ascending and descending keys, and "text" for text keys. The default is ascending
+func parseIndexOrder(v interface{}) (interface{}, error) {
+	if v == nil {
+		return int32(1), nil
+	}
+
+	if s, ok := v.(string); ok && s == indexOrderText {
+		return indexOrderText, nil
+	}
+
+	order, err := cast.ToInt32E(v)
+	if err != nil || (order != 1 && order != -1) {
+		return nil, errors.Errorf("Invalid order (%v), expected 1, -1 or (%s)", v, indexOrderText)
+	}
+
+	return order, nil
+}
+
+// model returns the index model of spec in scope. When tenants share a collection, all indexes
+// except TTL indexes are prefixed by the tenant field, so unique indexes are unique per tenant
+func (spec indexSpec) model(s tenantScope) mongo.IndexModel {
+	keys := spec.keys
+	if s.field && spec.ttl == 0 {
+		keys = append(bson.D{{Key: tenantField, Value: int32(1)}}, spec.keys...)
+	}
+
+	o := options.Index().SetName(spec.name)
+	if spec.unique {
+		o.SetUnique(true)
+	}
+
+	if spec.ttl > 0 {
+		o.SetExpireAfterSeconds(int32(spec.ttl.Seconds()))
+	}
+
+	return mongo.IndexModel{Keys: keys, Options: o}
+}
+
+// drift describes how the existing index differs from its declaration, it returns an empty string if they match
+func (spec indexSpec) drift(s tenantScope, existing existingIndex) string {
+	m := spec.model(s)
+	var diffs []string
+
+	if !sameIndexKeys(m.Keys.(bson.D), existing) {
+		diffs = append(diffs, fmt.Sprintf("keys %v, declared %v", existing.Key, m.Keys))
+	}
+
+	if existing.Unique != spec.unique {
+		diffs = append(diffs, fmt.Sprintf("unique %t, declared %t", existing.Unique, spec.unique))
+	}
+
+	var ttl int64
+	if existing.ExpireAfterSeconds != nil {
+		ttl = cast.ToInt64(existing.ExpireAfterSeconds)
+	}
+
+	if ttl != int64(spec.ttl.Seconds()) {
+		diffs = append(diffs, fmt.Sprintf("ttl %ds, declared %ds", ttl, int64(spec.ttl.Seconds())))
+	}
+
+	return strings.Join(diffs, "; ")
+}
+
+// sameIndexKeys compares declared keys to the keys of an existing index.
+// Mongodb stores the fields of a text index as weights instead of keys
+func sameIndexKeys(declared bson.D, existing existingIndex) bool {
+	var declaredKeys, existingKeys []string
+	var declaredText, existingText []string
+
+	for _, k := range declared {
+		if k.Value == indexOrderText {
+			declaredText = append(declaredText, k.Key)
+			continue
+		}
+
+		declaredKeys = append(declaredKeys, fmt.Sprintf("%s:%d", k.Key, cast.ToInt32(k.Value)))
+	}
+
+	for _, k := range existing.Key {
+		if k.Key == textIndexKeyField || k.Key == textIndexKeyField+"x" {
+			continue
+		}
+
+		existingKeys = append(existingKeys, fmt.Sprintf("%s:%d", k.Key, cast.ToInt32(k.Value)))
+	}
+
+	for field := range existing.Weights {
+		existingText = append(existingText, field)
+	}
+
+	sort.Strings(declaredText)
+	sort.Strings(existingText)
+
+	return reflect.DeepEqual(declaredKeys, existingKeys) && reflect.DeepEqual(declaredText, existingText)
+}
+
+// ReconcileIndexes creates the declared indexes that are missing in the documents collection of every tenant database,
+// and reports declared indexes whose definition drifted and existing indexes that are not declared.
+// Undeclared indexes are dropped when mongo.indexes.dropUndeclared is set
+func (m *MongoDB) ReconcileIndexes(ctx context.Context) ([]models.IndexReport, error) {
+	scopes, err := m.scopes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]models.IndexReport, 0, len(scopes))
+	for _, s := range scopes {
+		report, err := m.reconcileIndexes(ctx, s)
+		if err != nil {
+			return reports, err
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// scopes returns a scope for every database that holds documents
+func (m *MongoDB) scopes(ctx context.Context) ([]tenantScope, error) {
+	if err := m.checkReady(); err != nil {
+		return nil, err
+	}
+
+	if m.strategy == tenancyField {
+		return []tenantScope{m.newScope("", m.currentClient().Database(m.database), true)}, nil
+	}
+
+	prefix := m.database + "_"
+	filter := bson.M{"name": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}}
+	names, err := m.currentClient().ListDatabaseNames(ctx, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list tenant databases").SetType(errors.ErrorTypeInternal)
+	}
+
+	scopes := make([]tenantScope, 0, len(names))
+	for _, name := range names {
+		scopes = append(scopes, m.newScope(strings.TrimPrefix(name, prefix), m.currentClient().Database(name), false))
+	}
+
+	return scopes, nil
+}
+
+func (m *MongoDB) reconcileIndexes(ctx context.Context, s tenantScope) (models.IndexReport, error) {
+	report := models.IndexReport{
+		Database:   s.database.Name(),
+		Collection: s.collection.Name(),
+	}
+
+	cursor, err := s.collection.Indexes().List(ctx)
+	if err != nil {
+		return report, errors.Wrapf(err, "Failed to list indexes of database (%s)", report.Database).SetType(errors.ErrorTypeInternal)
+	}
+
+	var existing []existingIndex
+	if err := cursor.All(ctx, &existing); err != nil {
+		return report, errors.Wrapf(err, "Failed to decode indexes of database (%s)", report.Database).SetType(errors.ErrorTypeInternal)
+	}
+
+	byName := make(map[string]existingIndex, len(existing))
+	for _, idx := range existing {
+		byName[idx.Name] = idx
+	}
+
+	declared := make(map[string]bool, len(m.indexes))
+	for _, spec := range m.indexes {
+		declared[spec.name] = true
+
+		idx, ok := byName[spec.name]
+		if !ok {
+			if _, err := s.collection.Indexes().CreateOne(ctx, spec.model(s)); err != nil {
+				return report, errors.Wrapf(err, "Failed to create index (%s) in database (%s)", spec.name, report.Database).SetType(errors.ErrorTypeInternal)
+			}
+
+			report.Created = append(report.Created, spec.name)
+			continue
+		}
+
+		if d := spec.drift(s, idx); d != "" {
+			report.Drifted = append(report.Drifted, fmt.Sprintf("%s: %s", spec.name, d))
+		}
+	}
+
+	managed := m.managedIndexNames(s)
+	for _, idx := range existing {
+		if declared[idx.Name] || managed[idx.Name] {
+			continue
+		}
+
+		report.Undeclared = append(report.Undeclared, idx.Name)
+		if !m.dropUndeclared {
+			continue
+		}
+
+		if _, err := s.collection.Indexes().DropOne(ctx, idx.Name); err != nil {
+			return report, errors.Wrapf(err, "Failed to drop index (%s) in database (%s)", idx.Name, report.Database).SetType(errors.ErrorTypeInternal)
+		}
+
+		report.Dropped = append(report.Dropped, idx.Name)
+	}
+
+	return report, nil
+}
+
+// managedIndexNames returns the names of the indexes the service creates on its own, which are never reported or dropped
+func (m *MongoDB) managedIndexNames(s tenantScope) map[string]bool {
+	names := map[string]bool{idIndexName: true}
+	if s.field {
+		names[tenantField+"_1"] = true
+	}
+
+	if m.uniqueNames {
+		if s.field {
+			names[tenantField+"_1_"+nameField+"_1"] = true
+		} else {
+			names[nameField+"_1"] = true
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,352 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	migrationsCollection     = "_migrations"
+	migrationsLockCollection = "_migrations_lock"
+	migrationsLockID         = "migrations"
+)
+
+// Tenants provides the tenant given to documents stored before tenancy was introduced
+type Tenants interface {
+	DefaultTenant() string
+}
+
+// migration is a versioned change of the stored documents that can be reverted
+type migration struct {
+	version     int64
+	description string
+	// field migrations only change the collection shared by tenants, they are recorded without changes elsewhere
+	field bool
+	up    migrationStep
+	down  migrationStep
+}
+
+// migrationStep changes the documents matching its filter
+type migrationStep struct {
+	// filter selects the documents the step changes, it is used to count the affected documents on dry runs
+	filter map[string]interface{}
+
+	// apply changes the documents of the collection matching filter and returns the number of changed documents
+	apply func(ctx context.Context, c *mongo.Collection, filter map[string]interface{}) (int64, error)
+}
+
+// migrationRecord is stored in the migrations collection of a database for every applied migration
+type migrationRecord struct {
+	Version     int64     `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+// migrationLock is held by a single migration runner across all instances of the service
+type migrationLock struct {
+	ID        string    `bson:"_id"`
+	Owner     string    `bson:"owner"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// validateMigrations verifies that migrations are ordered by unique versions and can be applied and reverted
+func validateMigrations(ms []migration) error {
+	for i, mig := range ms {
+		if mig.version <= 0 {
+			return errors.Errorf("Migration (%s) must have a positive version", mig.description)
+		}
+
+		if i > 0 && mig.version <= ms[i-1].version {
+			return errors.Errorf("Migration version (%d) must be greater than the version of the previous migration (%d)", mig.version, ms[i-1].version)
+		}
+
+		if mig.up.apply == nil || mig.down.apply == nil {
+			return errors.Errorf("Migration (%d) must define both up and down", mig.version)
+		}
+	}
+
+	return nil
+}
+
+// MigrationStatus returns the status of every migration in every database that holds documents
+func (m *MongoDB) MigrationStatus(ctx context.Context) ([]models.MigrationStatus, error) {
+	scopes, err := m.scopes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []models.MigrationStatus
+	for _, s := range scopes {
+		applied, err := m.appliedMigrations(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, mig := range m.migrations {
+			status := models.MigrationStatus{
+				Database:    s.database.Name(),
+				Version:     mig.version,
+				Description: mig.description,
+			}
+
+			if rec, ok := applied[mig.version]; ok {
+				status.Applied = true
+				status.AppliedAt = rec.AppliedAt
+			}
+
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses, nil
+}
+
+// MigrateUp applies the pending migrations in order in every database that holds documents.
+// A dry run only counts the documents every pending migration would change
+func (m *MongoDB) MigrateUp(ctx context.Context, dryRun bool) ([]models.MigrationResult, error) {
+	return m.migrate(ctx, dryRun, func(ctx context.Context, s tenantScope, applied map[int64]migrationRecord) ([]models.MigrationResult, error) {
+		var results []models.MigrationResult
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.version]; ok {
+				continue
+			}
+
+			r, err := m.runMigration(ctx, s, mig, false, dryRun)
+			if err != nil {
+				return results, err
+			}
+
+			results = append(results, r)
+		}
+
+		return results, nil
+	})
+}
+
+// MigrateDown reverts the latest applied migration in every database that holds documents.
+// A dry run only counts the documents the migration would change
+func (m *MongoDB) MigrateDown(ctx context.Context, dryRun bool) ([]models.MigrationResult, error) {
+	return m.migrate(ctx, dryRun, func(ctx context.Context, s tenantScope, applied map[int64]migrationRecord) ([]models.MigrationResult, error) {
+		if len(applied) == 0 {
+			return nil, nil
+		}
+
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, mig := range m.migrations {
+			if mig.version != versions[0] {
+				continue
+			}
+
+			r, err := m.runMigration(ctx, s, mig, true, dryRun)
+			if err != nil {
+				return nil, err
+			}
+
+			return []models.MigrationResult{r}, nil
+		}
+
+		return nil, errors.Errorf("Applied migration (%d) of database (%s) is unknown and can't be reverted", versions[0], s.database.Name()).SetType(errors.ErrorTypeInternal)
+	})
+}
+
+// migrate runs fn on every database that holds documents while holding the migrations lock.
+// Dry runs don't change data and don't take the lock
+func (m *MongoDB) migrate(ctx context.Context, dryRun bool, fn func(context.Context, tenantScope, map[int64]migrationRecord) ([]models.MigrationResult, error)) ([]models.MigrationResult, error) {
+	if err := m.checkReady(); err != nil {
+		return nil, err
+	}
+
+	if !dryRun {
+		release, err := m.acquireMigrationLock(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	scopes, err := m.scopes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []models.MigrationResult
+	for _, s := range scopes {
+		applied, err := m.appliedMigrations(ctx, s)
+		if err != nil {
+			return results, err
+		}
+
+		r, err := fn(ctx, s, applied)
+		results = append(results, r...)
+		if err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+// runMigration applies or reverts mig in the database of s and records the outcome
+func (m *MongoDB) runMigration(ctx context.Context, s tenantScope, mig migration, down bool, dryRun bool) (models.MigrationResult, error) {
+	result := models.MigrationResult{
+		Database:    s.database.Name(),
+		Version:     mig.version,
+		Description: mig.description,
+		Down:        down,
+		DryRun:      dryRun,
+	}
+
+	step := mig.up
+	if down {
+		step = mig.down
+	}
+
+	if mig.field && !s.field {
+		step.apply = keepDocuments
+		if dryRun {
+			return result, nil
+		}
+	}
+
+	if dryRun {
+		count, err := s.collection.CountDocuments(ctx, copyFilter(step.filter))
+		if err != nil {
+			return result, errors.Wrapf(err, "Failed to count documents affected by migration (%d) in database (%s)", mig.version, result.Database).SetType(errors.ErrorTypeInternal)
+		}
+
+		result.Affected = count
+		return result, nil
+	}
+
+	affected, err := step.apply(ctx, s.collection, copyFilter(step.filter))
+	if err != nil {
+		return result, errors.Wrapf(err, "Failed to run migration (%d) in database (%s)", mig.version, result.Database).SetType(errors.ErrorTypeInternal)
+	}
+
+	result.Affected = affected
+
+	records := s.database.Collection(migrationsCollection)
+	if down {
+		_, err = records.DeleteOne(ctx, map[string]interface{}{"_id": mig.version})
+	} else {
+		_, err = records.InsertOne(ctx, migrationRecord{Version: mig.version, Description: mig.description, AppliedAt: time.Now().UTC()})
+	}
+
+	if err != nil {
+		return result, errors.Wrapf(err, "Failed to record migration (%d) in database (%s)", mig.version, result.Database).SetType(errors.ErrorTypeInternal)
+	}
+
+	if err := m.extendMigrationLock(ctx); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (m *MongoDB) appliedMigrations(ctx context.Context, s tenantScope) (map[int64]migrationRecord, error) {
+	cursor, err := s.database.Collection(migrationsCollection).Find(ctx, map[string]interface{}{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list applied migrations of database (%s)", s.database.Name()).SetType(errors.ErrorTypeInternal)
+	}
+
+	var records []migrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode applied migrations of database (%s)", s.database.Name()).SetType(errors.ErrorTypeInternal)
+	}
+
+	applied := make(map[int64]migrationRecord, len(records))
+	for _, rec := range records {
+		applied[rec.Version] = rec
+	}
+
+	return applied, nil
+}
+
+// acquireMigrationLock takes the migrations lock, or takes it over if its holder let it expire.
+// It returns a function that releases the lock
+func (m *MongoDB) acquireMigrationLock(ctx context.Context) (func(), error) {
+	locks := m.currentClient().Database(m.database).Collection(migrationsLockCollection)
+	now := time.Now().UTC()
+	lock := migrationLock{
+		ID:        migrationsLockID,
+		Owner:     m.lockOwner,
+		ExpiresAt: now.Add(m.migrationLockTTL),
+	}
+
+	_, err := locks.InsertOne(ctx, lock)
+	if mongo.IsDuplicateKeyError(err) {
+		filter := map[string]interface{}{"_id": migrationsLockID, "expiresAt": map[string]interface{}{"$lt": now}}
+		update := map[string]interface{}{"$set": map[string]interface{}{"owner": lock.Owner, "expiresAt": lock.ExpiresAt}}
+
+		var res *mongo.UpdateResult
+		res, err = locks.UpdateOne(ctx, filter, update)
+		if err == nil && res.MatchedCount == 0 {
+			var holder migrationLock
+			if err := locks.FindOne(ctx, map[string]interface{}{"_id": migrationsLockID}).Decode(&holder); err != nil {
+				return nil, errors.Wrap(err, "Migrations are locked by another runner").SetType(errors.ErrorTypeConflict)
+			}
+
+			return nil, errors.Errorf("Migrations are locked by (%s) until %s", holder.Owner, holder.ExpiresAt.Format(time.RFC3339)).SetType(errors.ErrorTypeConflict)
+		}
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to acquire migrations lock").SetType(errors.ErrorTypeInternal)
+	}
+
+	return func() {
+		// The lock expires on its own if it can't be released, so the error is ignored
+		_, _ = locks.DeleteOne(context.Background(), map[string]interface{}{"_id": migrationsLockID, "owner": lock.Owner})
+	}, nil
+}
+
+// extendMigrationLock renews the lock after every migration, so long runs don't lose it
+func (m *MongoDB) extendMigrationLock(ctx context.Context) error {
+	locks := m.currentClient().Database(m.database).Collection(migrationsLockCollection)
+	filter := map[string]interface{}{"_id": migrationsLockID, "owner": m.lockOwner}
+	update := map[string]interface{}{"$set": map[string]interface{}{"expiresAt": time.Now().UTC().Add(m.migrationLockTTL)}}
+
+	res, err := locks.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return errors.Wrap(err, "Failed to extend migrations lock").SetType(errors.ErrorTypeInternal)
+	}
+
+	if res.MatchedCount == 0 {
+		return errors.New("Migrations lock was lost to another runner").SetType(errors.ErrorTypeConflict)
+	}
+
+	return nil
+}
+
+// newLockOwner identifies this process as the holder of locks
+func newLockOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return fmt.Sprintf("%s/%d/%s", host, os.Getpid(), uuid.New().String())
+}
+
+func copyFilter(f map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(f))
+	for k, v := range f {
+		c[k] = v
+	}
+
+	return c
+}
@@ -0,0 +1,283 @@
+package mongodb
+
+import (
+	"context"
+
+	"microservice/internal/pkg/errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rekeyJournalCollection holds the documents being moved to a new _id, so the moves of an interrupted run are finished
+const rekeyJournalCollection = "_migrations_rekey"
+
+// newMigrations lists the data migrations in the order of their versions, documents stored without a tenant are
+// given defaultTenant.
+// A released migration must never change, new migrations are appended with a greater version
+func newMigrations(defaultTenant string) []migration {
+	return []migration{
+		{
+			version:     1,
+			description: "Set version 0 on documents stored before versioning was introduced",
+			up: migrationStep{
+				filter: map[string]interface{}{"version": map[string]interface{}{"$exists": false}},
+				apply:  setFields(map[string]interface{}{"version": 0}),
+			},
+			down: migrationStep{
+				filter: map[string]interface{}{"version": 0},
+				apply:  unsetFields("version"),
+			},
+		},
+		{
+			version:     2,
+			description: "Set the default tenant on documents stored before tenancy was introduced",
+			field:       true,
+			up: migrationStep{
+				filter: map[string]interface{}{tenantField: map[string]interface{}{"$exists": false}},
+				apply:  setDefaultTenant(defaultTenant),
+			},
+			down: migrationStep{
+				// the documents given the default tenant can't be told apart from the ones stored for it, so they keep it
+				filter: map[string]interface{}{"_id": map[string]interface{}{"$in": []interface{}{}}},
+				apply:  keepDocuments,
+			},
+		},
+		{
+			version:     3,
+			description: "Store the documents of tenants sharing a collection under an _id compound of the tenant and the id",
+			field:       true,
+			up: migrationStep{
+				filter: map[string]interface{}{
+					"_id":       map[string]interface{}{"$not": map[string]interface{}{"$type": "object"}},
+					tenantField: map[string]interface{}{"$exists": true},
+				},
+				apply: rekeyDocuments(tenantID),
+			},
+			down: migrationStep{
+				filter: map[string]interface{}{"_id": map[string]interface{}{"$type": "object"}},
+				// documents of different tenants may have the same id, which can't be shared by their old _ids
+				apply: rekeyDocuments(sharedID),
+			},
+		},
+	}
+}
+
+// tenantID returns the _id compound of the tenant and the id of a document stored under its id
+func tenantID(doc bson.Raw) (interface{}, error) {
+	return bson.D{{Key: tenantField, Value: doc.Lookup(tenantField)}, {Key: storedIDField, Value: doc.Lookup("_id")}}, nil
+}
+
+// sharedID returns the id of a document stored under an _id compound of its tenant and id
+func sharedID(doc bson.Raw) (interface{}, error) {
+	return doc.LookupErr("_id", storedIDField)
+}
+
+// setFields returns a migration step function that sets fields on the matching documents
+func setFields(fields map[string]interface{}) func(context.Context, *mongo.Collection, map[string]interface{}) (int64, error) {
+	return func(ctx context.Context, c *mongo.Collection, filter map[string]interface{}) (int64, error) {
+		res, err := c.UpdateMany(ctx, filter, map[string]interface{}{"$set": fields})
+		if err != nil {
+			return 0, err
+		}
+
+		return res.ModifiedCount, nil
+	}
+}
+
+// unsetFields returns a migration step function that removes fields from the matching documents
+func unsetFields(fields ...string) func(context.Context, *mongo.Collection, map[string]interface{}) (int64, error) {
+	unset := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		unset[f] = ""
+	}
+
+	return func(ctx context.Context, c *mongo.Collection, filter map[string]interface{}) (int64, error) {
+		res, err := c.UpdateMany(ctx, filter, map[string]interface{}{"$unset": unset})
+		if err != nil {
+			return 0, err
+		}
+
+		return res.ModifiedCount, nil
+	}
+}
+
+// setDefaultTenant returns a migration step function that sets defaultTenant on the matching documents.
+// Without a default tenant the documents would be unreachable, so the step fails if there are any
+func setDefaultTenant(defaultTenant string) func(context.Context, *mongo.Collection, map[string]interface{}) (int64, error) {
+	if defaultTenant != "" {
+		return setFields(map[string]interface{}{tenantField: defaultTenant})
+	}
+
+	return func(ctx context.Context, c *mongo.Collection, filter map[string]interface{}) (int64, error) {
+		count, err := c.CountDocuments(ctx, filter)
+		if err != nil {
+			return 0, err
+		}
+
+		if count > 0 {
+			return 0, errors.Errorf("Found %d documents without a tenant, configure (tenancy.defaultTenant) to give them one", count)
+		}
+
+		return 0, nil
+	}
+}
+
+// keepDocuments is a migration step function that changes no document
+func keepDocuments(context.Context, *mongo.Collection, map[string]interface{}) (int64, error) {
+	return 0, nil
+}
+
+// rekeyDocuments returns a migration step function that moves the matching documents to the _id returned by key
+func rekeyDocuments(key func(doc bson.Raw) (interface{}, error)) func(context.Context, *mongo.Collection, map[string]interface{}) (int64, error) {
+	return func(ctx context.Context, c *mongo.Collection, filter map[string]interface{}) (int64, error) {
+		return moveDocuments(ctx, c, c.Database().Collection(rekeyJournalCollection), filter, key)
+	}
+}
+
+// documentCollection is the part of a collection used to move documents
+type documentCollection interface {
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error)
+	InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+}
+
+// rekeyEntry journals the move of a document from its old _id to the _id of Document
+type rekeyEntry struct {
+	OldID    bson.RawValue `bson:"_id"`
+	Document bson.Raw      `bson:"document"`
+}
+
+// moveDocuments moves the documents of c matching filter to the _id returned by key.
+// A document is journaled before it is deleted from its old _id and inserted under its new _id, so the copy never
+// collides with the original on a unique index. The moves journaled by an interrupted run are finished first
+func moveDocuments(ctx context.Context, c documentCollection, journal documentCollection, filter map[string]interface{}, key func(doc bson.Raw) (interface{}, error)) (int64, error) {
+	if err := finishMoves(ctx, c, journal); err != nil {
+		return 0, err
+	}
+
+	cursor, err := c.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var moved int64
+	for cursor.Next(ctx) {
+		oldID := cursor.Current.Lookup("_id")
+		newID, err := key(cursor.Current)
+		if err != nil {
+			return moved, errors.Wrapf(err, "Failed to get the new _id of document (%s)", oldID)
+		}
+
+		doc, err := withID(cursor.Current, newID)
+		if err != nil {
+			return moved, err
+		}
+
+		entry := rekeyEntry{OldID: oldID, Document: doc}
+		if _, err := journal.InsertOne(ctx, entry); err != nil {
+			return moved, errors.Wrapf(err, "Failed to journal the move of document (%s)", oldID)
+		}
+
+		if _, err := c.DeleteOne(ctx, bson.M{"_id": oldID}); err != nil {
+			return moved, errors.Wrapf(err, "Failed to remove document (%s) from its old _id", oldID)
+		}
+
+		if err := insertMoved(ctx, c, journal, entry); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+
+	return moved, cursor.Err()
+}
+
+// finishMoves completes the moves left in the journal by an interrupted run. A move whose document is still under
+// its old _id or already under its new _id is done, the others are inserted under their new _id
+func finishMoves(ctx context.Context, c documentCollection, journal documentCollection) error {
+	cursor, err := journal.Find(ctx, bson.M{})
+	if err != nil {
+		return errors.Wrap(err, "Failed to list the journaled moves of documents")
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry rekeyEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return errors.Wrap(err, "Failed to decode a journaled move of a document")
+		}
+
+		done := false
+		for _, id := range []bson.RawValue{entry.OldID, entry.Document.Lookup("_id")} {
+			count, err := c.CountDocuments(ctx, bson.M{"_id": id})
+			if err != nil {
+				return errors.Wrapf(err, "Failed to find document (%s)", id)
+			}
+			done = done || count > 0
+		}
+
+		if !done {
+			if err := insertMoved(ctx, c, journal, entry); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := journal.DeleteOne(ctx, bson.M{"_id": entry.OldID}); err != nil {
+			return errors.Wrapf(err, "Failed to remove the journaled move of document (%s)", entry.OldID)
+		}
+	}
+
+	return cursor.Err()
+}
+
+// insertMoved inserts the journaled document under its new _id and removes it from the journal.
+// If it can't be inserted, such as when another document has the new _id, it is restored under its old _id
+func insertMoved(ctx context.Context, c documentCollection, journal documentCollection, entry rekeyEntry) error {
+	newID := entry.Document.Lookup("_id")
+	if _, err := c.InsertOne(ctx, entry.Document); err != nil {
+		old, rerr := withID(entry.Document, entry.OldID)
+		if rerr == nil {
+			_, rerr = c.InsertOne(ctx, old)
+		}
+
+		if rerr != nil {
+			return errors.Wrapf(err, "Failed to move document (%s) to _id (%s), it is kept in (%s) until the next run", entry.OldID, newID, rekeyJournalCollection)
+		}
+
+		// the document is back under its old _id, so a journal entry left behind is discarded by the next run
+		_, _ = journal.DeleteOne(ctx, bson.M{"_id": entry.OldID})
+		return errors.Wrapf(err, "Failed to move document (%s) to _id (%s), kept it under its old _id", entry.OldID, newID)
+	}
+
+	if _, err := journal.DeleteOne(ctx, bson.M{"_id": entry.OldID}); err != nil {
+		return errors.Wrapf(err, "Failed to remove the journaled move of document (%s)", entry.OldID)
+	}
+
+	return nil
+}
+
+// withID returns doc stored under id
+func withID(doc bson.Raw, id interface{}) (bson.Raw, error) {
+	elements, err := doc.Elements()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read document")
+	}
+
+	d := bson.D{{Key: "_id", Value: id}}
+	for _, e := range elements {
+		if e.Key() != "_id" {
+			d = append(d, bson.E{Key: e.Key(), Value: e.Value()})
+		}
+	}
+
+	b, err := bson.Marshal(d)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to store document under _id (%v)", id)
+	}
+
+	return b, nil
+}
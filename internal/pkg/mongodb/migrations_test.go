@@ -0,0 +1,248 @@
+package mongodb
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"microservice/internal/pkg/errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testCollection is an in-memory documentCollection. It enforces a unique _id and its unique indexes, and filters
+// by the value of _id. Other filters select the documents accepted by match
+type testCollection struct {
+	docs    []bson.Raw
+	unique  [][]string
+	match   func(doc bson.Raw) bool
+	inserts int
+	// failInserts fails the inserts numbered in it, counting from 1, as if the connection was lost
+	failInserts map[int]bool
+}
+
+func newTestCollection(t *testing.T, unique [][]string, docs ...bson.D) *testCollection {
+	c := &testCollection{unique: unique, match: func(bson.Raw) bool { return true }}
+	for _, doc := range docs {
+		if _, err := c.InsertOne(context.Background(), doc); err != nil {
+			t.Fatalf("Failed to insert document (%v). Error: %s", doc, err)
+		}
+	}
+	c.inserts = 0
+
+	return c
+}
+
+func rawValue(v interface{}) bson.RawValue {
+	if rv, ok := v.(bson.RawValue); ok {
+		return rv
+	}
+
+	b, err := bson.Marshal(bson.D{{Key: "v", Value: v}})
+	if err != nil {
+		panic(err)
+	}
+
+	return bson.Raw(b).Lookup("v")
+}
+
+// filtered returns the documents selected by filter
+func (c *testCollection) filtered(filter interface{}) []bson.Raw {
+	id, byID := filter.(bson.M)["_id"]
+	if _, operator := id.(map[string]interface{}); operator {
+		byID = false
+	}
+
+	var docs []bson.Raw
+	for _, doc := range c.docs {
+		if (byID && doc.Lookup("_id").Equal(rawValue(id))) || (!byID && c.match(doc)) {
+			docs = append(docs, doc)
+		}
+	}
+
+	return docs
+}
+
+func (c *testCollection) Find(_ context.Context, filter interface{}, _ ...*options.FindOptions) (*mongo.Cursor, error) {
+	if f, ok := filter.(map[string]interface{}); ok {
+		filter = bson.M(f)
+	}
+
+	var docs []interface{}
+	for _, doc := range c.filtered(filter) {
+		docs = append(docs, doc)
+	}
+
+	return mongo.NewCursorFromDocuments(docs, nil, nil)
+}
+
+func (c *testCollection) CountDocuments(_ context.Context, filter interface{}, _ ...*options.CountOptions) (int64, error) {
+	return int64(len(c.filtered(filter))), nil
+}
+
+func (c *testCollection) InsertOne(_ context.Context, document interface{}, _ ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	c.inserts++
+	if c.failInserts[c.inserts] {
+		return nil, errors.New("Connection lost")
+	}
+
+	b, err := bson.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.Raw(b)
+
+	for _, existing := range c.docs {
+		for _, fields := range append([][]string{{"_id"}}, c.unique...) {
+			same := true
+			for _, field := range fields {
+				same = same && existing.Lookup(field).Equal(doc.Lookup(field))
+			}
+
+			if same {
+				return nil, mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: "E11000 duplicate key error"}}}
+			}
+		}
+	}
+
+	c.docs = append(c.docs, doc)
+	return &mongo.InsertOneResult{InsertedID: doc.Lookup("_id")}, nil
+}
+
+func (c *testCollection) DeleteOne(_ context.Context, filter interface{}, _ ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	id := rawValue(filter.(bson.M)["_id"])
+	for i, doc := range c.docs {
+		if doc.Lookup("_id").Equal(id) {
+			c.docs = append(c.docs[:i], c.docs[i+1:]...)
+			return &mongo.DeleteResult{DeletedCount: 1}, nil
+		}
+	}
+
+	return &mongo.DeleteResult{}, nil
+}
+
+// storedDocuments returns the _id and name of every document of c, sorted
+func storedDocuments(c *testCollection) []string {
+	var docs []string
+	for _, doc := range c.docs {
+		docs = append(docs, doc.Lookup("_id").String()+" "+doc.Lookup(nameField).StringValue())
+	}
+	sort.Strings(docs)
+
+	return docs
+}
+
+func TestNewMigrations(t *testing.T) {
+	ms := newMigrations("default")
+	if err := validateMigrations(ms); err != nil {
+		t.Fatalf("validateMigrations() error = %v", err)
+	}
+
+	// documents need a tenant before they can be moved under an _id compound of it
+	tenant, rekey := ms[1], ms[2]
+	if !tenant.field || !rekey.field || tenant.version >= rekey.version {
+		t.Errorf("newMigrations() default tenant migration (%d) must precede the rekey migration (%d) in shared collections", tenant.version, rekey.version)
+	}
+}
+
+func TestMigration_rekeyDocuments(t *testing.T) {
+	ids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+	stored := func() []bson.D {
+		return []bson.D{
+			{{Key: "_id", Value: ids[0]}, {Key: tenantField, Value: "acme"}, {Key: nameField, Value: "report"}},
+			{{Key: "_id", Value: ids[1]}, {Key: tenantField, Value: "globex"}, {Key: nameField, Value: "report"}},
+			{{Key: "_id", Value: ids[2]}, {Key: tenantField, Value: "acme"}, {Key: nameField, Value: "invoice"}},
+		}
+	}
+	compound := func(tenant string, id primitive.ObjectID) bson.D {
+		return bson.D{{Key: tenantField, Value: tenant}, {Key: storedIDField, Value: id}}
+	}
+	uniqueNames := [][]string{{tenantField, nameField}}
+	up := newMigrations("default")[2].up
+	notMoved := func(doc bson.Raw) bool { return doc.Lookup("_id").Type != bsontype.EmbeddedDocument }
+
+	moved := newTestCollection(t, uniqueNames, []bson.D{
+		{{Key: "_id", Value: compound("acme", ids[0])}, {Key: tenantField, Value: "acme"}, {Key: nameField, Value: "report"}},
+		{{Key: "_id", Value: compound("globex", ids[1])}, {Key: tenantField, Value: "globex"}, {Key: nameField, Value: "report"}},
+		{{Key: "_id", Value: compound("acme", ids[2])}, {Key: tenantField, Value: "acme"}, {Key: nameField, Value: "invoice"}},
+	}...)
+	want := storedDocuments(moved)
+
+	tests := []struct {
+		name string
+		// failInserts are the inserts of the first run that fail
+		failInserts map[int]bool
+		wantErr     bool
+	}{
+		{
+			name: "documents under a unique name index expect every document moved",
+		},
+		{
+			name: "failed insert under the new _id expect document kept under its old _id and moved by the next run",
+			// the second document can't be inserted under its new _id
+			failInserts: map[int]bool{2: true},
+			wantErr:     true,
+		},
+		{
+			name: "run interrupted between removing and inserting a document expect document moved by the next run",
+			// the second document can't be inserted under its new _id, nor restored under its old _id
+			failInserts: map[int]bool{2: true, 3: true},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCollection(t, uniqueNames, stored()...)
+			c.match = notMoved
+			c.failInserts = tt.failInserts
+			journal := newTestCollection(t, nil)
+
+			_, err := moveDocuments(context.Background(), c, journal, up.filter, tenantID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("moveDocuments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				c.failInserts = nil
+				if _, err := moveDocuments(context.Background(), c, journal, up.filter, tenantID); err != nil {
+					t.Fatalf("moveDocuments() of the next run error = %v", err)
+				}
+			}
+
+			if got := storedDocuments(c); !reflect.DeepEqual(got, want) {
+				t.Errorf("moveDocuments() stored = %v, want %v", got, want)
+			}
+
+			if len(journal.docs) != 0 {
+				t.Errorf("moveDocuments() journal = %v, want empty", storedDocuments(journal))
+			}
+		})
+	}
+}
+
+func TestMigration_rekeyDocuments_conflict(t *testing.T) {
+	id := primitive.NewObjectID()
+	down := newMigrations("default")[2].down
+
+	// documents of two tenants with the same id can't both get it back as their _id
+	c := newTestCollection(t, nil,
+		bson.D{{Key: "_id", Value: bson.D{{Key: tenantField, Value: "acme"}, {Key: storedIDField, Value: id}}}, {Key: nameField, Value: "a"}},
+		bson.D{{Key: "_id", Value: bson.D{{Key: tenantField, Value: "globex"}, {Key: storedIDField, Value: id}}}, {Key: nameField, Value: "b"}},
+	)
+	c.match = func(doc bson.Raw) bool { return doc.Lookup("_id").Type == bsontype.EmbeddedDocument }
+	journal := newTestCollection(t, nil)
+
+	moved, err := moveDocuments(context.Background(), c, journal, down.filter, sharedID)
+	if err == nil {
+		t.Fatal("moveDocuments() error = nil, want error of the conflicting _id")
+	}
+
+	if moved != 1 || len(c.docs) != 2 || len(journal.docs) != 0 {
+		t.Errorf("moveDocuments() moved = %d, stored = %v, journal = %v, want 1 moved and the other kept", moved, storedDocuments(c), storedDocuments(journal))
+	}
+}
@@ -2,99 +2,270 @@ package mongodb
 
 import (
 	"context"
+	"fmt"
 	"reflect"
-	"strings"
+	"sync"
 	"time"
 
+	"microservice/internal/pkg/config"
 	"microservice/internal/pkg/errors"
+	"microservice/internal/pkg/tenancy"
 	"microservice/models"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const (
-	mongoBaseKey       = "mongo"
-	mongoHostsKey      = mongoBaseKey + ".hosts"
-	mongoUsernameKey   = mongoBaseKey + ".username"
-	mongoPasswordKey   = mongoBaseKey + ".password"
-	mongoDatabaseKey   = mongoBaseKey + ".database"
-	mongoCollectionKey = mongoBaseKey + ".collection"
-)
+	// tenancyField keeps the documents of all tenants in one collection, separated by the tenant field
+	tenancyField = "field"
 
-// Configuration expose an interface of configuration related actions
-type Configuration interface {
-	GetString(key string) (string, error)
-	GetInt(key string) (int, error)
-	GetDuration(key string) (time.Duration, error)
-}
+	// tenancyDatabase keeps the documents of each tenant in a database of its own
+	tenancyDatabase = "database"
+
+	tenantField     = "tenant"
+	nameField       = "name"
+	modifiedAtField = "modifiedat"
+
+	// storedIDField is the field of the id of a document within the compound _id of a shared collection
+	storedIDField = "id"
+
+	// upsertAttempts bounds retries of an upsert that lost an insert race on the unique name index
+	upsertAttempts = 2
+)
 
 // MongoDB client fpr mongodb which specifies which database and collection to use
 type MongoDB struct {
-	client     *mongo.Client
-	collection *mongo.Collection
+	// mu guards client, which is replaced when the credentials rotate, and rotationErr, the last failure to rotate them
+	mu            sync.RWMutex
+	client        *mongo.Client
+	clientOptions *options.ClientOptions
+	credential    options.Credential
+	rotating      sync.Mutex
+	rotationErr   error
+	stopWatches   []func()
+
+	// ready is set once mongodb answered and the database was prepared, until then connectErr holds the last failure
+	ready      int32
+	connectErr error
+	startup    startup
+
+	database       string
+	collectionName string
+	strategy       string
+	ids            idStrategy
+	uniqueNames    bool
+
+	indexes        []indexSpec
+	dropUndeclared bool
+
+	migrations       []migration
+	migrationLockTTL time.Duration
+	lockOwner        string
+
+	idempotencyCollection string
+	idempotencyTTL        time.Duration
+	// idempotencyReservation is how long a key is reserved for a request that hasn't completed
+	idempotencyReservation time.Duration
+
+	// indexedDatabases holds the names of the databases whose indexes were ensured
+	indexedDatabases sync.Map
+}
+
+// tenantScope restricts queries to the collections and documents of a single tenant.
+// Every query goes through a scope, so documents of other tenants can't be read or modified
+type tenantScope struct {
+	tenant      string
+	database    *mongo.Database
+	collection  *mongo.Collection
+	idempotency *mongo.Collection
+	field       bool
 }
 
-// NewClient returns a new instance of the MongoDB struct
-func NewClient(ctx context.Context, conf Configuration) (*MongoDB, error) {
-	o := options.Client()
+// NewClient returns a new instance of the MongoDB struct.
+// The username and password may be secret references, the client re-authenticates when they rotate.
+// The initial connection is retried with backoff, either until ctx is done or, when the startup doesn't wait for
+// the database, in the background while operations fail as unavailable
+func NewClient(ctx context.Context, conf config.Mongo, secrets Secrets, tenants Tenants) (*MongoDB, error) {
+	if conf.Tenancy != tenancyField && conf.Tenancy != tenancyDatabase {
+		return nil, errors.Errorf("Invalid mongo tenancy strategy (%s), expected (%s) or (%s)", conf.Tenancy, tenancyField, tenancyDatabase)
+	}
 
-	hosts, err := conf.GetString(mongoHostsKey)
+	ids, err := newIDStrategy(conf.IDStrategy)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Fail to get mongo hosts from configuration key (%s)", mongoHostsKey)
+		return nil, err
 	}
 
-	username, err := conf.GetString(mongoUsernameKey)
+	indexes, err := parseIndexSpecs(conf.Indexes.Declared)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Fail to get mongo username from configuration key (%s)", mongoUsernameKey)
+		return nil, err
+	}
+
+	migrations := newMigrations(tenants.DefaultTenant())
+	if err := validateMigrations(migrations); err != nil {
+		return nil, err
 	}
 
-	password, err := conf.GetString(mongoPasswordKey)
+	credential, err := resolveCredential(ctx, conf, secrets)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Fail to get mongo password from configuration key (%s)", mongoPasswordKey)
+		return nil, err
 	}
 
-	database, err := conf.GetString(mongoDatabaseKey)
+	o, err := clientOptions(ctx, conf, secrets)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Fail to get mongo database from configuration key (%s)", mongoDatabaseKey)
+		return nil, err
 	}
 
-	collection, err := conf.GetString(mongoCollectionKey)
+	client, err := dial(ctx, o, credential)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Fail to get mongo collection from configuration key (%s)", mongoCollectionKey)
+		return nil, err
 	}
 
-	o.SetHosts(strings.Split(hosts, ","))
-	o.SetAuth(options.Credential{
-		Username: username,
-		Password: password,
-	})
+	m := &MongoDB{
+		client:                 client,
+		clientOptions:          o,
+		credential:             credential,
+		database:               conf.Database,
+		collectionName:         conf.Collection,
+		strategy:               conf.Tenancy,
+		ids:                    ids,
+		uniqueNames:            conf.UniqueNames,
+		indexes:                indexes,
+		dropUndeclared:         conf.Indexes.DropUndeclared,
+		migrations:             migrations,
+		migrationLockTTL:       conf.Migrations.LockTTL,
+		lockOwner:              newLockOwner(),
+		idempotencyCollection:  conf.Idempotency.Collection,
+		idempotencyTTL:         conf.Idempotency.TTL,
+		idempotencyReservation: conf.Idempotency.ReservationTimeout,
+		startup:                newStartup(conf.Startup),
+	}
 
-	client, err := mongo.Connect(ctx, o)
-	if err != nil {
-		return nil, errors.Wrap(err, "Fail to connect to mongodb")
+	if conf.Startup.WaitForDatabase {
+		if err := m.waitForDatabase(ctx); err != nil {
+			_ = client.Disconnect(context.Background())
+			return nil, err
+		}
+	} else {
+		m.connectInBackground()
+	}
+
+	m.watchCredential(conf, secrets)
+
+	return m, nil
+}
+
+// scope returns the tenant scope of the tenant in ctx.
+// With a database per tenant, the indexes of the tenant database are created on its first use
+func (m *MongoDB) scope(ctx context.Context) (tenantScope, error) {
+	if err := m.checkReady(); err != nil {
+		return tenantScope{}, err
+	}
+
+	tenant, ok := tenancy.FromContext(ctx)
+	if !ok {
+		return tenantScope{}, errors.New("Missing tenant for mongodb operation").SetType(errors.ErrorTypeInternal)
+	}
+
+	if m.strategy == tenancyField {
+		return m.newScope(tenant, m.currentClient().Database(m.database), true), nil
+	}
+
+	s := m.newScope(tenant, m.currentClient().Database(m.database+"_"+tenant), false)
+	if err := m.ensureIndexes(ctx, s); err != nil {
+		return tenantScope{}, err
+	}
+
+	return s, nil
+}
+
+func (m *MongoDB) newScope(tenant string, db *mongo.Database, field bool) tenantScope {
+	return tenantScope{
+		tenant:      tenant,
+		database:    db,
+		collection:  db.Collection(m.collectionName),
+		idempotency: db.Collection(m.idempotencyCollection),
+		field:       field,
+	}
+}
+
+// ensureIndexes creates the indexes of the collections of scope once per database
+func (m *MongoDB) ensureIndexes(ctx context.Context, s tenantScope) error {
+	if _, ok := m.indexedDatabases.Load(s.database.Name()); ok {
+		return nil
+	}
+
+	if s.field {
+		index := mongo.IndexModel{Keys: bson.D{{Key: tenantField, Value: 1}}}
+		if _, err := s.collection.Indexes().CreateOne(ctx, index); err != nil {
+			return errors.Wrapf(err, "Fail to create tenant index in database (%s)", s.database.Name())
+		}
+	}
+
+	if m.uniqueNames {
+		keys := bson.D{{Key: nameField, Value: 1}}
+		if s.field {
+			keys = bson.D{{Key: tenantField, Value: 1}, {Key: nameField, Value: 1}}
+		}
+
+		index := mongo.IndexModel{Keys: keys, Options: options.Index().SetUnique(true)}
+		if _, err := s.collection.Indexes().CreateOne(ctx, index); err != nil {
+			return errors.Wrapf(err, "Fail to create unique name index in database (%s)", s.database.Name())
+		}
+	}
+
+	ttl := mongo.IndexModel{
+		Keys:    bson.D{{Key: idempotencyCreatedAtField, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(m.idempotencyTTL.Seconds())),
+	}
+	if _, err := s.idempotency.Indexes().CreateOne(ctx, ttl); err != nil {
+		return errors.Wrapf(err, "Fail to create idempotency ttl index in database (%s)", s.database.Name())
+	}
+
+	m.indexedDatabases.Store(s.database.Name(), struct{}{})
+	return nil
+}
+
+// filter adds the tenant condition to f when tenants share a collection
+func (s tenantScope) filter(f map[string]interface{}) map[string]interface{} {
+	if s.field {
+		f[tenantField] = s.tenant
 	}
 
-	if err := client.Ping(ctx, nil); err != nil {
-		return nil, errors.Wrap(err, "Fail to ping to mongodb server")
+	return f
+}
+
+// documentID returns the _id a document of the given id is stored under. When tenants share a collection the _id is
+// compound of the tenant and the id, so every tenant has ids of its own and inserts never collide across tenants
+func (s tenantScope) documentID(id interface{}) interface{} {
+	if s.field {
+		return bson.D{{Key: tenantField, Value: s.tenant}, {Key: storedIDField, Value: id}}
 	}
 
-	return &MongoDB{
-		client:     client,
-		collection: client.Database(database).Collection(collection),
-	}, nil
+	return id
 }
 
-// GetDocumentByID get document by ID from mongodb, and put it in the parameter 'result'.
+// GetDocumentByID get document by ID from mongodb, and put the fields selected by opts in the parameter 'result'.
 // Note that result should be a pointer the the desired type
-func (m *MongoDB) GetDocumentByID(ctx context.Context, id string, result interface{}) error {
-	objID, err := primitive.ObjectIDFromHex(id)
+func (m *MongoDB) GetDocumentByID(ctx context.Context, id string, opts models.ReadOptions, result interface{}) error {
+	objID, err := m.ids.parseID(id)
+	if err != nil {
+		return err
+	}
+
+	scope, err := m.scope(ctx)
 	if err != nil {
-		return errors.Errorf("id (%s) is not a valid ObjectID", id).SetType(errors.ErrorTypeBadRequest)
+		return err
 	}
 
-	s := m.collection.FindOne(ctx, map[string]interface{}{"_id": objID})
+	o := options.FindOne()
+	if p := projection(opts); p != nil {
+		o.SetProjection(p)
+	}
+
+	s := scope.collection.FindOne(ctx, scope.filter(map[string]interface{}{"_id": scope.documentID(objID)}), o)
 	if err := s.Err(); err != nil {
 		if err == mongo.ErrNoDocuments {
 			return errors.Errorf("Document with id (%s) was not found in mongodb", id).SetType(errors.ErrorTypeNotFound)
@@ -110,21 +281,255 @@ func (m *MongoDB) GetDocumentByID(ctx context.Context, id string, result interfa
 	return nil
 }
 
-// SaveDocument add document to mongodb, return the id of the document
+// projection returns the projection of the fields selected by opts, nil when all fields are read.
+// Selecting fields keeps the modification time, which reads report along with the fields
+func projection(opts models.ReadOptions) bson.D {
+	var p bson.D
+	switch {
+	case len(opts.Fields) > 0:
+		for _, path := range opts.Fields {
+			p = append(p, bson.E{Key: path, Value: 1})
+		}
+		p = append(p, bson.E{Key: modifiedAtField, Value: 1})
+	case len(opts.Exclude) > 0:
+		for _, path := range opts.Exclude {
+			p = append(p, bson.E{Key: path, Value: 0})
+		}
+	}
+
+	return p
+}
+
+// storedDocument is a document together with the _id it is stored under
+type storedDocument struct {
+	ID              interface{} `bson:"_id"`
+	models.Document `bson:",inline"`
+}
+
+// GetDocumentByName get the document with the given name from mongodb, and put it in the parameter 'result'.
+// Note that result should be a pointer the the desired type
+func (m *MongoDB) GetDocumentByName(ctx context.Context, name string, result interface{}) error {
+	scope, err := m.scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	s := scope.collection.FindOne(ctx, scope.filter(map[string]interface{}{nameField: name}))
+	if err := s.Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.Errorf("Document with name (%s) was not found in mongodb", name).SetType(errors.ErrorTypeNotFound)
+		}
+
+		return errors.Wrapf(err, "Failed to find document with name (%s) in mongodb", name).SetType(errors.ErrorTypeInternal)
+	}
+
+	if err := s.Decode(result); err != nil {
+		return errors.Wrapf(err, "Failed to decode document to result type (%s)", reflect.TypeOf(result)).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	return nil
+}
+
+// UpsertDocumentByName replaces the content of the document with the name of doc, or adds doc if there is no such document.
+// It returns the id of the document and whether it was created
+func (m *MongoDB) UpsertDocumentByName(ctx context.Context, doc models.Document) (string, bool, error) {
+	scope, err := m.scope(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	for i := 0; i < upsertAttempts; i++ {
+		newID, err := m.ids.newID()
+		if err != nil {
+			return "", false, err
+		}
+
+		objID, err := m.ids.parseID(newID)
+		if err != nil {
+			return "", false, err
+		}
+
+		filter := scope.filter(map[string]interface{}{nameField: doc.Name})
+		update := map[string]interface{}{
+			"$set":         map[string]interface{}{"doc": doc.Doc, modifiedAtField: time.Now().UTC()},
+			"$setOnInsert": map[string]interface{}{"_id": scope.documentID(objID), "createdby": doc.CreatedBy},
+			"$inc":         map[string]interface{}{"version": 1},
+		}
+		opts := options.FindOneAndUpdate().
+			SetUpsert(true).
+			SetReturnDocument(options.Before).
+			SetProjection(map[string]interface{}{"_id": 1})
+
+		var previous struct {
+			ID interface{} `bson:"_id"`
+		}
+		err = scope.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&previous)
+		if err == mongo.ErrNoDocuments {
+			return newID, true, nil
+		}
+
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+
+		if err != nil {
+			return "", false, errors.Wrapf(err, "Failed to upsert document with name (%s) in mongodb", doc.Name).SetType(errors.ErrorTypeInternal)
+		}
+
+		return formatID(previous.ID), false, nil
+	}
+
+	return "", false, errors.Errorf("Document with name (%s) was modified concurrently", doc.Name).SetType(errors.ErrorTypeConflict)
+}
+
+// formatID returns the string representation of the id of a stored _id
+func formatID(id interface{}) string {
+	if inner, ok := compoundID(id); ok {
+		id = inner
+	}
+
+	if objID, ok := id.(primitive.ObjectID); ok {
+		return objID.Hex()
+	}
+
+	return fmt.Sprint(id)
+}
+
+// compoundID returns the id within a compound _id of a shared collection
+func compoundID(id interface{}) (interface{}, bool) {
+	switch id := id.(type) {
+	case primitive.D:
+		for _, e := range id {
+			if e.Key == storedIDField {
+				return e.Value, true
+			}
+		}
+	case primitive.M:
+		inner, ok := id[storedIDField]
+		return inner, ok
+	}
+
+	return nil, false
+}
+
+// SaveDocument add document to mongodb with an id generated by the id strategy, return the id of the document
 func (m *MongoDB) SaveDocument(ctx context.Context, doc models.Document) (string, error) {
-	res, err := m.collection.InsertOne(ctx, doc)
+	id, err := m.ids.newID()
 	if err != nil {
-		return "", errors.Wrapf(err, "Failed to insert document (%v) to mongodb", doc).SetType(errors.ErrorTypeInternal)
+		return "", err
 	}
 
-	id := res.InsertedID.(primitive.ObjectID)
+	if err := m.SaveDocumentWithID(ctx, id, doc); err != nil {
+		return "", err
+	}
 
-	return id.Hex(), nil
+	return id, nil
+}
+
+// SaveDocumentWithID add document to mongodb under the given id.
+// It returns a conflict error if a document of the tenant with that id already exists
+func (m *MongoDB) SaveDocumentWithID(ctx context.Context, id string, doc models.Document) error {
+	objID, err := m.ids.parseID(id)
+	if err != nil {
+		return err
+	}
+
+	scope, err := m.scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	doc.ModifiedAt = time.Now().UTC()
+	doc.Tenant = ""
+	if scope.field {
+		doc.Tenant = scope.tenant
+	}
+
+	if _, err := scope.collection.InsertOne(ctx, storedDocument{ID: scope.documentID(objID), Document: doc}); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.Errorf("Document with id (%s) already exists", id).SetType(errors.ErrorTypeConflict)
+		}
+
+		return errors.Wrapf(err, "Failed to insert document (%v) to mongodb", doc).SetType(errors.ErrorTypeInternal)
+	}
+
+	return nil
+}
+
+// UpdateDocument replaces the name and content of the document with the given id and increments its version.
+// The update only applies if the stored version matches the version of doc, otherwise a conflict error is returned
+func (m *MongoDB) UpdateDocument(ctx context.Context, id string, doc models.Document) error {
+	objID, err := m.ids.parseID(id)
+	if err != nil {
+		return err
+	}
+
+	scope, err := m.scope(ctx)
+	if err != nil {
+		return err
+	}
+
+	filter := scope.filter(map[string]interface{}{
+		"_id":     scope.documentID(objID),
+		"version": versionFilter(doc.Version),
+	})
+	update := map[string]interface{}{
+		"$set": map[string]interface{}{"name": doc.Name, "doc": doc.Doc, modifiedAtField: time.Now().UTC()},
+		"$inc": map[string]interface{}{"version": 1},
+	}
+
+	res, err := scope.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to update document with id (%s) in mongodb", id).SetType(errors.ErrorTypeInternal)
+	}
+
+	if res.MatchedCount == 0 {
+		count, err := scope.collection.CountDocuments(ctx, scope.filter(map[string]interface{}{"_id": scope.documentID(objID)}))
+		if err != nil {
+			return errors.Wrapf(err, "Failed to find document with id (%s) in mongodb", id).SetType(errors.ErrorTypeInternal)
+		}
+
+		if count == 0 {
+			return errors.Errorf("Document with id (%s) was not found in mongodb", id).SetType(errors.ErrorTypeNotFound)
+		}
+
+		return errors.Errorf("Document with id (%s) was modified concurrently", id).SetType(errors.ErrorTypeConflict)
+	}
+
+	return nil
+}
+
+// CountDocuments returns the number of documents of the tenant
+func (m *MongoDB) CountDocuments(ctx context.Context) (int64, error) {
+	scope, err := m.scope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := scope.collection.CountDocuments(ctx, scope.filter(map[string]interface{}{}))
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed to count documents of tenant (%s) in mongodb", scope.tenant).SetType(errors.ErrorTypeInternal)
+	}
+
+	return count, nil
+}
+
+// versionFilter matches the given version. Documents stored before versioning was introduced have no version field
+func versionFilter(version int64) interface{} {
+	if version == 0 {
+		return map[string]interface{}{"$in": []interface{}{0, nil}}
+	}
+
+	return version
 }
 
 // Teardown disconnect from mongodb client
 func (m *MongoDB) Teardown(ctx context.Context) error {
-	if err := m.client.Disconnect(ctx); err != nil {
+	for _, stop := range m.stopWatches {
+		stop()
+	}
+
+	if err := m.currentClient().Disconnect(ctx); err != nil {
 		return errors.Wrap(err, "Failed to close mongodb connections")
 	}
 
@@ -0,0 +1,160 @@
+package mongodb
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/errors"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+const writeConcernMajority = "majority"
+
+// clientOptions returns the options of the connection to mongodb, except for the credentials.
+// Options given in configuration override those of the connection string
+func clientOptions(ctx context.Context, conf config.Mongo, secrets Secrets) (*options.ClientOptions, error) {
+	o := options.Client()
+
+	if conf.URI != "" {
+		uri, err := secrets.Resolve(ctx, conf.URI)
+		if err != nil {
+			return nil, errors.Wrap(err, "Fail to resolve mongo uri")
+		}
+
+		if err := o.ApplyURI(uri).Validate(); err != nil {
+			return nil, errors.Wrap(err, "Invalid mongo uri")
+		}
+	} else {
+		o.SetHosts(strings.Split(conf.Hosts, ","))
+	}
+
+	if conf.ReplicaSet != "" {
+		o.SetReplicaSet(conf.ReplicaSet)
+	}
+
+	if conf.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(conf.ReadPreference)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid mongo read preference (%s)", conf.ReadPreference)
+		}
+
+		rp, err := readpref.New(mode)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid mongo read preference (%s)", conf.ReadPreference)
+		}
+		o.SetReadPreference(rp)
+	}
+
+	if conf.ReadConcern != "" {
+		o.SetReadConcern(readconcern.New(readconcern.Level(conf.ReadConcern)))
+	}
+
+	if wc, err := writeConcern(conf.WriteConcern); err != nil {
+		return nil, err
+	} else if wc != nil {
+		o.SetWriteConcern(wc)
+	}
+
+	if conf.TLS.Enabled {
+		tlsConfig, err := newTLSConfig(conf.TLS)
+		if err != nil {
+			return nil, err
+		}
+		o.SetTLSConfig(tlsConfig)
+	}
+
+	if conf.Pool.MinSize > 0 {
+		o.SetMinPoolSize(uint64(conf.Pool.MinSize))
+	}
+
+	if conf.Pool.MaxSize > 0 {
+		o.SetMaxPoolSize(uint64(conf.Pool.MaxSize))
+	}
+
+	if conf.Pool.MaxIdleTime > 0 {
+		o.SetMaxConnIdleTime(conf.Pool.MaxIdleTime)
+	}
+
+	if conf.Timeouts.ServerSelection > 0 {
+		o.SetServerSelectionTimeout(conf.Timeouts.ServerSelection)
+	}
+
+	if conf.Timeouts.Connect > 0 {
+		o.SetConnectTimeout(conf.Timeouts.Connect)
+	}
+
+	if conf.Timeouts.Socket > 0 {
+		o.SetSocketTimeout(conf.Timeouts.Socket)
+	}
+
+	return o, nil
+}
+
+// writeConcern returns nil when the write concern is left to the server
+func writeConcern(conf config.MongoWriteConcern) (*writeconcern.WriteConcern, error) {
+	var opts []writeconcern.Option
+
+	switch conf.W {
+	case "":
+	case writeConcernMajority:
+		opts = append(opts, writeconcern.WMajority())
+	default:
+		w, err := strconv.Atoi(conf.W)
+		if err != nil || w < 0 {
+			return nil, errors.Errorf("Invalid mongo write concern (%s), expected a number of nodes or (%s)", conf.W, writeConcernMajority)
+		}
+		opts = append(opts, writeconcern.W(w))
+	}
+
+	if conf.Journal {
+		opts = append(opts, writeconcern.J(true))
+	}
+
+	if conf.Timeout > 0 {
+		opts = append(opts, writeconcern.WTimeout(conf.Timeout))
+	}
+
+	if len(opts) == 0 {
+		return nil, nil
+	}
+
+	return writeconcern.New(opts...), nil
+}
+
+func newTLSConfig(conf config.MongoTLS) (*tls.Config, error) {
+	c := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: conf.InsecureSkipVerify,
+	}
+
+	if conf.CAFile != "" {
+		pem, err := ioutil.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to read mongo tls ca file (%s)", conf.CAFile)
+		}
+
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("No certificates in mongo tls ca file (%s)", conf.CAFile)
+		}
+	}
+
+	if conf.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to load mongo tls client certificate (%s)", conf.CertFile)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+
+	return c, nil
+}
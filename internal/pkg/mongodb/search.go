@@ -0,0 +1,156 @@
+package mongodb
+
+import (
+	"context"
+	"regexp"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// scoreField holds the relevance of the documents found by a text search
+	scoreField = "score"
+
+	// indexNotFoundCode is the code of the error of text searches of a collection without a text index
+	indexNotFoundCode = 27
+)
+
+// filterFieldPattern accepts the field paths inside the content of a document that filters may test
+var filterFieldPattern = regexp.MustCompile(`^doc(\.[^.$\x00][^.\x00]*)+$`)
+
+// searchedDocument is a document found by a search, with the _id it is stored under and its relevance
+type searchedDocument struct {
+	ID              interface{} `bson:"_id"`
+	Score           float64     `bson:"score"`
+	models.Document `bson:",inline"`
+}
+
+// SearchDocuments returns the fields selected by query of the documents of the tenant that match the text and the
+// filter of query. Documents matching a text are sorted by relevance, which requires a text index on the collection,
+// and the others by id
+func (m *MongoDB) SearchDocuments(ctx context.Context, query models.SearchQuery) ([]models.SearchResult, error) {
+	f := map[string]interface{}{}
+	if query.Filter != nil {
+		filter, err := searchFilter(*query.Filter)
+		if err != nil {
+			return nil, err
+		}
+		f["$and"] = bson.A{filter}
+	}
+
+	if query.Text != "" {
+		f["$text"] = bson.D{{Key: "$search", Value: query.Text}}
+	}
+	o := searchOptions(query)
+
+	scope, err := m.scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := scope.collection.Find(ctx, scope.filter(f), o)
+	if err != nil {
+		if ce, ok := err.(mongo.CommandError); ok && ce.HasErrorCode(indexNotFoundCode) {
+			return nil, errors.Wrap(err, "Searching documents by text requires a text index, declare one in mongo.indexes.declared").SetType(errors.ErrorTypeUnprocessable)
+		}
+
+		return nil, errors.Wrapf(err, "Failed to search documents of tenant (%s) in mongodb", scope.tenant).SetType(errors.ErrorTypeInternal)
+	}
+
+	var found []searchedDocument
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode documents found in mongodb").SetType(errors.ErrorTypeInternal)
+	}
+
+	results := make([]models.SearchResult, len(found))
+	for i, doc := range found {
+		results[i] = models.SearchResult{ID: formatID(doc.ID), Score: doc.Score, Document: doc.Document}
+	}
+
+	return results, nil
+}
+
+// searchOptions returns the limit, order and projection of the results of query. Results of a text search are
+// sorted by their relevance, which is projected along with the fields selected by query
+func searchOptions(query models.SearchQuery) *options.FindOptions {
+	o := options.Find().SetLimit(int64(query.Limit))
+	p := projection(query.ReadOptions)
+	if query.Text != "" {
+		score := bson.D{{Key: "$meta", Value: "textScore"}}
+		p = append(p, bson.E{Key: scoreField, Value: score})
+		o.SetSort(bson.D{{Key: scoreField, Value: score}})
+	} else {
+		o.SetSort(bson.D{{Key: "_id", Value: 1}})
+	}
+
+	if p != nil {
+		o.SetProjection(p)
+	}
+
+	return o
+}
+
+// searchFilter translates a filter to the mongodb filter of the same condition. Only the operators of the filter
+// language are emitted, fields are checked to be paths inside the content of documents, and values are compared
+// with $eq, $in and the range operators, so values that look like operators are matched as they are
+func searchFilter(f models.Filter) (bson.D, error) {
+	switch {
+	case len(f.And) > 0:
+		filters, err := searchFilters(f.And)
+		return bson.D{{Key: "$and", Value: filters}}, err
+	case len(f.Or) > 0:
+		filters, err := searchFilters(f.Or)
+		return bson.D{{Key: "$or", Value: filters}}, err
+	case f.Not != nil:
+		// $not only negates operators of a field, $nor negates a whole filter
+		filters, err := searchFilters([]models.Filter{*f.Not})
+		return bson.D{{Key: "$nor", Value: filters}}, err
+	}
+
+	if !filterFieldPattern.MatchString(f.Field) {
+		return nil, errors.Errorf("Invalid filter field (%s), expected a path in doc", f.Field).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	var condition bson.D
+	switch {
+	case f.Eq != nil:
+		condition = bson.D{{Key: "$eq", Value: f.Eq}}
+	case len(f.In) > 0:
+		condition = bson.D{{Key: "$in", Value: bson.A(f.In)}}
+	case f.Exists != nil:
+		condition = bson.D{{Key: "$exists", Value: *f.Exists}}
+	default:
+		for _, bound := range []struct {
+			operator string
+			value    interface{}
+		}{{"$gt", f.Gt}, {"$gte", f.Gte}, {"$lt", f.Lt}, {"$lte", f.Lte}} {
+			if bound.value != nil {
+				condition = append(condition, bson.E{Key: bound.operator, Value: bound.value})
+			}
+		}
+	}
+
+	if len(condition) == 0 {
+		return nil, errors.Errorf("Filter of field (%s) has no condition", f.Field).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	return bson.D{{Key: f.Field, Value: condition}}, nil
+}
+
+func searchFilters(filters []models.Filter) (bson.A, error) {
+	translated := make(bson.A, len(filters))
+	for i, f := range filters {
+		filter, err := searchFilter(f)
+		if err != nil {
+			return nil, err
+		}
+		translated[i] = filter
+	}
+
+	return translated, nil
+}
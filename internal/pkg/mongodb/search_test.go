@@ -0,0 +1,67 @@
+package mongodb
+
+import (
+	"reflect"
+	"testing"
+
+	"microservice/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func Test_searchOptions(t *testing.T) {
+	score := bson.D{{Key: "$meta", Value: "textScore"}}
+
+	tests := []struct {
+		name           string
+		query          models.SearchQuery
+		wantProjection interface{}
+		wantSort       interface{}
+	}{
+		{
+			name:     "filter search of whole documents expect no projection and sorted by id",
+			query:    models.SearchQuery{Filter: &models.Filter{Field: "doc.age", Eq: 18}, Limit: 5},
+			wantSort: bson.D{{Key: "_id", Value: 1}},
+		},
+		{
+			name:           "text search of whole documents expect score projected and sorted by score",
+			query:          models.SearchQuery{Text: "aviv", Limit: 5},
+			wantProjection: bson.D{{Key: scoreField, Value: score}},
+			wantSort:       bson.D{{Key: scoreField, Value: score}},
+		},
+		{
+			name:  "text search of selected fields expect fields, modification time and score projected",
+			query: models.SearchQuery{Text: "aviv", Limit: 5, ReadOptions: models.ReadOptions{Fields: []string{"name", "doc.city"}}},
+			wantProjection: bson.D{
+				{Key: "name", Value: 1},
+				{Key: "doc.city", Value: 1},
+				{Key: modifiedAtField, Value: 1},
+				{Key: scoreField, Value: score},
+			},
+			wantSort: bson.D{{Key: scoreField, Value: score}},
+		},
+		{
+			name:           "filter search excluding fields expect excluded fields projected out",
+			query:          models.SearchQuery{Filter: &models.Filter{Field: "doc.age", Eq: 18}, Limit: 5, ReadOptions: models.ReadOptions{Exclude: []string{"doc.history"}}},
+			wantProjection: bson.D{{Key: "doc.history", Value: 0}},
+			wantSort:       bson.D{{Key: "_id", Value: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := searchOptions(tt.query)
+
+			if o.Limit == nil || *o.Limit != int64(tt.query.Limit) {
+				t.Errorf("searchOptions() limit = %v, want %v", o.Limit, tt.query.Limit)
+			}
+
+			if !reflect.DeepEqual(o.Projection, tt.wantProjection) {
+				t.Errorf("searchOptions() projection = %v, want %v", o.Projection, tt.wantProjection)
+			}
+
+			if !reflect.DeepEqual(o.Sort, tt.wantSort) {
+				t.Errorf("searchOptions() sort = %v, want %v", o.Sort, tt.wantSort)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package mongodb
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"microservice/internal/pkg/backoff"
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	healthCheckName            = "mongodb"
+	credentialsHealthCheckName = "mongodb.credentials"
+
+	// healthCheckTimeout bounds the ping of a health check
+	healthCheckTimeout = 2 * time.Second
+)
+
+// startup holds how the initial connection to mongodb is retried
+type startup struct {
+	attemptTimeout time.Duration
+	backoff        backoff.Policy
+}
+
+func newStartup(conf config.MongoStartup) startup {
+	return startup{
+		attemptTimeout: conf.AttemptTimeout,
+		backoff:        backoff.Policy(conf.Backoff),
+	}
+}
+
+// dial returns a client of the server in o authenticated with credential, without waiting for the server to answer
+func dial(ctx context.Context, o *options.ClientOptions, credential options.Credential) (*mongo.Client, error) {
+	o = options.MergeClientOptions(o)
+	if credential.Username != "" || credential.AuthMechanism != "" {
+		o.SetAuth(credential)
+	}
+
+	client, err := mongo.Connect(ctx, o)
+	if err != nil {
+		return nil, errors.Wrap(err, "Fail to connect to mongodb")
+	}
+
+	return client, nil
+}
+
+// waitForDatabase pings mongodb until it answers and then prepares the database for the service.
+// Failed attempts are retried after the backoff delay until ctx is done
+func (m *MongoDB) waitForDatabase(ctx context.Context) error {
+	for attempt := 0; ; attempt++ {
+		err := m.prepare(ctx)
+		if err == nil {
+			m.setReady(nil)
+			return nil
+		}
+		m.setReady(err)
+
+		if werr := m.startup.backoff.Wait(ctx, attempt); werr != nil {
+			return errors.Wrapf(err, "Gave up connecting to mongodb after (%d) attempts", attempt+1).SetType(errors.ErrorTypeUnavailable)
+		}
+	}
+}
+
+// connectInBackground waits for the database until it is ready or the client is torn down
+func (m *MongoDB) connectInBackground() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.stopWatches = append(m.stopWatches, cancel)
+
+	go func() {
+		_ = m.waitForDatabase(ctx)
+	}()
+}
+
+// prepare makes a single attempt, bounded by the attempt timeout, to ping mongodb and ensure the shared indexes
+func (m *MongoDB) prepare(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, m.startup.attemptTimeout)
+	defer cancel()
+
+	if err := m.currentClient().Ping(ctx, nil); err != nil {
+		return errors.Wrap(err, "Fail to ping to mongodb server")
+	}
+
+	if m.strategy == tenancyField {
+		return m.ensureIndexes(ctx, m.newScope("", m.currentClient().Database(m.database), true))
+	}
+
+	return nil
+}
+
+// setReady marks the database ready when err is nil, otherwise err is kept as the reason it isn't ready
+func (m *MongoDB) setReady(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.connectErr = err
+	if err == nil {
+		atomic.StoreInt32(&m.ready, 1)
+	}
+}
+
+// checkReady fails fast while the database isn't ready yet
+func (m *MongoDB) checkReady() error {
+	if atomic.LoadInt32(&m.ready) == 1 {
+		return nil
+	}
+
+	return errors.New("Mongodb is not reachable yet").SetType(errors.ErrorTypeUnavailable)
+}
+
+// CheckHealth reports whether mongodb is ready to serve requests, and whether rotating its credentials fails
+func (m *MongoDB) CheckHealth(ctx context.Context) []models.HealthCheck {
+	return []models.HealthCheck{m.checkConnection(ctx), m.checkCredentials()}
+}
+
+// checkConnection reports whether mongodb was prepared and answers a ping
+func (m *MongoDB) checkConnection(ctx context.Context) models.HealthCheck {
+	check := models.HealthCheck{Name: healthCheckName}
+
+	if atomic.LoadInt32(&m.ready) == 0 {
+		m.mu.RLock()
+		connectErr := m.connectErr
+		m.mu.RUnlock()
+
+		check.Details = map[string]interface{}{"state": "connecting"}
+		if connectErr != nil {
+			check.Details["error"] = connectErr.Error()
+		}
+
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := m.currentClient().Ping(ctx, nil); err != nil {
+		check.Details = map[string]interface{}{"state": "unreachable", "error": err.Error()}
+		return check
+	}
+
+	check.Ready = true
+	check.Details = map[string]interface{}{"state": "connected", "latency": time.Since(start).String()}
+
+	return check
+}
+
+// checkCredentials reports the last failure to rotate the credentials. The client stays authenticated with the
+// previous credentials until a rotation succeeds, so a failing rotation doesn't make the service unready
+func (m *MongoDB) checkCredentials() models.HealthCheck {
+	m.mu.RLock()
+	rotationErr := m.rotationErr
+	m.mu.RUnlock()
+
+	check := models.HealthCheck{Name: credentialsHealthCheckName, Ready: true, Details: map[string]interface{}{"state": "current"}}
+	if rotationErr != nil {
+		check.Details = map[string]interface{}{"state": "rotationFailing", "error": rotationErr.Error()}
+	}
+
+	return check
+}
@@ -0,0 +1,219 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"microservice/internal/pkg/auth"
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/errors"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	rateLimitBaseKey    = "rateLimit"
+	rateLimitEnabledKey = rateLimitBaseKey + ".enabled"
+	rateLimitKeyByKey   = rateLimitBaseKey + ".keyBy"
+	rateLimitRoutesKey  = rateLimitBaseKey + ".routes"
+
+	// KeyByAPIKey limits each api key separately
+	KeyByAPIKey = "apiKey"
+
+	// KeyByPrincipal limits each authenticated principal separately
+	KeyByPrincipal = "principal"
+
+	// KeyByIP limits each client address separately
+	KeyByIP = "ip"
+
+	defaultRoute = "default"
+	rateField    = "rate"
+	burstField   = "burst"
+
+	apiKeyHeader = "X-API-Key"
+)
+
+// Configuration expose an interface of configuration related actions
+type Configuration interface {
+	Get(key string) interface{}
+	GetString(key string) (string, error)
+	GetBool(key string) (bool, error)
+	IsSet(key string) bool
+	Subscribe(keys []string, handler config.ChangeHandler)
+}
+
+// settingsReader reads the rate limit settings from the current or a candidate configuration
+type settingsReader interface {
+	Get(key string) interface{}
+	GetString(key string) (string, error)
+	GetBool(key string) (bool, error)
+}
+
+// Service applies the configured per route limits to clients
+type Service struct {
+	store Store
+
+	mu       sync.RWMutex
+	settings settings
+}
+
+// settings are the rate limits, which are replaced when the configuration is reloaded
+type settings struct {
+	enabled bool
+	keyBy   string
+	limits  map[string]Limit
+}
+
+// NewService returns a new instance of the Service struct. The limits follow reloads of the configuration
+func NewService(conf Configuration, store Store) (*Service, error) {
+	st, err := readSettings(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		store:    store,
+		settings: st,
+	}
+
+	conf.Subscribe([]string{rateLimitBaseKey}, s.reload)
+
+	return s, nil
+}
+
+// reload prepares the replacement of the limits by those of candidate
+func (s *Service) reload(candidate config.Values) (func(), error) {
+	st, err := readSettings(candidate)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.settings = st
+	}, nil
+}
+
+func readSettings(conf settingsReader) (settings, error) {
+	enabled, err := conf.GetBool(rateLimitEnabledKey)
+	if err != nil {
+		return settings{}, errors.Wrapf(err, "Fail to get rate limit enabled flag from configuration key (%s)", rateLimitEnabledKey)
+	}
+
+	st := settings{
+		enabled: enabled,
+		limits:  make(map[string]Limit),
+	}
+
+	if !enabled {
+		return st, nil
+	}
+
+	if st.keyBy, err = conf.GetString(rateLimitKeyByKey); err != nil {
+		return settings{}, errors.Wrapf(err, "Fail to get rate limit key from configuration key (%s)", rateLimitKeyByKey)
+	}
+
+	if st.keyBy != KeyByAPIKey && st.keyBy != KeyByPrincipal && st.keyBy != KeyByIP {
+		return settings{}, errors.Errorf("Invalid rate limit key (%s), expected (%s), (%s) or (%s)", st.keyBy, KeyByAPIKey, KeyByPrincipal, KeyByIP)
+	}
+
+	routes, err := cast.ToStringMapE(conf.Get(rateLimitRoutesKey))
+	if err != nil {
+		return settings{}, errors.Wrapf(err, "Invalid route limits in configuration key (%s)", rateLimitRoutesKey)
+	}
+
+	for route, v := range routes {
+		limit, err := parseLimit(v)
+		if err != nil {
+			return settings{}, errors.Wrapf(err, "Invalid limit of route (%s) in configuration key (%s)", route, rateLimitRoutesKey)
+		}
+		st.limits[route] = limit
+	}
+
+	if _, ok := st.limits[defaultRoute]; !ok {
+		return settings{}, errors.Errorf("Missing (%s) limit in configuration key (%s)", defaultRoute, rateLimitRoutesKey)
+	}
+
+	return st, nil
+}
+
+// Allow takes a token of the client of the request from the bucket of the route.
+// Routes without a limit of their own use the default limit. The returned bool is false when rate limiting is disabled
+func (s *Service) Allow(r *http.Request, route string) (Result, bool, error) {
+	s.mu.RLock()
+	st := s.settings
+	s.mu.RUnlock()
+
+	if !st.enabled {
+		return Result{}, false, nil
+	}
+
+	limit, ok := st.limits[strings.ToLower(route)]
+	if !ok {
+		limit = st.limits[defaultRoute]
+	}
+
+	res, err := s.store.Take(r.Context(), route+":"+clientKey(r, st.keyBy), limit)
+	if err != nil {
+		return Result{}, false, errors.Wrapf(err, "Failed to take rate limit token of route (%s)", route)
+	}
+
+	return res, true, nil
+}
+
+// clientKey identifies the client of the request by keyBy. Clients that are not authenticated yet, or have no
+// api key or principal, are identified by their address, as their credentials may be made up
+func clientKey(r *http.Request, keyBy string) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		switch keyBy {
+		case KeyByAPIKey:
+			if key := r.Header.Get(apiKeyHeader); key != "" {
+				sum := sha256.Sum256([]byte(key))
+				return KeyByAPIKey + ":" + hex.EncodeToString(sum[:])
+			}
+		case KeyByPrincipal:
+			if p.Subject != "" {
+				return KeyByPrincipal + ":" + p.Subject
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return KeyByIP + ":" + host
+}
+
+func parseLimit(v interface{}) (Limit, error) {
+	fields, err := cast.ToStringMapE(v)
+	if err != nil {
+		return Limit{}, err
+	}
+
+	rate, err := cast.ToFloat64E(fields[rateField])
+	if err != nil {
+		return Limit{}, errors.Wrapf(err, "Invalid (%s)", rateField)
+	}
+
+	burst, err := cast.ToIntE(fields[burstField])
+	if err != nil {
+		return Limit{}, errors.Wrapf(err, "Invalid (%s)", burstField)
+	}
+
+	if rate <= 0 || burst < 1 {
+		return Limit{}, errors.New("Rate must be positive and burst at least 1")
+	}
+
+	return Limit{
+		Rate:  rate,
+		Burst: burst,
+	}, nil
+}
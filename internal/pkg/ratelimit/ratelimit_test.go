@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"microservice/internal/pkg/auth"
+	"microservice/models"
+)
+
+func Test_clientKey(t *testing.T) {
+	sum := sha256.Sum256([]byte("alice-key"))
+	alice := models.Principal{Subject: "alice"}
+
+	tests := []struct {
+		name      string
+		keyBy     string
+		apiKey    string
+		principal *models.Principal
+		want      string
+	}{
+		{
+			name:      "authenticated principal keyed by principal expect principal key",
+			keyBy:     KeyByPrincipal,
+			principal: &alice,
+			want:      KeyByPrincipal + ":alice",
+		},
+		{
+			name:      "authenticated api key keyed by api key expect hash of the api key",
+			keyBy:     KeyByAPIKey,
+			apiKey:    "alice-key",
+			principal: &alice,
+			want:      KeyByAPIKey + ":" + hex.EncodeToString(sum[:]),
+		},
+		{
+			name:   "unauthenticated api key keyed by api key expect address key",
+			keyBy:  KeyByAPIKey,
+			apiKey: "guessed-key",
+			want:   KeyByIP + ":192.0.2.1",
+		},
+		{
+			name:  "unauthenticated client keyed by principal expect address key",
+			keyBy: KeyByPrincipal,
+			want:  KeyByIP + ":192.0.2.1",
+		},
+		{
+			name:      "authenticated principal keyed by address expect address key",
+			keyBy:     KeyByIP,
+			principal: &alice,
+			want:      KeyByIP + ":192.0.2.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/documents", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			if tt.apiKey != "" {
+				r.Header.Set(apiKeyHeader, tt.apiKey)
+			}
+
+			if tt.principal != nil {
+				r = r.WithContext(auth.NewContext(r.Context(), *tt.principal))
+			}
+
+			if got := clientKey(r, tt.keyBy); got != tt.want {
+				t.Errorf("clientKey() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package resilience
+
+import (
+	"sync"
+	"time"
+
+	"microservice/internal/pkg/errors"
+)
+
+// State of a circuit breaker
+type State int32
+
+const (
+	// StateClosed lets every call through and counts consecutive failures
+	StateClosed State = iota
+	// StateOpen rejects every call until the open timeout elapses
+	StateOpen
+	// StateHalfOpen lets a limited number of trial calls through, which close or reopen the breaker
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerPolicy configures when a breaker opens and how it recovers
+type BreakerPolicy struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	HalfOpenMaxCalls int
+}
+
+// Breaker is a circuit breaker. It opens after consecutive failures so callers fail fast instead of waiting on a
+// dependency that doesn't answer, and lets trial calls through after the open timeout to find out if it recovered
+type Breaker struct {
+	policy BreakerPolicy
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trials   int
+	// generation counts the transitions, so the outcome of a call allowed before a transition is ignored
+	generation int
+
+	// onChange is called with the new state on every transition, while the breaker is locked
+	onChange func(s State)
+	now      func() time.Time
+}
+
+// NewBreaker returns a new closed instance of the Breaker struct
+func NewBreaker(policy BreakerPolicy, onChange func(s State)) *Breaker {
+	if onChange == nil {
+		onChange = func(State) {}
+	}
+
+	return &Breaker{
+		policy:   policy,
+		onChange: onChange,
+		now:      time.Now,
+	}
+}
+
+// Allow reports whether a call may go through. The caller must report the outcome of an allowed call to done.
+// A rejected call gets an error of type Unavailable
+func (b *Breaker) Allow() (done func(failed bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.policy.OpenTimeout {
+		b.transition(StateHalfOpen)
+	}
+
+	switch b.state {
+	case StateOpen:
+		return nil, errors.New("Circuit breaker is open").SetType(errors.ErrorTypeUnavailable)
+	case StateHalfOpen:
+		if b.trials >= b.policy.HalfOpenMaxCalls {
+			return nil, errors.New("Circuit breaker is half-open and busy with trial calls").SetType(errors.ErrorTypeUnavailable)
+		}
+		b.trials++
+
+		return b.report(b.generation, b.doneTrial), nil
+	default:
+		return b.report(b.generation, b.done), nil
+	}
+}
+
+// State returns the current state and the number of consecutive failures
+func (b *Breaker) State() (State, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state, b.failures
+}
+
+// report returns a function that passes the outcome of a call to fn, unless the breaker changed state since generation
+func (b *Breaker) report(generation int, fn func(failed bool)) func(failed bool) {
+	return func(failed bool) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if b.generation == generation {
+			fn(failed)
+		}
+	}
+}
+
+func (b *Breaker) done(failed bool) {
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.policy.FailureThreshold {
+		b.open()
+	}
+}
+
+func (b *Breaker) doneTrial(failed bool) {
+	b.trials--
+
+	if failed {
+		b.failures++
+		b.open()
+		return
+	}
+
+	b.failures = 0
+	b.transition(StateClosed)
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.now()
+	b.transition(StateOpen)
+}
+
+func (b *Breaker) transition(s State) {
+	b.state = s
+	b.trials = 0
+	b.generation++
+	b.onChange(s)
+}
@@ -0,0 +1,197 @@
+package resilience
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"microservice/internal/pkg/errors"
+)
+
+// testClock is a clock that only moves when advanced
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// newTestBreaker returns a breaker on clock, and the states it transitioned to
+func newTestBreaker(policy BreakerPolicy, clock *testClock) (*Breaker, *[]State) {
+	var transitions []State
+	b := NewBreaker(policy, func(s State) { transitions = append(transitions, s) })
+	b.now = clock.Now
+
+	return b, &transitions
+}
+
+// call makes a call through b that fails if failed is set, and fails the test if the breaker rejects it
+func call(t *testing.T, b *Breaker, failed bool) {
+	t.Helper()
+
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() error = %v, want call allowed", err)
+	}
+
+	done(failed)
+}
+
+func assertRejected(t *testing.T, b *Breaker) {
+	t.Helper()
+
+	if _, err := b.Allow(); !errors.IsType(err, errors.ErrorTypeUnavailable) {
+		t.Fatalf("Allow() error = %v, want Unavailable error", err)
+	}
+}
+
+func assertState(t *testing.T, b *Breaker, want State) {
+	t.Helper()
+
+	if got, _ := b.State(); got != want {
+		t.Fatalf("State() got = %v, want %v", got, want)
+	}
+}
+
+var testPolicy = BreakerPolicy{FailureThreshold: 3, OpenTimeout: time.Minute, HalfOpenMaxCalls: 1}
+
+func TestBreaker_recovery(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	b, transitions := newTestBreaker(testPolicy, clock)
+
+	// Failures below the threshold keep the breaker closed, a success resets them
+	call(t, b, true)
+	call(t, b, true)
+	call(t, b, false)
+	call(t, b, true)
+	call(t, b, true)
+	assertState(t, b, StateClosed)
+
+	call(t, b, true)
+	assertState(t, b, StateOpen)
+	assertRejected(t, b)
+
+	clock.advance(testPolicy.OpenTimeout - time.Second)
+	assertRejected(t, b)
+
+	clock.advance(time.Second)
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() error = %v, want trial call allowed after the open timeout", err)
+	}
+	assertState(t, b, StateHalfOpen)
+
+	// Trial calls beyond the limit are rejected while half-open
+	assertRejected(t, b)
+
+	done(false)
+	assertState(t, b, StateClosed)
+	if _, failures := b.State(); failures != 0 {
+		t.Errorf("State() failures = %v, want 0 after recovery", failures)
+	}
+
+	want := []State{StateOpen, StateHalfOpen, StateClosed}
+	if !reflect.DeepEqual(*transitions, want) {
+		t.Errorf("NewBreaker() transitions = %v, want %v", *transitions, want)
+	}
+}
+
+func TestBreaker_failedTrialReopens(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	b, transitions := newTestBreaker(testPolicy, clock)
+
+	for i := 0; i < testPolicy.FailureThreshold; i++ {
+		call(t, b, true)
+	}
+
+	clock.advance(testPolicy.OpenTimeout)
+	call(t, b, true)
+	assertState(t, b, StateOpen)
+
+	// The open timeout starts over from the failed trial
+	clock.advance(testPolicy.OpenTimeout - time.Second)
+	assertRejected(t, b)
+
+	clock.advance(time.Second)
+	call(t, b, false)
+	assertState(t, b, StateClosed)
+
+	want := []State{StateOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateClosed}
+	if !reflect.DeepEqual(*transitions, want) {
+		t.Errorf("NewBreaker() transitions = %v, want %v", *transitions, want)
+	}
+}
+
+func TestBreaker_staleOutcome(t *testing.T) {
+	tests := []struct {
+		name      string
+		failed    bool
+		wantState State
+	}{
+		{
+			name:      "success of a call allowed before the breaker opened expect breaker still open",
+			failed:    false,
+			wantState: StateOpen,
+		},
+		{
+			name:      "failure of a call allowed before the breaker opened expect failures not counted",
+			failed:    true,
+			wantState: StateOpen,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &testClock{now: time.Now()}
+			b, _ := newTestBreaker(testPolicy, clock)
+
+			stale, err := b.Allow()
+			if err != nil {
+				t.Fatalf("Allow() error = %v", err)
+			}
+
+			for i := 0; i < testPolicy.FailureThreshold; i++ {
+				call(t, b, true)
+			}
+			_, failures := b.State()
+
+			stale(tt.failed)
+
+			state, gotFailures := b.State()
+			if state != tt.wantState {
+				t.Errorf("State() got = %v, want %v", state, tt.wantState)
+			}
+
+			if gotFailures != failures {
+				t.Errorf("State() failures = %v, want %v", gotFailures, failures)
+			}
+		})
+	}
+}
+
+func TestBreaker_staleTrialOutcome(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	b, _ := newTestBreaker(BreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxCalls: 2}, clock)
+
+	call(t, b, true)
+	clock.advance(time.Minute)
+
+	first, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+
+	second, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+
+	// The first trial closes the breaker, the outcome of the second belongs to the half-open state and is ignored
+	first(false)
+	second(true)
+	assertState(t, b, StateClosed)
+}
@@ -0,0 +1,224 @@
+package resilience
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"microservice/internal/pkg/backoff"
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const breakerHealthCheckName = "mongodb.circuitBreaker"
+
+// metrics of the document operations, exposed with the other expvar variables
+var (
+	metrics      = expvar.NewMap("documentDB")
+	breakerState = new(expvar.String)
+)
+
+func init() {
+	breakerState.Set(StateClosed.String())
+	metrics.Set("breakerState", breakerState)
+}
+
+// Store is the document database decorated by DocumentDB
+type Store interface {
+	GetDocumentByID(ctx context.Context, id string, opts models.ReadOptions, result interface{}) error
+	GetDocumentByName(ctx context.Context, name string, result interface{}) error
+	SaveDocument(ctx context.Context, doc models.Document) (string, error)
+	SaveDocumentWithID(ctx context.Context, id string, doc models.Document) error
+	UpdateDocument(ctx context.Context, id string, doc models.Document) error
+	UpsertDocumentByName(ctx context.Context, doc models.Document) (string, bool, error)
+	CountDocuments(ctx context.Context) (int64, error)
+	SearchDocuments(ctx context.Context, query models.SearchQuery) ([]models.SearchResult, error)
+	CheckHealth(ctx context.Context) []models.HealthCheck
+	Teardown(ctx context.Context) error
+}
+
+// DocumentDB decorates a Store with a circuit breaker and per operation timeouts.
+// Reads are idempotent and retried with backoff on transient errors, writes are attempted once
+type DocumentDB struct {
+	store        Store
+	breaker      *Breaker
+	attempts     int
+	retry        backoff.Policy
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+// NewDocumentDB returns a new instance of the DocumentDB struct
+func NewDocumentDB(conf config.Mongo, store Store) (*DocumentDB, error) {
+	r := conf.Resilience
+
+	breaker := NewBreaker(BreakerPolicy(r.Breaker), func(s State) {
+		breakerState.Set(s.String())
+		if s == StateOpen {
+			metrics.Add("breakerOpened", 1)
+		}
+	})
+
+	return &DocumentDB{
+		store:        store,
+		breaker:      breaker,
+		attempts:     r.Retry.Attempts,
+		retry:        backoff.Policy(r.Retry.Backoff),
+		readTimeout:  r.Timeouts.Read,
+		writeTimeout: r.Timeouts.Write,
+	}, nil
+}
+
+// GetDocumentByID reads the fields of the document of id selected by opts into result
+func (d *DocumentDB) GetDocumentByID(ctx context.Context, id string, opts models.ReadOptions, result interface{}) error {
+	return d.read(ctx, func(ctx context.Context) error {
+		return d.store.GetDocumentByID(ctx, id, opts, result)
+	})
+}
+
+// GetDocumentByName reads the document of name into result
+func (d *DocumentDB) GetDocumentByName(ctx context.Context, name string, result interface{}) error {
+	return d.read(ctx, func(ctx context.Context) error {
+		return d.store.GetDocumentByName(ctx, name, result)
+	})
+}
+
+// CountDocuments returns the number of documents of the tenant
+func (d *DocumentDB) CountDocuments(ctx context.Context) (int64, error) {
+	var count int64
+	err := d.read(ctx, func(ctx context.Context) error {
+		var err error
+		count, err = d.store.CountDocuments(ctx)
+		return err
+	})
+
+	return count, err
+}
+
+// SearchDocuments returns the documents that match query
+func (d *DocumentDB) SearchDocuments(ctx context.Context, query models.SearchQuery) ([]models.SearchResult, error) {
+	var results []models.SearchResult
+	err := d.read(ctx, func(ctx context.Context) error {
+		var err error
+		results, err = d.store.SearchDocuments(ctx, query)
+		return err
+	})
+
+	return results, err
+}
+
+// SaveDocument saves doc under a new id
+func (d *DocumentDB) SaveDocument(ctx context.Context, doc models.Document) (string, error) {
+	var id string
+	err := d.write(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = d.store.SaveDocument(ctx, doc)
+		return err
+	})
+
+	return id, err
+}
+
+// SaveDocumentWithID saves doc under id
+func (d *DocumentDB) SaveDocumentWithID(ctx context.Context, id string, doc models.Document) error {
+	return d.write(ctx, func(ctx context.Context) error {
+		return d.store.SaveDocumentWithID(ctx, id, doc)
+	})
+}
+
+// UpdateDocument replaces the document of id by doc
+func (d *DocumentDB) UpdateDocument(ctx context.Context, id string, doc models.Document) error {
+	return d.write(ctx, func(ctx context.Context) error {
+		return d.store.UpdateDocument(ctx, id, doc)
+	})
+}
+
+// UpsertDocumentByName creates or replaces the document of the name of doc
+func (d *DocumentDB) UpsertDocumentByName(ctx context.Context, doc models.Document) (string, bool, error) {
+	var (
+		id      string
+		created bool
+	)
+	err := d.write(ctx, func(ctx context.Context) error {
+		var err error
+		id, created, err = d.store.UpsertDocumentByName(ctx, doc)
+		return err
+	})
+
+	return id, created, err
+}
+
+// CheckHealth reports the health of the store and the state of the circuit breaker, which isn't ready while open
+func (d *DocumentDB) CheckHealth(ctx context.Context) []models.HealthCheck {
+	state, failures := d.breaker.State()
+
+	return append(d.store.CheckHealth(ctx), models.HealthCheck{
+		Name:    breakerHealthCheckName,
+		Ready:   state != StateOpen,
+		Details: map[string]interface{}{"state": state.String(), "failures": failures},
+	})
+}
+
+// Teardown tears down the store
+func (d *DocumentDB) Teardown(ctx context.Context) error {
+	return d.store.Teardown(ctx)
+}
+
+// read calls fn until it succeeds, fails with an error that isn't transient, or runs out of attempts
+func (d *DocumentDB) read(ctx context.Context, fn func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		err := d.call(ctx, d.readTimeout, fn)
+		if err == nil || !isTransient(err) || attempt+1 >= d.attempts {
+			return unavailable(err)
+		}
+
+		if d.retry.Wait(ctx, attempt) != nil {
+			return unavailable(err)
+		}
+		metrics.Add("retries", 1)
+	}
+}
+
+func (d *DocumentDB) write(ctx context.Context, fn func(ctx context.Context) error) error {
+	return unavailable(d.call(ctx, d.writeTimeout, fn))
+}
+
+// call calls fn through the circuit breaker, bounded by timeout
+func (d *DocumentDB) call(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	done, err := d.breaker.Allow()
+	if err != nil {
+		metrics.Add("rejected", 1)
+		return err
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err = fn(opCtx)
+
+	// the caller giving up is not a failure of the database
+	failed := isTransient(err) && ctx.Err() == nil
+	if failed {
+		metrics.Add("failures", 1)
+	}
+	done(failed)
+
+	return err
+}
+
+// isTransient reports whether err is a timeout or a network error, which may not recur on another attempt
+func isTransient(err error) bool {
+	return err != nil && (mongo.IsTimeout(err) || mongo.IsNetworkError(err))
+}
+
+// unavailable marks transient errors as Unavailable, so callers can tell them from errors of the request
+func unavailable(err error) error {
+	if !isTransient(err) {
+		return err
+	}
+
+	return errors.Wrap(err, "Mongodb didn't answer in time").SetType(errors.ErrorTypeUnavailable)
+}
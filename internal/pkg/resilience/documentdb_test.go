@@ -0,0 +1,191 @@
+package resilience
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/errors"
+	"microservice/mocks"
+	"microservice/models"
+
+	"github.com/golang/mock/gomock"
+)
+
+const testAttempts = 3
+
+func newTestDocumentDB(t *testing.T, store Store) *DocumentDB {
+	d, err := NewDocumentDB(config.Mongo{Resilience: config.MongoResilience{
+		Breaker: config.Breaker{FailureThreshold: 5, OpenTimeout: time.Minute, HalfOpenMaxCalls: 1},
+		Retry: config.Retry{
+			Attempts: testAttempts,
+			Backoff:  config.Backoff{Initial: time.Millisecond, Max: time.Millisecond, Multiplier: 1},
+		},
+		Timeouts: config.MongoOperationTimeouts{Read: time.Second, Write: time.Second},
+	}}, store)
+	if err != nil {
+		t.Fatalf("NewDocumentDB() error = %v", err)
+	}
+
+	return d
+}
+
+func TestDocumentDB_retries(t *testing.T) {
+	errTransient := context.DeadlineExceeded
+	errRequest := errors.New("Duplicate key").SetType(errors.ErrorTypeConflict)
+
+	read := func(d *DocumentDB) error {
+		return d.GetDocumentByID(context.Background(), "id", models.ReadOptions{}, &models.Document{})
+	}
+	write := func(d *DocumentDB) error {
+		_, err := d.SaveDocument(context.Background(), models.Document{})
+		return err
+	}
+
+	tests := []struct {
+		name string
+		// errs are the errors of the store in order, the last is repeated
+		errs      []error
+		op        func(d *DocumentDB) error
+		readOp    bool
+		wantCalls int
+		wantType  errors.ErrorType
+		wantErr   bool
+	}{
+		{
+			name:      "read that succeeds expect a single attempt",
+			errs:      []error{nil},
+			op:        read,
+			readOp:    true,
+			wantCalls: 1,
+		},
+		{
+			name:      "read failing transiently once expect retried and succeeded",
+			errs:      []error{errTransient, nil},
+			op:        read,
+			readOp:    true,
+			wantCalls: 2,
+		},
+		{
+			name:      "read failing transiently on every attempt expect all attempts and Unavailable error",
+			errs:      []error{errTransient},
+			op:        read,
+			readOp:    true,
+			wantCalls: testAttempts,
+			wantType:  errors.ErrorTypeUnavailable,
+			wantErr:   true,
+		},
+		{
+			name:      "read failing with an error of the request expect no retry and error as is",
+			errs:      []error{errRequest},
+			op:        read,
+			readOp:    true,
+			wantCalls: 1,
+			wantType:  errors.ErrorTypeConflict,
+			wantErr:   true,
+		},
+		{
+			name:      "write failing transiently expect a single attempt and Unavailable error",
+			errs:      []error{errTransient},
+			op:        write,
+			wantCalls: 1,
+			wantType:  errors.ErrorTypeUnavailable,
+			wantErr:   true,
+		},
+		{
+			name:      "write failing with an error of the request expect error as is",
+			errs:      []error{errRequest},
+			op:        write,
+			wantCalls: 1,
+			wantType:  errors.ErrorTypeConflict,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			calls := 0
+			next := func() error {
+				err := tt.errs[len(tt.errs)-1]
+				if calls < len(tt.errs) {
+					err = tt.errs[calls]
+				}
+				calls++
+
+				return err
+			}
+
+			store := mocks.NewMockResilienceStore(ctrl)
+			if tt.readOp {
+				store.EXPECT().GetDocumentByID(gomock.Any(), "id", gomock.Any(), gomock.Any()).
+					DoAndReturn(func(context.Context, string, models.ReadOptions, interface{}) error { return next() }).
+					Times(tt.wantCalls)
+			} else {
+				store.EXPECT().SaveDocument(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(context.Context, models.Document) (string, error) { return "", next() }).
+					Times(tt.wantCalls)
+			}
+
+			err := tt.op(newTestDocumentDB(t, store))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.IsType(err, tt.wantType) {
+				t.Errorf("error = %v, want error of type %v", err, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestDocumentDB_breakerOpens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockResilienceStore(ctrl)
+	d := newTestDocumentDB(t, store)
+	threshold := d.breaker.policy.FailureThreshold
+
+	// Failed writes open the breaker, after which calls are rejected without reaching the store
+	store.EXPECT().SaveDocumentWithID(gomock.Any(), "id", gomock.Any()).Return(context.DeadlineExceeded).Times(threshold)
+	for i := 0; i < threshold; i++ {
+		_ = d.SaveDocumentWithID(context.Background(), "id", models.Document{})
+	}
+
+	if err := d.SaveDocumentWithID(context.Background(), "id", models.Document{}); !errors.IsType(err, errors.ErrorTypeUnavailable) {
+		t.Errorf("SaveDocumentWithID() error = %v, want Unavailable error of the open breaker", err)
+	}
+
+	if _, err := d.CountDocuments(context.Background()); !errors.IsType(err, errors.ErrorTypeUnavailable) {
+		t.Errorf("CountDocuments() error = %v, want Unavailable error of the open breaker", err)
+	}
+
+	store.EXPECT().CheckHealth(gomock.Any()).Return([]models.HealthCheck{{Name: "mongodb", Ready: true}})
+	checks := d.CheckHealth(context.Background())
+	if len(checks) != 2 || checks[1].Name != breakerHealthCheckName || checks[1].Ready {
+		t.Errorf("CheckHealth() got = %+v, want the store check followed by a breaker check that isn't ready", checks)
+	}
+}
+
+func TestDocumentDB_canceledCallerIsNotAFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockResilienceStore(ctrl)
+	d := newTestDocumentDB(t, store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	store.EXPECT().UpdateDocument(gomock.Any(), "id", gomock.Any()).Return(context.DeadlineExceeded).Times(d.breaker.policy.FailureThreshold)
+	for i := 0; i < d.breaker.policy.FailureThreshold; i++ {
+		_ = d.UpdateDocument(ctx, "id", models.Document{})
+	}
+
+	if state, failures := d.breaker.State(); state != StateClosed || failures != 0 {
+		t.Errorf("State() got = %v with (%d) failures, want closed without failures", state, failures)
+	}
+}
@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"microservice/internal/pkg/errors"
+
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v2"
+)
+
+// vaultKeySeparator separates the path of a vault secret from the key of the value in it
+const vaultKeySeparator = "#"
+
+// VaultStore reads the key value pairs of secrets by path, as a vault does
+type VaultStore interface {
+	ReadSecret(ctx context.Context, path string) (map[string]string, error)
+}
+
+// fileProvider reads secrets from files, such as docker and kubernetes secrets. A trailing line break is dropped
+type fileProvider struct{}
+
+func (fileProvider) Resolve(_ context.Context, path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to read secret file (%s)", path)
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// envProvider reads secrets from environment variables
+type envProvider struct{}
+
+func (envProvider) Resolve(_ context.Context, name string) (string, error) {
+	secret, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errors.Errorf("Environment variable (%s) is not set", name)
+	}
+
+	return secret, nil
+}
+
+// vaultProvider reads secrets from a vault store, referenced as path#key
+type vaultProvider struct {
+	store VaultStore
+}
+
+func (p vaultProvider) Resolve(ctx context.Context, ref string) (string, error) {
+	i := strings.LastIndex(ref, vaultKeySeparator)
+	if i <= 0 || i == len(ref)-1 {
+		return "", errors.Errorf("Invalid vault reference (%s), expected path%skey", ref, vaultKeySeparator)
+	}
+
+	path, key := ref[:i], ref[i+1:]
+	secret, err := p.store.ReadSecret(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secret[key]
+	if !ok {
+		return "", errors.Errorf("Vault secret (%s) has no key (%s)", path, key)
+	}
+
+	return value, nil
+}
+
+// LocalVault is a stand-in for a vault, reading secrets from a yaml file of paths mapped to key value pairs.
+// The file is read on every access, so editing it rotates the secrets
+type LocalVault struct {
+	file string
+}
+
+// NewLocalVault returns a new instance of the LocalVault struct
+func NewLocalVault(file string) *LocalVault {
+	return &LocalVault{file: file}
+}
+
+// ReadSecret returns the key value pairs of the secret at path
+func (v *LocalVault) ReadSecret(_ context.Context, path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(v.file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read vault file (%s)", v.file)
+	}
+
+	var secrets map[string]interface{}
+	if err := yaml.Unmarshal(b, &secrets); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse vault file (%s)", v.file)
+	}
+
+	secret, ok := secrets[path]
+	if !ok {
+		return nil, errors.Errorf("Vault has no secret (%s)", path).SetType(errors.ErrorTypeNotFound)
+	}
+
+	values, err := cast.ToStringMapStringE(secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid vault secret (%s), expected key value pairs", path)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,165 @@
+package secrets
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// writeFile writes content to a file named name in a temporary directory and returns its path
+func writeFile(t *testing.T, name string, content string) string {
+	file := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write file (%s). Error: %s", file, err)
+	}
+
+	return file
+}
+
+func TestFileProvider_Resolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		missing bool
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "secret file expect its content",
+			content: "hunter2",
+			want:    "hunter2",
+		},
+		{
+			name:    "secret file ending with a line break expect line break dropped",
+			content: "hunter2\r\n",
+			want:    "hunter2",
+		},
+		{
+			name:    "missing secret file expect error",
+			missing: true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "missing")
+			if !tt.missing {
+				path = writeFile(t, "secret", tt.content)
+			}
+
+			got, err := fileProvider{}.Resolve(context.Background(), path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Resolve() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvProvider_Resolve(t *testing.T) {
+	t.Setenv("SECRETS_TEST_PASSWORD", "hunter2")
+	t.Setenv("SECRETS_TEST_EMPTY", "")
+
+	tests := []struct {
+		name    string
+		env     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "set environment variable expect its value",
+			env:  "SECRETS_TEST_PASSWORD",
+			want: "hunter2",
+		},
+		{
+			name: "environment variable set to empty expect empty secret",
+			env:  "SECRETS_TEST_EMPTY",
+			want: "",
+		},
+		{
+			name:    "unset environment variable expect error",
+			env:     "SECRETS_TEST_UNSET",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := envProvider{}.Resolve(context.Background(), tt.env)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Resolve() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultProvider_Resolve(t *testing.T) {
+	vault := NewLocalVault(writeFile(t, "vault.yaml", `
+database/mongo:
+  username: "bob"
+  password: "hunter2"
+database/invalid: "not key value pairs"
+`))
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "path and key of a secret expect its value",
+			ref:  "database/mongo#password",
+			want: "hunter2",
+		},
+		{
+			name:    "reference without key expect error",
+			ref:     "database/mongo",
+			wantErr: true,
+		},
+		{
+			name:    "reference with empty key expect error",
+			ref:     "database/mongo#",
+			wantErr: true,
+		},
+		{
+			name:    "reference with empty path expect error",
+			ref:     "#password",
+			wantErr: true,
+		},
+		{
+			name:    "unknown key expect error",
+			ref:     "database/mongo#token",
+			wantErr: true,
+		},
+		{
+			name:    "unknown path expect error",
+			ref:     "database/redis#password",
+			wantErr: true,
+		},
+		{
+			name:    "secret which isn't key value pairs expect error",
+			ref:     "database/invalid#password",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := vaultProvider{store: vault}.Resolve(context.Background(), tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Resolve() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
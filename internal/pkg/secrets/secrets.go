@@ -0,0 +1,192 @@
+package secrets
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"microservice/internal/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	secretsBaseKey            = "secrets"
+	secretsRefreshIntervalKey = secretsBaseKey + ".refreshInterval"
+	secretsVaultFileKey       = secretsBaseKey + ".vault.file"
+
+	// SchemeFile references a file holding the secret, as in file:///run/secrets/mongo
+	SchemeFile = "file://"
+
+	// SchemeEnv references an environment variable holding the secret, as in env:MONGO_PASSWORD
+	SchemeEnv = "env:"
+
+	// SchemeVault references a key of a secret in the vault, as in vault:database/mongo#password
+	SchemeVault = "vault:"
+
+	defaultRefreshInterval = time.Minute
+)
+
+// Configuration expose an interface of configuration related actions
+type Configuration interface {
+	GetString(key string) (string, error)
+	GetDuration(key string) (time.Duration, error)
+	IsSet(key string) bool
+}
+
+// Provider resolves the references of a scheme, given without the scheme prefix
+type Provider interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// Service resolves secret references and watches them for rotation.
+// Values without a known scheme are literal secrets and resolve to themselves
+type Service struct {
+	providers       map[string]Provider
+	refreshInterval time.Duration
+}
+
+// NewService returns a new instance of the Service struct
+func NewService(conf Configuration) (*Service, error) {
+	s := &Service{
+		providers: map[string]Provider{
+			SchemeFile: fileProvider{},
+			SchemeEnv:  envProvider{},
+		},
+		refreshInterval: defaultRefreshInterval,
+	}
+
+	if conf.IsSet(secretsRefreshIntervalKey) {
+		interval, err := conf.GetDuration(secretsRefreshIntervalKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to get secrets refresh interval from configuration key (%s)", secretsRefreshIntervalKey)
+		}
+
+		if interval <= 0 {
+			return nil, errors.Errorf("Invalid secrets refresh interval (%s), expected a positive duration", interval)
+		}
+		s.refreshInterval = interval
+	}
+
+	if conf.IsSet(secretsVaultFileKey) {
+		file, err := conf.GetString(secretsVaultFileKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to get secrets vault file from configuration key (%s)", secretsVaultFileKey)
+		}
+
+		s.providers[SchemeVault] = vaultProvider{store: NewLocalVault(file)}
+	}
+
+	return s, nil
+}
+
+// Resolve returns the secret referenced by value
+func (s *Service) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, ref, ok := parse(value)
+	if !ok {
+		return value, nil
+	}
+
+	p, ok := s.providers[scheme]
+	if !ok {
+		return "", errors.Errorf("No provider of secret references with scheme (%s)", scheme)
+	}
+
+	secret, err := p.Resolve(ctx, ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to resolve secret reference with scheme (%s)", scheme)
+	}
+
+	return secret, nil
+}
+
+// Watch resolves the references in values together every refresh interval and calls onRotate once with all of the
+// secrets when any of them differs from current, so secrets that only work together, like a username and its password,
+// rotate as one unit. onRefresh is called with the outcome of every refresh, which is logged when it fails.
+// A rotation that onRotate fails is retried on the next refresh. Literal values are never rotated
+func (s *Service) Watch(values []string, current []string, onRotate func(secrets []string) error, onRefresh func(err error)) (stop func()) {
+	if !anyReference(values) {
+		return func() {}
+	}
+
+	current = append([]string(nil), current...)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(s.refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), s.refreshInterval)
+			rotated, err := s.refresh(ctx, values, current, onRotate)
+			cancel()
+
+			if err != nil {
+				log.Errorf("Failed to refresh watched secrets. Error: %s", err)
+			} else if rotated != nil {
+				current = rotated
+			}
+			onRefresh(err)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+// refresh resolves values and passes them to onRotate when they differ from current.
+// It returns the rotated secrets, or nil when none of the secrets changed
+func (s *Service) refresh(ctx context.Context, values []string, current []string, onRotate func(secrets []string) error) ([]string, error) {
+	secrets := make([]string, len(values))
+	changed := false
+	for i, value := range values {
+		secret, err := s.Resolve(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+
+		secrets[i] = secret
+		changed = changed || i >= len(current) || secret != current[i]
+	}
+
+	if !changed {
+		return nil, nil
+	}
+
+	if err := onRotate(secrets); err != nil {
+		return nil, errors.Wrap(err, "Failed to rotate secrets")
+	}
+
+	return secrets, nil
+}
+
+func anyReference(values []string) bool {
+	for _, value := range values {
+		if _, _, ok := parse(value); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parse splits value into its scheme and reference, ok is false for literal values
+func parse(value string) (scheme string, ref string, ok bool) {
+	for _, scheme := range []string{SchemeFile, SchemeEnv, SchemeVault} {
+		if strings.HasPrefix(value, scheme) {
+			return scheme, strings.TrimPrefix(value, scheme), true
+		}
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,313 @@
+package secrets
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"microservice/internal/pkg/errors"
+
+	"github.com/spf13/cast"
+)
+
+// testConfiguration is a Configuration of fixed values
+type testConfiguration map[string]interface{}
+
+func (c testConfiguration) GetString(key string) (string, error) {
+	return cast.ToStringE(c[key])
+}
+
+func (c testConfiguration) GetDuration(key string) (time.Duration, error) {
+	return cast.ToDurationE(c[key])
+}
+
+func (c testConfiguration) IsSet(key string) bool {
+	_, ok := c[key]
+	return ok
+}
+
+// testProvider resolves references to the secrets it holds, which may be changed while it is in use
+type testProvider struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func (p *testProvider) Resolve(_ context.Context, ref string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	secret, ok := p.secrets[ref]
+	if !ok {
+		return "", errors.Errorf("No secret (%s)", ref)
+	}
+
+	return secret, nil
+}
+
+func (p *testProvider) set(secrets map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.secrets = secrets
+}
+
+func TestNewService(t *testing.T) {
+	tests := []struct {
+		name      string
+		conf      testConfiguration
+		wantVault bool
+		wantErr   bool
+	}{
+		{
+			name: "empty configuration expect default refresh interval",
+		},
+		{
+			name:      "vault file configured expect vault provider",
+			conf:      testConfiguration{secretsVaultFileKey: "vault.yaml", secretsRefreshIntervalKey: "10s"},
+			wantVault: true,
+		},
+		{
+			name:    "zero refresh interval expect error",
+			conf:    testConfiguration{secretsRefreshIntervalKey: "0s"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid refresh interval expect error",
+			conf:    testConfiguration{secretsRefreshIntervalKey: "soon"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewService(tt.conf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewService() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if _, ok := s.providers[SchemeVault]; ok != tt.wantVault {
+				t.Errorf("NewService() vault provider = %v, want %v", ok, tt.wantVault)
+			}
+		})
+	}
+}
+
+func TestService_Resolve(t *testing.T) {
+	t.Setenv("SECRETS_TEST_PASSWORD", "envPassword")
+	vault := writeFile(t, "vault.yaml", "database/mongo:\n  password: vaultPassword\n")
+
+	s, err := NewService(testConfiguration{secretsVaultFileKey: vault})
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	withoutVault, err := NewService(testConfiguration{})
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		s       *Service
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "literal value expect value itself",
+			s:     s,
+			value: "literalPassword",
+			want:  "literalPassword",
+		},
+		{
+			name:  "literal value resembling a scheme expect value itself",
+			s:     s,
+			value: "files://password",
+			want:  "files://password",
+		},
+		{
+			name:  "file reference expect file content",
+			s:     s,
+			value: SchemeFile + writeFile(t, "password", "filePassword\n"),
+			want:  "filePassword",
+		},
+		{
+			name:  "env reference expect environment variable",
+			s:     s,
+			value: SchemeEnv + "SECRETS_TEST_PASSWORD",
+			want:  "envPassword",
+		},
+		{
+			name:  "vault reference expect vault secret",
+			s:     s,
+			value: SchemeVault + "database/mongo#password",
+			want:  "vaultPassword",
+		},
+		{
+			name:    "vault reference without vault expect error",
+			s:       withoutVault,
+			value:   SchemeVault + "database/mongo#password",
+			wantErr: true,
+		},
+		{
+			name:    "unresolvable reference expect error",
+			s:       s,
+			value:   SchemeEnv + "SECRETS_TEST_UNSET",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.s.Resolve(context.Background(), tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Resolve() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_refresh(t *testing.T) {
+	values := []string{SchemeVault + "username", SchemeVault + "password"}
+	current := []string{"bob", "hunter2"}
+
+	tests := []struct {
+		name        string
+		secrets     map[string]string
+		rotateErr   error
+		want        []string
+		wantRotated []string
+		wantErr     bool
+	}{
+		{
+			name:    "unchanged secrets expect no rotation",
+			secrets: map[string]string{"username": "bob", "password": "hunter2"},
+		},
+		{
+			name:        "only password rotated expect rotation with both secrets",
+			secrets:     map[string]string{"username": "bob", "password": "hunter3"},
+			want:        []string{"bob", "hunter3"},
+			wantRotated: []string{"bob", "hunter3"},
+		},
+		{
+			name:        "username and password rotated expect a single rotation with both",
+			secrets:     map[string]string{"username": "alice", "password": "hunter3"},
+			want:        []string{"alice", "hunter3"},
+			wantRotated: []string{"alice", "hunter3"},
+		},
+		{
+			name:    "unresolvable secret expect error and no rotation",
+			secrets: map[string]string{"username": "alice"},
+			wantErr: true,
+		},
+		{
+			name:        "failed rotation expect error and secrets not rotated",
+			secrets:     map[string]string{"username": "alice", "password": "hunter3"},
+			rotateErr:   errors.New("Authentication failed"),
+			wantRotated: []string{"alice", "hunter3"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{providers: map[string]Provider{SchemeVault: &testProvider{secrets: tt.secrets}}}
+
+			var rotated []string
+			calls := 0
+			got, err := s.refresh(context.Background(), values, current, func(secrets []string) error {
+				calls++
+				rotated = secrets
+				return tt.rotateErr
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("refresh() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("refresh() got = %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(rotated, tt.wantRotated) {
+				t.Errorf("refresh() rotated = %v, want %v", rotated, tt.wantRotated)
+			}
+
+			if calls > 1 {
+				t.Errorf("refresh() rotated (%d) times, want at most once", calls)
+			}
+		})
+	}
+}
+
+func TestService_Watch(t *testing.T) {
+	provider := &testProvider{secrets: map[string]string{"password": "hunter2"}}
+	s := &Service{providers: map[string]Provider{SchemeVault: provider}, refreshInterval: 5 * time.Millisecond}
+
+	rotations := make(chan []string, 10)
+	refreshes := make(chan error, 100)
+	failRotation := true
+	stop := s.Watch([]string{"bob", SchemeVault + "password"}, []string{"bob", "hunter2"}, func(secrets []string) error {
+		if failRotation {
+			failRotation = false
+			return errors.New("Authentication failed")
+		}
+
+		rotations <- secrets
+		return nil
+	}, func(err error) {
+		refreshes <- err
+	})
+	defer stop()
+
+	provider.set(map[string]string{"password": "hunter3"})
+
+	// The first rotation fails and is retried on the next refresh, which reports success
+	timeout := time.After(time.Second)
+	failed := false
+	for recovered := false; !recovered; {
+		select {
+		case err := <-refreshes:
+			failed = failed || err != nil
+			recovered = failed && err == nil
+		case <-timeout:
+			t.Fatalf("Watch() didn't report a failed refresh followed by a successful one, failed = %v", failed)
+		}
+	}
+
+	select {
+	case got := <-rotations:
+		if want := []string{"bob", "hunter3"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Watch() rotated = %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("Watch() didn't rotate the secrets")
+	}
+
+	stop()
+	select {
+	case got := <-rotations:
+		t.Errorf("Watch() rotated = %v, want no rotation of unchanged secrets", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestService_Watch_literals(t *testing.T) {
+	s := &Service{providers: map[string]Provider{}, refreshInterval: time.Millisecond}
+
+	stop := s.Watch([]string{"bob", "hunter2"}, []string{"alice", "hunter3"}, func(secrets []string) error {
+		t.Errorf("Watch() rotated = %v, want literal values never rotated", secrets)
+		return nil
+	}, func(err error) {
+		t.Errorf("Watch() refreshed with error = %v, want literal values never refreshed", err)
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	stop()
+}
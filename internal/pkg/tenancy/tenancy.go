@@ -0,0 +1,196 @@
+package tenancy
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	tenancyBaseKey          = "tenancy"
+	tenancyHeaderKey        = tenancyBaseKey + ".header"
+	tenancyDefaultTenantKey = tenancyBaseKey + ".defaultTenant"
+	tenancyQuotasKey        = tenancyBaseKey + ".quotas"
+	tenancyDefaultQuotaKey  = tenancyQuotasKey + ".default"
+	tenancyTenantQuotasKey  = tenancyQuotasKey + ".tenants"
+
+	maxDocumentsField     = "maxdocuments"
+	maxDocumentBytesField = "maxdocumentbytes"
+
+	defaultHeader = "X-Tenant-ID"
+
+	// RoleCrossTenant lets principals that are not bound to a tenant act on behalf of any tenant
+	RoleCrossTenant = "cross-tenant"
+)
+
+// tenantIDPattern restricts tenant ids to characters that are safe in database names and filters.
+// Tenant ids are not case sensitive and are lowercased when resolved, since database names may not differ only by case
+var tenantIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,48}$`)
+
+// Configuration expose an interface of configuration related actions
+type Configuration interface {
+	Get(key string) interface{}
+	GetString(key string) (string, error)
+	IsSet(key string) bool
+}
+
+// Service resolves the tenant of requests and the quota of tenants
+type Service struct {
+	header        string
+	defaultTenant string
+	defaultQuota  models.TenantQuota
+	quotas        map[string]models.TenantQuota
+}
+
+type contextKey struct{}
+
+// NewService returns a new instance of the Service struct
+func NewService(conf Configuration) (*Service, error) {
+	s := &Service{
+		header: defaultHeader,
+		quotas: make(map[string]models.TenantQuota),
+	}
+
+	if conf.IsSet(tenancyHeaderKey) {
+		header, err := conf.GetString(tenancyHeaderKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to get tenant header from configuration key (%s)", tenancyHeaderKey)
+		}
+		s.header = header
+	}
+
+	if conf.IsSet(tenancyDefaultTenantKey) {
+		defaultTenant, err := conf.GetString(tenancyDefaultTenantKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Fail to get default tenant from configuration key (%s)", tenancyDefaultTenantKey)
+		}
+
+		if defaultTenant != "" && !tenantIDPattern.MatchString(defaultTenant) {
+			return nil, errors.Errorf("Invalid default tenant (%s) in configuration key (%s)", defaultTenant, tenancyDefaultTenantKey)
+		}
+		s.defaultTenant = strings.ToLower(defaultTenant)
+	}
+
+	if conf.IsSet(tenancyDefaultQuotaKey) {
+		quota, err := parseQuota(conf.Get(tenancyDefaultQuotaKey))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid quota in configuration key (%s)", tenancyDefaultQuotaKey)
+		}
+		s.defaultQuota = quota
+	}
+
+	if conf.IsSet(tenancyTenantQuotasKey) {
+		tenants, err := cast.ToStringMapE(conf.Get(tenancyTenantQuotasKey))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid tenant quotas in configuration key (%s)", tenancyTenantQuotasKey)
+		}
+
+		for tenant, q := range tenants {
+			quota, err := parseQuota(q)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Invalid quota of tenant (%s) in configuration key (%s)", tenant, tenancyTenantQuotasKey)
+			}
+			s.quotas[tenant] = quota
+		}
+	}
+
+	return s, nil
+}
+
+// ResolveTenant returns the tenant of the request.
+// A principal bound to a tenant always acts on behalf of that tenant. Principals that are not bound to a tenant must
+// hold the cross tenant role, and select the tenant by header, falling back to the default tenant
+func (s *Service) ResolveTenant(r *http.Request, p models.Principal) (string, error) {
+	requested := strings.ToLower(r.Header.Get(s.header))
+	bound := strings.ToLower(p.Tenant)
+
+	var tenant string
+	switch {
+	case bound != "" && requested != "" && requested != bound:
+		return "", errors.Errorf("Principal (%s) may not act on behalf of tenant (%s)", p.Subject, requested).SetType(errors.ErrorTypeForbidden)
+	case bound != "":
+		tenant = bound
+	case !hasRole(p, RoleCrossTenant):
+		return "", errors.Errorf("Principal (%s) is not bound to a tenant", p.Subject).SetType(errors.ErrorTypeForbidden)
+	case requested != "":
+		tenant = requested
+	case s.defaultTenant != "":
+		tenant = s.defaultTenant
+	default:
+		return "", errors.Errorf("Missing tenant, set the (%s) header", s.header).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	if !tenantIDPattern.MatchString(tenant) {
+		return "", errors.Errorf("Invalid tenant (%s)", tenant).SetType(errors.ErrorTypeBadRequest)
+	}
+
+	return tenant, nil
+}
+
+// DefaultTenant returns the tenant of principals that are not bound to a tenant and don't select one
+func (s *Service) DefaultTenant() string {
+	return s.defaultTenant
+}
+
+func hasRole(p models.Principal, role string) bool {
+	for _, have := range p.Roles {
+		if have == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Quota returns the quota of the tenant, or the default quota if the tenant has none of its own.
+// Tenant quotas are looked up case insensitively since configuration keys and tenant ids are not case sensitive
+func (s *Service) Quota(tenant string) models.TenantQuota {
+	if q, ok := s.quotas[strings.ToLower(tenant)]; ok {
+		return q
+	}
+
+	return s.defaultQuota
+}
+
+// NewContext returns a copy of ctx which carries the tenant
+func NewContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext returns the tenant stored in ctx, if any
+func FromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(contextKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+func parseQuota(v interface{}) (models.TenantQuota, error) {
+	fields, err := cast.ToStringMapE(v)
+	if err != nil {
+		return models.TenantQuota{}, err
+	}
+
+	maxDocuments, err := cast.ToInt64E(fields[maxDocumentsField])
+	if err != nil {
+		return models.TenantQuota{}, errors.Wrapf(err, "Invalid (%s)", maxDocumentsField)
+	}
+
+	maxDocumentBytes, err := cast.ToInt64E(fields[maxDocumentBytesField])
+	if err != nil {
+		return models.TenantQuota{}, errors.Wrapf(err, "Invalid (%s)", maxDocumentBytesField)
+	}
+
+	if maxDocuments < 0 || maxDocumentBytes < 0 {
+		return models.TenantQuota{}, errors.New("Quota limits must not be negative")
+	}
+
+	return models.TenantQuota{
+		MaxDocuments:     maxDocuments,
+		MaxDocumentBytes: maxDocumentBytes,
+	}, nil
+}
@@ -0,0 +1,189 @@
+package tenancy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"microservice/internal/pkg/errors"
+	"microservice/models"
+
+	"github.com/spf13/cast"
+)
+
+// testConfiguration is a Configuration of fixed values
+type testConfiguration map[string]interface{}
+
+func (c testConfiguration) Get(key string) interface{} {
+	return c[key]
+}
+
+func (c testConfiguration) GetString(key string) (string, error) {
+	return cast.ToStringE(c[key])
+}
+
+func (c testConfiguration) IsSet(key string) bool {
+	_, ok := c[key]
+	return ok
+}
+
+func TestNewService_defaultTenant(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultTenant string
+		want          string
+		wantErr       bool
+	}{
+		{
+			name:          "lowercase default tenant expect it",
+			defaultTenant: "default",
+			want:          "default",
+			wantErr:       false,
+		},
+		{
+			name:          "mixed case default tenant expect it lowercased",
+			defaultTenant: "Default-Tenant",
+			want:          "default-tenant",
+			wantErr:       false,
+		},
+		{
+			name:          "invalid default tenant expect error",
+			defaultTenant: "default/tenant",
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewService(testConfiguration{tenancyDefaultTenantKey: tt.defaultTenant})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewService() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if got := s.DefaultTenant(); got != tt.want {
+				t.Errorf("DefaultTenant() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_ResolveTenant(t *testing.T) {
+	bound := models.Principal{Subject: "alice", Roles: []string{"writer"}, Tenant: "tenant-a"}
+	unbound := models.Principal{Subject: "bob", Roles: []string{"admin"}}
+	crossTenant := models.Principal{Subject: "operator", Roles: []string{"reader", RoleCrossTenant}}
+
+	tests := []struct {
+		name          string
+		principal     models.Principal
+		header        string
+		defaultTenant string
+		want          string
+		wantErr       bool
+		wantType      errors.ErrorType
+	}{
+		{
+			name:      "principal bound to a tenant expect its tenant",
+			principal: bound,
+			want:      "tenant-a",
+			wantErr:   false,
+		},
+		{
+			name:      "principal bound to a tenant requesting its tenant expect its tenant",
+			principal: bound,
+			header:    "tenant-a",
+			want:      "tenant-a",
+			wantErr:   false,
+		},
+		{
+			name:      "principal bound to a tenant requesting its tenant in another case expect its tenant lowercased",
+			principal: models.Principal{Subject: "alice", Tenant: "Tenant-A"},
+			header:    "TENANT-a",
+			want:      "tenant-a",
+			wantErr:   false,
+		},
+		{
+			name:      "principal bound to a tenant requesting another tenant expect forbidden error",
+			principal: bound,
+			header:    "tenant-b",
+			wantErr:   true,
+			wantType:  errors.ErrorTypeForbidden,
+		},
+		{
+			name:      "principal without tenant requesting a tenant expect forbidden error",
+			principal: unbound,
+			header:    "tenant-b",
+			wantErr:   true,
+			wantType:  errors.ErrorTypeForbidden,
+		},
+		{
+			name:          "principal without tenant falling back to the default tenant expect forbidden error",
+			principal:     unbound,
+			defaultTenant: "default",
+			wantErr:       true,
+			wantType:      errors.ErrorTypeForbidden,
+		},
+		{
+			name:      "cross tenant principal requesting a tenant expect requested tenant",
+			principal: crossTenant,
+			header:    "tenant-b",
+			want:      "tenant-b",
+			wantErr:   false,
+		},
+		{
+			name:      "cross tenant principal requesting a mixed case tenant expect tenant lowercased",
+			principal: crossTenant,
+			header:    "Tenant-B",
+			want:      "tenant-b",
+			wantErr:   false,
+		},
+		{
+			name:          "cross tenant principal without header expect default tenant",
+			principal:     crossTenant,
+			defaultTenant: "default",
+			want:          "default",
+			wantErr:       false,
+		},
+		{
+			name:      "cross tenant principal without header nor default tenant expect bad request error",
+			principal: crossTenant,
+			wantErr:   true,
+			wantType:  errors.ErrorTypeBadRequest,
+		},
+		{
+			name:      "cross tenant principal requesting an invalid tenant expect bad request error",
+			principal: crossTenant,
+			header:    "tenant/../b",
+			wantErr:   true,
+			wantType:  errors.ErrorTypeBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{header: defaultHeader, defaultTenant: tt.defaultTenant}
+
+			r := httptest.NewRequest(http.MethodGet, "/documents", nil)
+			if tt.header != "" {
+				r.Header.Set(defaultHeader, tt.header)
+			}
+
+			got, err := s.ResolveTenant(r, tt.principal)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveTenant() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !errors.IsType(err, tt.wantType) {
+					t.Errorf("ResolveTenant() error = %v, want error of type %v", err, tt.wantType)
+				}
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("ResolveTenant() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
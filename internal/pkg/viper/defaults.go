@@ -0,0 +1,73 @@
+package viper
+
+// defaults are the built-in values of the configuration, used when no other layer sets a key
+var defaults = map[string]interface{}{
+	"server.port":         8080,
+	"server.timeout":      "15s",
+	"server.cacheControl": "no-cache",
+
+	"server.routes.default.maxBodySize":                     16 << 20,
+	"server.routes.default.compression.enabled":             true,
+	"server.routes.default.compression.minSize":             1024,
+	"server.routes.default.compression.encodings":           []string{"zstd", "gzip", "deflate"},
+	"server.routes.default.compression.decompressRequests":  false,
+	"server.routes.default.compression.maxDecompressedSize": 16 << 20,
+
+	"server.routes.addDocument.maxBodySize":                     16 << 20,
+	"server.routes.addDocument.compression.enabled":             true,
+	"server.routes.addDocument.compression.minSize":             1024,
+	"server.routes.addDocument.compression.encodings":           []string{"zstd", "gzip", "deflate"},
+	"server.routes.addDocument.compression.decompressRequests":  true,
+	"server.routes.addDocument.compression.maxDecompressedSize": 16 << 20,
+
+	"log.level": "info",
+
+	"startup.reconcileIndexes": false,
+
+	"mongo.hosts":                               "localhost:27017",
+	"mongo.readPreference":                      "primary",
+	"mongo.pool.maxSize":                        100,
+	"mongo.timeouts.serverSelection":            "30s",
+	"mongo.timeouts.connect":                    "30s",
+	"mongo.startup.waitForDatabase":             true,
+	"mongo.startup.attemptTimeout":              "5s",
+	"mongo.startup.backoff.initial":             "500ms",
+	"mongo.startup.backoff.max":                 "10s",
+	"mongo.startup.backoff.multiplier":          2,
+	"mongo.startup.backoff.jitter":              0.2,
+	"mongo.resilience.breaker.failureThreshold": 5,
+	"mongo.resilience.breaker.openTimeout":      "30s",
+	"mongo.resilience.breaker.halfOpenMaxCalls": 1,
+	"mongo.resilience.retry.attempts":           3,
+	"mongo.resilience.retry.backoff.initial":    "100ms",
+	"mongo.resilience.retry.backoff.max":        "1s",
+	"mongo.resilience.retry.backoff.multiplier": 2,
+	"mongo.resilience.retry.backoff.jitter":     0.2,
+	"mongo.resilience.timeouts.read":            "5s",
+	"mongo.resilience.timeouts.write":           "10s",
+	"mongo.tenancy":                             "field",
+	"mongo.idStrategy":                          "objectID",
+	"mongo.uniqueNames":                         false,
+	"mongo.indexes.dropUndeclared":              false,
+	"mongo.migrations.lockTTL":                  "10m",
+	"mongo.idempotency.collection":              "idempotencyKeys",
+	"mongo.idempotency.ttl":                     "24h",
+	"mongo.idempotency.reservationTimeout":      "1m",
+
+	"secrets.refreshInterval": "1m",
+
+	"cache.enabled":     false,
+	"cache.ttl":         "1m",
+	"cache.maxEntries":  10000,
+	"cache.maxBytes":    64 << 20,
+	"cache.loadTimeout": "5s",
+
+	"auth.enabled":          true,
+	"auth.jwt.rolesClaim":   "roles",
+	"auth.jwt.tenantClaim":  "tenant",
+	"tenancy.header":        "X-Tenant-ID",
+	"tenancy.defaultTenant": "default",
+
+	"rateLimit.enabled": false,
+	"rateLimit.keyBy":   "principal",
+}
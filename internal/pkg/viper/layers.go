@@ -0,0 +1,169 @@
+package viper
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"microservice/internal/pkg/errors"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultConfigFile is the base configuration file used when no other file is given
+	DefaultConfigFile = "./conf/bootstrapConfiguration.yaml"
+
+	// EnvConfigFile is the environment variable holding the base configuration file
+	EnvConfigFile = envPrefix + "_CONFIG"
+	// EnvEnvironment is the environment variable holding the name of the environment overlay
+	EnvEnvironment = envPrefix + "_ENV"
+
+	envPrefix = "MICROSERVICE"
+)
+
+// Sources of configuration values, from lowest to highest priority
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceOverlay = "overlay"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Options selects the configuration layers
+type Options struct {
+	// File is the base configuration file. EnvConfigFile or DefaultConfigFile is used when empty
+	File string
+	// Environment names the overlay file <Environment>.yaml next to the base file. EnvEnvironment is used when empty
+	Environment string
+	// Overrides are values given on the command line by key
+	Overrides map[string]string
+}
+
+// Setting is an effective configuration value and the layer it came from
+type Setting struct {
+	Key    string
+	Value  interface{}
+	Source string
+}
+
+// layers keeps the files and overrides of the configuration apart so the source of each value can be told
+type layers struct {
+	environment string
+	baseFile    string
+	base        *viper.Viper
+	overlayFile string
+	overlay     *viper.Viper
+	overrides   map[string]string
+}
+
+func loadLayers(opts Options) (layers, error) {
+	l := layers{
+		baseFile:  firstNonEmpty(opts.File, os.Getenv(EnvConfigFile), DefaultConfigFile),
+		overrides: make(map[string]string, len(opts.Overrides)),
+	}
+
+	for key, value := range opts.Overrides {
+		l.overrides[strings.ToLower(key)] = value
+	}
+
+	base, err := readFile(l.baseFile)
+	if err != nil {
+		return layers{}, errors.Wrap(err, "Failed to load configuration")
+	}
+	l.base = base
+
+	environment := firstNonEmpty(opts.Environment, os.Getenv(EnvEnvironment))
+	if environment == "" {
+		return l, nil
+	}
+	l.environment = environment
+
+	l.overlayFile = filepath.Join(filepath.Dir(l.baseFile), environment+filepath.Ext(l.baseFile))
+	overlay, err := readFile(l.overlayFile)
+	if err != nil {
+		return layers{}, errors.Wrapf(err, "Failed to load configuration overlay for environment (%s)", environment)
+	}
+	l.overlay = overlay
+
+	return l, nil
+}
+
+func readFile(file string) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigFile(file)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// source returns the layer the effective value of key came from
+func (l layers) source(key string) string {
+	if _, ok := l.overrides[key]; ok {
+		return SourceFlag
+	}
+
+	if name := envName(key); os.Getenv(name) != "" {
+		return SourceEnv + " " + name
+	}
+
+	if l.overlay != nil && l.overlay.IsSet(key) {
+		return SourceOverlay + " " + l.overlayFile
+	}
+
+	if l.base.IsSet(key) {
+		return SourceFile + " " + l.baseFile
+	}
+
+	return SourceDefault
+}
+
+// Environment returns the name of the environment overlay of the configuration, empty when there is none
+func (v *Service) Environment() string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.layers.environment
+}
+
+// Settings returns the effective configuration values sorted by key, with secrets redacted
+func (v *Service) Settings() []Setting {
+	v.mu.RLock()
+	c, l := v.v, v.layers
+	v.mu.RUnlock()
+
+	keys := c.AllKeys()
+	sort.Strings(keys)
+
+	settings := make([]Setting, 0, len(keys))
+	for _, key := range keys {
+		name := key[strings.LastIndex(key, ".")+1:]
+		settings = append(settings, Setting{
+			Key:    key,
+			Value:  redact(c.Get(key), isSecretKey(name)),
+			Source: l.source(key),
+		})
+	}
+
+	return settings
+}
+
+// envName returns the environment variable that sets key
+func envName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
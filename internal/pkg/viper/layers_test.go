@@ -0,0 +1,159 @@
+package viper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigFile writes a configuration file named name in dir and returns its path
+func writeConfigFile(t *testing.T, dir string, name string, content string) string {
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write configuration file (%s). Error: %s", file, err)
+	}
+
+	return file
+}
+
+func TestNewConfiguration_layers(t *testing.T) {
+	dir := t.TempDir()
+	base := writeConfigFile(t, dir, "base.yaml", `
+log:
+  level: "debug"
+mongo:
+  database: "baseDatabase"
+  collection: "baseCollection"
+  hosts: "base:27017"
+  username: "baseUser"
+`)
+	writeConfigFile(t, dir, "production.yaml", `
+mongo:
+  collection: "overlayCollection"
+  hosts: "overlay:27017"
+  username: "overlayUser"
+`)
+
+	t.Setenv(envName("mongo.hosts"), "env:27017")
+	t.Setenv(envName("mongo.username"), "envUser")
+
+	v, err := NewConfiguration(Options{
+		File:        base,
+		Environment: "production",
+		Overrides:   map[string]string{"mongo.username": "flagUser"},
+	})
+	if err != nil {
+		t.Fatalf("NewConfiguration() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		want       string
+		wantSource string
+	}{
+		{
+			name:       "key set by no layer expect built-in default",
+			key:        "mongo.readpreference",
+			want:       "primary",
+			wantSource: SourceDefault,
+		},
+		{
+			name:       "key set by base file over default expect base file value",
+			key:        "log.level",
+			want:       "debug",
+			wantSource: SourceFile + " " + base,
+		},
+		{
+			name:       "key set by base file only expect base file value",
+			key:        "mongo.database",
+			want:       "baseDatabase",
+			wantSource: SourceFile + " " + base,
+		},
+		{
+			name:       "key set by base file and overlay expect overlay value",
+			key:        "mongo.collection",
+			want:       "overlayCollection",
+			wantSource: SourceOverlay + " " + filepath.Join(dir, "production.yaml"),
+		},
+		{
+			name:       "key set by files and environment variable expect environment variable value",
+			key:        "mongo.hosts",
+			want:       "env:27017",
+			wantSource: SourceEnv + " " + envName("mongo.hosts"),
+		},
+		{
+			name:       "key set by every layer expect flag value",
+			key:        "mongo.username",
+			want:       "flagUser",
+			wantSource: SourceFlag,
+		},
+	}
+	settings := make(map[string]Setting)
+	for _, s := range v.Settings() {
+		settings[s.Key] = s
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.GetString(tt.key)
+			if err != nil {
+				t.Fatalf("GetString() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("GetString() got = %v, want %v", got, tt.want)
+			}
+
+			if source := settings[tt.key].Source; source != tt.wantSource {
+				t.Errorf("Settings() source of (%s) = %v, want %v", tt.key, source, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestNewConfiguration_missingOverlay(t *testing.T) {
+	base := writeConfigFile(t, t.TempDir(), "base.yaml", "log:\n  level: debug\n")
+
+	_, err := NewConfiguration(Options{File: base, Environment: "staging"})
+	if err == nil || !strings.Contains(err.Error(), "staging") {
+		t.Errorf("NewConfiguration() error = %v, want error of the missing overlay", err)
+	}
+}
+
+func TestService_Settings_redactsCredentials(t *testing.T) {
+	base := writeConfigFile(t, t.TempDir(), "base.yaml", `
+auth:
+  apiKeys:
+    - key: "fileApiKey"
+      subject: "service"
+mongo:
+  password: "filePassword"
+`)
+	t.Setenv(envName("auth.jwt.secret"), "envSecret")
+
+	v, err := NewConfiguration(Options{
+		File:      base,
+		Overrides: map[string]string{"mongo.uri": "mongodb://bob:flagPassword@db:27017/documents"},
+	})
+	if err != nil {
+		t.Fatalf("NewConfiguration() error = %v", err)
+	}
+
+	settings := make(map[string]interface{})
+	for _, s := range v.Settings() {
+		printed := fmt.Sprint(s.Value)
+		for _, secret := range []string{"fileApiKey", "filePassword", "envSecret", "flagPassword"} {
+			if strings.Contains(printed, secret) {
+				t.Errorf("Settings() value of (%s) = %v, contains secret (%s)", s.Key, printed, secret)
+			}
+		}
+		settings[s.Key] = s.Value
+	}
+
+	if want := "mongodb://bob:" + redactedValue + "@db:27017/documents"; settings["mongo.uri"] != want {
+		t.Errorf("Settings() value of (mongo.uri) = %v, want %v", settings["mongo.uri"], want)
+	}
+}
@@ -0,0 +1,118 @@
+package viper
+
+import (
+	"reflect"
+	"strings"
+
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/errors"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// subscriber is notified on reload when the value of one of its keys changed
+type subscriber struct {
+	keys    []string
+	handler config.ChangeHandler
+}
+
+// Subscribe registers handler for changes of keys on reload. A key covers every key nested under it
+func (v *Service) Subscribe(keys []string, handler config.ChangeHandler) {
+	lowered := make([]string, len(keys))
+	for i, key := range keys {
+		lowered[i] = strings.ToLower(key)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.subscribers = append(v.subscribers, subscriber{keys: lowered, handler: handler})
+}
+
+// Reload reads the configuration layers again. The new configuration replaces the current one only if every
+// subscriber of a changed key accepts it, otherwise the current configuration is kept and the error returned
+func (v *Service) Reload() error {
+	v.reloading.Lock()
+	defer v.reloading.Unlock()
+
+	next, l, err := build(v.opts)
+	if err != nil {
+		return errors.Wrap(err, "Failed to reload configuration")
+	}
+
+	v.mu.RLock()
+	prev, subscribers := v.v, v.subscribers
+	v.mu.RUnlock()
+
+	candidate := &Service{v: next, layers: l}
+
+	var applies []func()
+	for _, s := range subscribers {
+		if !changed(prev, next, s.keys) {
+			continue
+		}
+
+		apply, err := s.handler(candidate)
+		if err != nil {
+			return errors.Wrapf(err, "Rejected configuration change of keys (%s)", strings.Join(s.keys, ", "))
+		}
+
+		if apply != nil {
+			applies = append(applies, apply)
+		}
+	}
+
+	v.mu.Lock()
+	v.v, v.layers = next, l
+	v.mu.Unlock()
+
+	for _, apply := range applies {
+		apply()
+	}
+
+	return nil
+}
+
+// Watch reloads the configuration whenever one of its files changes and passes the outcome of the reload to onReload
+func (v *Service) Watch(onReload func(err error)) {
+	v.mu.RLock()
+	files := []string{v.layers.baseFile}
+	if v.layers.overlay != nil {
+		files = append(files, v.layers.overlayFile)
+	}
+	v.mu.RUnlock()
+
+	for _, file := range files {
+		// the watcher reads the file on its own, apart from the layers of the current configuration
+		w := viper.New()
+		w.SetConfigFile(file)
+		w.OnConfigChange(func(fsnotify.Event) {
+			onReload(v.Reload())
+		})
+		w.WatchConfig()
+	}
+}
+
+// changed reports whether a key under one of keys has a different value in next than in prev
+func changed(prev *viper.Viper, next *viper.Viper, keys []string) bool {
+	for _, c := range []*viper.Viper{prev, next} {
+		for _, key := range c.AllKeys() {
+			if covered(key, keys) && !reflect.DeepEqual(prev.Get(key), next.Get(key)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func covered(key string, keys []string) bool {
+	for _, k := range keys {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+
+	return false
+}
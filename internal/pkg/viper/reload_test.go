@@ -0,0 +1,105 @@
+package viper
+
+import (
+	"testing"
+
+	"microservice/internal/pkg/config"
+	"microservice/internal/pkg/errors"
+)
+
+func TestService_Reload(t *testing.T) {
+	const initial = `
+log:
+  level: "info"
+rateLimit:
+  enabled: true
+`
+
+	accept := func(applied *bool) config.ChangeHandler {
+		return func(config.Values) (func(), error) {
+			return func() { *applied = true }, nil
+		}
+	}
+
+	reject := func(config.Values) (func(), error) {
+		return nil, errors.New("invalid change")
+	}
+
+	tests := []struct {
+		name            string
+		reloaded        string
+		rateLimitReject bool
+		wantErr         bool
+		wantLevel       string
+		wantLogApplied  bool
+		wantRateCalled  bool
+	}{
+		{
+			name:           "changed key accepted by subscriber expect new value applied and unchanged keys not notified",
+			reloaded:       "log:\n  level: \"debug\"\nrateLimit:\n  enabled: true\n",
+			wantErr:        false,
+			wantLevel:      "debug",
+			wantLogApplied: true,
+		},
+		{
+			name:            "change rejected by a subscriber expect previous configuration kept and nothing applied",
+			reloaded:        "log:\n  level: \"debug\"\nrateLimit:\n  enabled: false\n",
+			rateLimitReject: true,
+			wantErr:         true,
+			wantLevel:       "info",
+			wantLogApplied:  false,
+			wantRateCalled:  true,
+		},
+		{
+			name:           "malformed file expect previous configuration kept",
+			reloaded:       "log: [\n",
+			wantErr:        true,
+			wantLevel:      "info",
+			wantLogApplied: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := writeConfigFile(t, dir, "base.yaml", initial)
+
+			v, err := NewConfiguration(Options{File: file})
+			if err != nil {
+				t.Fatalf("NewConfiguration() error = %v", err)
+			}
+
+			logApplied, rateApplied, rateCalled := false, false, false
+			v.Subscribe([]string{"log.level"}, accept(&logApplied))
+			v.Subscribe([]string{"rateLimit"}, func(candidate config.Values) (func(), error) {
+				rateCalled = true
+				if tt.rateLimitReject {
+					return reject(candidate)
+				}
+				return accept(&rateApplied)(candidate)
+			})
+
+			writeConfigFile(t, dir, "base.yaml", tt.reloaded)
+
+			if err := v.Reload(); (err != nil) != tt.wantErr {
+				t.Fatalf("Reload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			level, err := v.GetString("log.level")
+			if err != nil {
+				t.Fatalf("GetString() error = %v", err)
+			}
+
+			if level != tt.wantLevel {
+				t.Errorf("Reload() log.level = %v, want %v", level, tt.wantLevel)
+			}
+
+			if logApplied != tt.wantLogApplied {
+				t.Errorf("Reload() applied log change = %v, want %v", logApplied, tt.wantLogApplied)
+			}
+
+			if rateCalled != tt.wantRateCalled {
+				t.Errorf("Reload() called rate limit subscriber = %v, want %v", rateCalled, tt.wantRateCalled)
+			}
+		})
+	}
+}
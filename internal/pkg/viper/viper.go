@@ -1,55 +1,122 @@
 package viper
 
 import (
+	"strings"
+	"sync"
 	"time"
 
+	"microservice/internal/pkg/config"
 	"microservice/internal/pkg/errors"
 
 	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 )
 
+// redactedValue replaces the values of secret configuration keys
+const redactedValue = "[REDACTED]"
+
+// secretKeyParts are parts of configuration key names whose values are secrets
+var secretKeyParts = []string{"password", "secret", "token", "credential", "privatekey"}
+
 // Service implements the configuration service
 type Service struct {
-	v *viper.Viper
-}
+	opts Options
 
-// NewConfiguration returns a new instance of the Service struct
-func NewConfiguration() (*Service, error) {
-	v := viper.New()
-	v.SetConfigFile("./conf/bootstrapConfiguration.yaml")
+	mu          sync.RWMutex
+	v           *viper.Viper
+	layers      layers
+	subscribers []subscriber
+
+	// reloading serializes reloads
+	reloading sync.Mutex
+}
 
-	if err := v.ReadInConfig(); err != nil {
-		return nil, errors.Wrap(err, "Failed to load configuration")
+// NewConfiguration returns a new instance of the Service struct with the configuration layers given by opts
+func NewConfiguration(opts Options) (*Service, error) {
+	v, l, err := build(opts)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Service{
-		v: v,
+		opts:   opts,
+		v:      v,
+		layers: l,
 	}, nil
 }
 
+// build reads the configuration layers given by opts and merges them
+func build(opts Options) (*viper.Viper, layers, error) {
+	l, err := loadLayers(opts)
+	if err != nil {
+		return nil, layers{}, err
+	}
+
+	v := viper.New()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+
+	if err := v.MergeConfigMap(l.base.AllSettings()); err != nil {
+		return nil, layers{}, errors.Wrap(err, "Failed to load configuration")
+	}
+
+	if l.overlay != nil {
+		if err := v.MergeConfigMap(l.overlay.AllSettings()); err != nil {
+			return nil, layers{}, errors.Wrap(err, "Failed to load configuration overlay")
+		}
+	}
+
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	for key, value := range l.overrides {
+		v.Set(key, value)
+	}
+
+	return v, l, nil
+}
+
+// current returns the current configuration, which is replaced on reload
+func (v *Service) current() *viper.Viper {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.v
+}
+
+// Unmarshal decodes the effective configuration into target
+func (v *Service) Unmarshal(target interface{}) error {
+	return v.current().Unmarshal(target)
+}
+
 // IsSet checks if the requested key exists
 func (v *Service) IsSet(key string) bool {
-	return v.v.IsSet(key)
+	return v.current().IsSet(key)
 }
 
 // Set places the key and value in configuration service
 func (v *Service) Set(key string, value interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
 	v.v.Set(key, value)
 }
 
 // Get returns the value for the requested key as interface{}
 func (v *Service) Get(key string) interface{} {
-	return v.v.Get(key)
+	return v.current().Get(key)
 }
 
 // GetString returns the value for the requested key as string
 func (v *Service) GetString(key string) (string, error) {
-	if !v.v.IsSet(key) {
+	c := v.current()
+	if !c.IsSet(key) {
 		return "", keyNotFoundError(key)
 	}
 
-	value, err := cast.ToStringE(v.v.Get(key))
+	value, err := cast.ToStringE(c.Get(key))
 	if err != nil {
 		return "", invalidDataTypeError(key)
 	}
@@ -59,11 +126,12 @@ func (v *Service) GetString(key string) (string, error) {
 
 // GetInt returns the value for the requested key as int
 func (v *Service) GetInt(key string) (int, error) {
-	if !v.v.IsSet(key) {
+	c := v.current()
+	if !c.IsSet(key) {
 		return 0, keyNotFoundError(key)
 	}
 
-	value, err := cast.ToIntE(v.v.Get(key))
+	value, err := cast.ToIntE(c.Get(key))
 	if err != nil {
 		return 0, invalidDataTypeError(key)
 	}
@@ -73,11 +141,12 @@ func (v *Service) GetInt(key string) (int, error) {
 
 // GetDuration returns the value for the requested key as duration
 func (v *Service) GetDuration(key string) (time.Duration, error) {
-	if !v.v.IsSet(key) {
+	c := v.current()
+	if !c.IsSet(key) {
 		return 0, keyNotFoundError(key)
 	}
 
-	value, err := time.ParseDuration(v.v.GetString(key))
+	value, err := time.ParseDuration(c.GetString(key))
 	if err != nil {
 		return 0, invalidDataTypeError(key)
 	}
@@ -87,11 +156,12 @@ func (v *Service) GetDuration(key string) (time.Duration, error) {
 
 // GetBool returns the value for the requested key as bool
 func (v *Service) GetBool(key string) (bool, error) {
-	if !v.v.IsSet(key) {
+	c := v.current()
+	if !c.IsSet(key) {
 		return false, keyNotFoundError(key)
 	}
 
-	value, err := cast.ToBoolE(v.v.Get(key))
+	value, err := cast.ToBoolE(c.Get(key))
 	if err != nil {
 		return false, invalidDataTypeError(key)
 	}
@@ -99,6 +169,58 @@ func (v *Service) GetBool(key string) (bool, error) {
 	return value, nil
 }
 
+// redact replaces secret values in value. secret marks values nested under a secret key, like the entries of api keys.
+// Connection strings keep everything but their password, whatever key they are under
+func redact(value interface{}, secret bool) interface{} {
+	switch val := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(val))
+		for k, nested := range val {
+			redacted[k] = redact(nested, isSecretKey(k))
+		}
+		return redacted
+	case map[interface{}]interface{}:
+		redacted := make(map[string]interface{}, len(val))
+		for k, nested := range val {
+			key := cast.ToString(k)
+			redacted[key] = redact(nested, isSecretKey(key))
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(val))
+		for i, nested := range val {
+			redacted[i] = redact(nested, secret)
+		}
+		return redacted
+	}
+
+	if secret && value != nil && value != "" {
+		return redactedValue
+	}
+
+	if s, ok := value.(string); ok && strings.Contains(s, "://") {
+		return config.RedactURI(s)
+	}
+
+	return value
+}
+
+// isSecretKey reports whether the values of key are secrets. Api keys are listed under a key named "key"
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	if key == "key" || key == "apikey" {
+		return true
+	}
+
+	for _, part := range secretKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func keyNotFoundError(key string) error {
 	return errors.Errorf("Failed to get key (%s) from configuration", key).SetType(errors.ErrorTypeNotFound)
 }
@@ -0,0 +1,166 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: microservice/internal/pkg/cache (interfaces: Store)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	models "microservice/models"
+	reflect "reflect"
+)
+
+// MockCacheStore is a mock of Store interface
+type MockCacheStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockCacheStoreMockRecorder
+}
+
+// MockCacheStoreMockRecorder is the mock recorder for MockCacheStore
+type MockCacheStoreMockRecorder struct {
+	mock *MockCacheStore
+}
+
+// NewMockCacheStore creates a new mock instance
+func NewMockCacheStore(ctrl *gomock.Controller) *MockCacheStore {
+	mock := &MockCacheStore{ctrl: ctrl}
+	mock.recorder = &MockCacheStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCacheStore) EXPECT() *MockCacheStoreMockRecorder {
+	return m.recorder
+}
+
+// CountDocuments mocks base method
+func (m *MockCacheStore) CountDocuments(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountDocuments", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountDocuments indicates an expected call of CountDocuments
+func (mr *MockCacheStoreMockRecorder) CountDocuments(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountDocuments", reflect.TypeOf((*MockCacheStore)(nil).CountDocuments), arg0)
+}
+
+// GetDocumentByID mocks base method
+func (m *MockCacheStore) GetDocumentByID(arg0 context.Context, arg1 string, arg2 models.ReadOptions, arg3 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDocumentByID", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetDocumentByID indicates an expected call of GetDocumentByID
+func (mr *MockCacheStoreMockRecorder) GetDocumentByID(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDocumentByID", reflect.TypeOf((*MockCacheStore)(nil).GetDocumentByID), arg0, arg1, arg2, arg3)
+}
+
+// GetDocumentByName mocks base method
+func (m *MockCacheStore) GetDocumentByName(arg0 context.Context, arg1 string, arg2 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDocumentByName", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetDocumentByName indicates an expected call of GetDocumentByName
+func (mr *MockCacheStoreMockRecorder) GetDocumentByName(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDocumentByName", reflect.TypeOf((*MockCacheStore)(nil).GetDocumentByName), arg0, arg1, arg2)
+}
+
+// SaveDocument mocks base method
+func (m *MockCacheStore) SaveDocument(arg0 context.Context, arg1 models.Document) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDocument", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveDocument indicates an expected call of SaveDocument
+func (mr *MockCacheStoreMockRecorder) SaveDocument(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDocument", reflect.TypeOf((*MockCacheStore)(nil).SaveDocument), arg0, arg1)
+}
+
+// SaveDocumentWithID mocks base method
+func (m *MockCacheStore) SaveDocumentWithID(arg0 context.Context, arg1 string, arg2 models.Document) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDocumentWithID", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDocumentWithID indicates an expected call of SaveDocumentWithID
+func (mr *MockCacheStoreMockRecorder) SaveDocumentWithID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDocumentWithID", reflect.TypeOf((*MockCacheStore)(nil).SaveDocumentWithID), arg0, arg1, arg2)
+}
+
+// SearchDocuments mocks base method
+func (m *MockCacheStore) SearchDocuments(arg0 context.Context, arg1 models.SearchQuery) ([]models.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchDocuments", arg0, arg1)
+	ret0, _ := ret[0].([]models.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchDocuments indicates an expected call of SearchDocuments
+func (mr *MockCacheStoreMockRecorder) SearchDocuments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchDocuments", reflect.TypeOf((*MockCacheStore)(nil).SearchDocuments), arg0, arg1)
+}
+
+// Teardown mocks base method
+func (m *MockCacheStore) Teardown(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Teardown", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Teardown indicates an expected call of Teardown
+func (mr *MockCacheStoreMockRecorder) Teardown(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Teardown", reflect.TypeOf((*MockCacheStore)(nil).Teardown), arg0)
+}
+
+// UpdateDocument mocks base method
+func (m *MockCacheStore) UpdateDocument(arg0 context.Context, arg1 string, arg2 models.Document) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDocument", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDocument indicates an expected call of UpdateDocument
+func (mr *MockCacheStoreMockRecorder) UpdateDocument(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDocument", reflect.TypeOf((*MockCacheStore)(nil).UpdateDocument), arg0, arg1, arg2)
+}
+
+// UpsertDocumentByName mocks base method
+func (m *MockCacheStore) UpsertDocumentByName(arg0 context.Context, arg1 models.Document) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertDocumentByName", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// UpsertDocumentByName indicates an expected call of UpsertDocumentByName
+func (mr *MockCacheStoreMockRecorder) UpsertDocumentByName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertDocumentByName", reflect.TypeOf((*MockCacheStore)(nil).UpsertDocumentByName), arg0, arg1)
+}
@@ -0,0 +1,72 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: microservice/internal/app (interfaces: ConfigReloader)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	config "microservice/internal/pkg/config"
+	reflect "reflect"
+)
+
+// MockConfigReloader is a mock of ConfigReloader interface
+type MockConfigReloader struct {
+	ctrl     *gomock.Controller
+	recorder *MockConfigReloaderMockRecorder
+}
+
+// MockConfigReloaderMockRecorder is the mock recorder for MockConfigReloader
+type MockConfigReloaderMockRecorder struct {
+	mock *MockConfigReloader
+}
+
+// NewMockConfigReloader creates a new mock instance
+func NewMockConfigReloader(ctrl *gomock.Controller) *MockConfigReloader {
+	mock := &MockConfigReloader{ctrl: ctrl}
+	mock.recorder = &MockConfigReloaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockConfigReloader) EXPECT() *MockConfigReloaderMockRecorder {
+	return m.recorder
+}
+
+// Reload mocks base method
+func (m *MockConfigReloader) Reload() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reload")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reload indicates an expected call of Reload
+func (mr *MockConfigReloaderMockRecorder) Reload() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reload", reflect.TypeOf((*MockConfigReloader)(nil).Reload))
+}
+
+// Subscribe mocks base method
+func (m *MockConfigReloader) Subscribe(arg0 []string, arg1 config.ChangeHandler) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Subscribe", arg0, arg1)
+}
+
+// Subscribe indicates an expected call of Subscribe
+func (mr *MockConfigReloaderMockRecorder) Subscribe(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockConfigReloader)(nil).Subscribe), arg0, arg1)
+}
+
+// Watch mocks base method
+func (m *MockConfigReloader) Watch(arg0 func(error)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Watch", arg0)
+}
+
+// Watch indicates an expected call of Watch
+func (mr *MockConfigReloaderMockRecorder) Watch(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockConfigReloader)(nil).Watch), arg0)
+}
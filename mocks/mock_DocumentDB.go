@@ -34,18 +34,47 @@ func (m *MockDocumentDB) EXPECT() *MockDocumentDBMockRecorder {
 	return m.recorder
 }
 
+// CountDocuments mocks base method
+func (m *MockDocumentDB) CountDocuments(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountDocuments", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountDocuments indicates an expected call of CountDocuments
+func (mr *MockDocumentDBMockRecorder) CountDocuments(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountDocuments", reflect.TypeOf((*MockDocumentDB)(nil).CountDocuments), arg0)
+}
+
 // GetDocumentByID mocks base method
-func (m *MockDocumentDB) GetDocumentByID(arg0 context.Context, arg1 string, arg2 interface{}) error {
+func (m *MockDocumentDB) GetDocumentByID(arg0 context.Context, arg1 string, arg2 models.ReadOptions, arg3 interface{}) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetDocumentByID", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "GetDocumentByID", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // GetDocumentByID indicates an expected call of GetDocumentByID
-func (mr *MockDocumentDBMockRecorder) GetDocumentByID(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockDocumentDBMockRecorder) GetDocumentByID(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDocumentByID", reflect.TypeOf((*MockDocumentDB)(nil).GetDocumentByID), arg0, arg1, arg2, arg3)
+}
+
+// GetDocumentByName mocks base method
+func (m *MockDocumentDB) GetDocumentByName(arg0 context.Context, arg1 string, arg2 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDocumentByName", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetDocumentByName indicates an expected call of GetDocumentByName
+func (mr *MockDocumentDBMockRecorder) GetDocumentByName(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDocumentByID", reflect.TypeOf((*MockDocumentDB)(nil).GetDocumentByID), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDocumentByName", reflect.TypeOf((*MockDocumentDB)(nil).GetDocumentByName), arg0, arg1, arg2)
 }
 
 // SaveDocument mocks base method
@@ -63,6 +92,35 @@ func (mr *MockDocumentDBMockRecorder) SaveDocument(arg0, arg1 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDocument", reflect.TypeOf((*MockDocumentDB)(nil).SaveDocument), arg0, arg1)
 }
 
+// SaveDocumentWithID mocks base method
+func (m *MockDocumentDB) SaveDocumentWithID(arg0 context.Context, arg1 string, arg2 models.Document) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDocumentWithID", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDocumentWithID indicates an expected call of SaveDocumentWithID
+func (mr *MockDocumentDBMockRecorder) SaveDocumentWithID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDocumentWithID", reflect.TypeOf((*MockDocumentDB)(nil).SaveDocumentWithID), arg0, arg1, arg2)
+}
+
+// SearchDocuments mocks base method
+func (m *MockDocumentDB) SearchDocuments(arg0 context.Context, arg1 models.SearchQuery) ([]models.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchDocuments", arg0, arg1)
+	ret0, _ := ret[0].([]models.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchDocuments indicates an expected call of SearchDocuments
+func (mr *MockDocumentDBMockRecorder) SearchDocuments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchDocuments", reflect.TypeOf((*MockDocumentDB)(nil).SearchDocuments), arg0, arg1)
+}
+
 // Teardown mocks base method
 func (m *MockDocumentDB) Teardown(arg0 context.Context) error {
 	m.ctrl.T.Helper()
@@ -76,3 +134,33 @@ func (mr *MockDocumentDBMockRecorder) Teardown(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Teardown", reflect.TypeOf((*MockDocumentDB)(nil).Teardown), arg0)
 }
+
+// UpdateDocument mocks base method
+func (m *MockDocumentDB) UpdateDocument(arg0 context.Context, arg1 string, arg2 models.Document) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDocument", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDocument indicates an expected call of UpdateDocument
+func (mr *MockDocumentDBMockRecorder) UpdateDocument(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDocument", reflect.TypeOf((*MockDocumentDB)(nil).UpdateDocument), arg0, arg1, arg2)
+}
+
+// UpsertDocumentByName mocks base method
+func (m *MockDocumentDB) UpsertDocumentByName(arg0 context.Context, arg1 models.Document) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertDocumentByName", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// UpsertDocumentByName indicates an expected call of UpsertDocumentByName
+func (mr *MockDocumentDBMockRecorder) UpsertDocumentByName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertDocumentByName", reflect.TypeOf((*MockDocumentDB)(nil).UpsertDocumentByName), arg0, arg1)
+}
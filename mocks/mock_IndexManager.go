@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: microservice/internal/app (interfaces: IndexManager)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	models "microservice/models"
+	reflect "reflect"
+)
+
+// MockIndexManager is a mock of IndexManager interface
+type MockIndexManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockIndexManagerMockRecorder
+}
+
+// MockIndexManagerMockRecorder is the mock recorder for MockIndexManager
+type MockIndexManagerMockRecorder struct {
+	mock *MockIndexManager
+}
+
+// NewMockIndexManager creates a new mock instance
+func NewMockIndexManager(ctrl *gomock.Controller) *MockIndexManager {
+	mock := &MockIndexManager{ctrl: ctrl}
+	mock.recorder = &MockIndexManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIndexManager) EXPECT() *MockIndexManagerMockRecorder {
+	return m.recorder
+}
+
+// ReconcileIndexes mocks base method
+func (m *MockIndexManager) ReconcileIndexes(arg0 context.Context) ([]models.IndexReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileIndexes", arg0)
+	ret0, _ := ret[0].([]models.IndexReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReconcileIndexes indicates an expected call of ReconcileIndexes
+func (mr *MockIndexManagerMockRecorder) ReconcileIndexes(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileIndexes", reflect.TypeOf((*MockIndexManager)(nil).ReconcileIndexes), arg0)
+}
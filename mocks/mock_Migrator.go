@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: microservice/internal/app (interfaces: Migrator)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	models "microservice/models"
+	reflect "reflect"
+)
+
+// MockMigrator is a mock of Migrator interface
+type MockMigrator struct {
+	ctrl     *gomock.Controller
+	recorder *MockMigratorMockRecorder
+}
+
+// MockMigratorMockRecorder is the mock recorder for MockMigrator
+type MockMigratorMockRecorder struct {
+	mock *MockMigrator
+}
+
+// NewMockMigrator creates a new mock instance
+func NewMockMigrator(ctrl *gomock.Controller) *MockMigrator {
+	mock := &MockMigrator{ctrl: ctrl}
+	mock.recorder = &MockMigratorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockMigrator) EXPECT() *MockMigratorMockRecorder {
+	return m.recorder
+}
+
+// MigrateDown mocks base method
+func (m *MockMigrator) MigrateDown(arg0 context.Context, arg1 bool) ([]models.MigrationResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateDown", arg0, arg1)
+	ret0, _ := ret[0].([]models.MigrationResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MigrateDown indicates an expected call of MigrateDown
+func (mr *MockMigratorMockRecorder) MigrateDown(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateDown", reflect.TypeOf((*MockMigrator)(nil).MigrateDown), arg0, arg1)
+}
+
+// MigrateUp mocks base method
+func (m *MockMigrator) MigrateUp(arg0 context.Context, arg1 bool) ([]models.MigrationResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateUp", arg0, arg1)
+	ret0, _ := ret[0].([]models.MigrationResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MigrateUp indicates an expected call of MigrateUp
+func (mr *MockMigratorMockRecorder) MigrateUp(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateUp", reflect.TypeOf((*MockMigrator)(nil).MigrateUp), arg0, arg1)
+}
+
+// MigrationStatus mocks base method
+func (m *MockMigrator) MigrationStatus(arg0 context.Context) ([]models.MigrationStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrationStatus", arg0)
+	ret0, _ := ret[0].([]models.MigrationStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MigrationStatus indicates an expected call of MigrationStatus
+func (mr *MockMigratorMockRecorder) MigrationStatus(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrationStatus", reflect.TypeOf((*MockMigrator)(nil).MigrationStatus), arg0)
+}
@@ -0,0 +1,48 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: microservice/internal/app/domain (interfaces: QuotaProvider)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	models "microservice/models"
+	reflect "reflect"
+)
+
+// MockQuotaProvider is a mock of QuotaProvider interface
+type MockQuotaProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockQuotaProviderMockRecorder
+}
+
+// MockQuotaProviderMockRecorder is the mock recorder for MockQuotaProvider
+type MockQuotaProviderMockRecorder struct {
+	mock *MockQuotaProvider
+}
+
+// NewMockQuotaProvider creates a new mock instance
+func NewMockQuotaProvider(ctrl *gomock.Controller) *MockQuotaProvider {
+	mock := &MockQuotaProvider{ctrl: ctrl}
+	mock.recorder = &MockQuotaProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockQuotaProvider) EXPECT() *MockQuotaProviderMockRecorder {
+	return m.recorder
+}
+
+// Quota mocks base method
+func (m *MockQuotaProvider) Quota(arg0 string) models.TenantQuota {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Quota", arg0)
+	ret0, _ := ret[0].(models.TenantQuota)
+	return ret0
+}
+
+// Quota indicates an expected call of Quota
+func (mr *MockQuotaProviderMockRecorder) Quota(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Quota", reflect.TypeOf((*MockQuotaProvider)(nil).Quota), arg0)
+}
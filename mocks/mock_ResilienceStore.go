@@ -0,0 +1,180 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: microservice/internal/pkg/resilience (interfaces: Store)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	models "microservice/models"
+	reflect "reflect"
+)
+
+// MockResilienceStore is a mock of Store interface
+type MockResilienceStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockResilienceStoreMockRecorder
+}
+
+// MockResilienceStoreMockRecorder is the mock recorder for MockResilienceStore
+type MockResilienceStoreMockRecorder struct {
+	mock *MockResilienceStore
+}
+
+// NewMockResilienceStore creates a new mock instance
+func NewMockResilienceStore(ctrl *gomock.Controller) *MockResilienceStore {
+	mock := &MockResilienceStore{ctrl: ctrl}
+	mock.recorder = &MockResilienceStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockResilienceStore) EXPECT() *MockResilienceStoreMockRecorder {
+	return m.recorder
+}
+
+// CheckHealth mocks base method
+func (m *MockResilienceStore) CheckHealth(arg0 context.Context) []models.HealthCheck {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckHealth", arg0)
+	ret0, _ := ret[0].([]models.HealthCheck)
+	return ret0
+}
+
+// CheckHealth indicates an expected call of CheckHealth
+func (mr *MockResilienceStoreMockRecorder) CheckHealth(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckHealth", reflect.TypeOf((*MockResilienceStore)(nil).CheckHealth), arg0)
+}
+
+// CountDocuments mocks base method
+func (m *MockResilienceStore) CountDocuments(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountDocuments", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountDocuments indicates an expected call of CountDocuments
+func (mr *MockResilienceStoreMockRecorder) CountDocuments(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountDocuments", reflect.TypeOf((*MockResilienceStore)(nil).CountDocuments), arg0)
+}
+
+// GetDocumentByID mocks base method
+func (m *MockResilienceStore) GetDocumentByID(arg0 context.Context, arg1 string, arg2 models.ReadOptions, arg3 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDocumentByID", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetDocumentByID indicates an expected call of GetDocumentByID
+func (mr *MockResilienceStoreMockRecorder) GetDocumentByID(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDocumentByID", reflect.TypeOf((*MockResilienceStore)(nil).GetDocumentByID), arg0, arg1, arg2, arg3)
+}
+
+// GetDocumentByName mocks base method
+func (m *MockResilienceStore) GetDocumentByName(arg0 context.Context, arg1 string, arg2 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDocumentByName", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetDocumentByName indicates an expected call of GetDocumentByName
+func (mr *MockResilienceStoreMockRecorder) GetDocumentByName(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDocumentByName", reflect.TypeOf((*MockResilienceStore)(nil).GetDocumentByName), arg0, arg1, arg2)
+}
+
+// SaveDocument mocks base method
+func (m *MockResilienceStore) SaveDocument(arg0 context.Context, arg1 models.Document) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDocument", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveDocument indicates an expected call of SaveDocument
+func (mr *MockResilienceStoreMockRecorder) SaveDocument(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDocument", reflect.TypeOf((*MockResilienceStore)(nil).SaveDocument), arg0, arg1)
+}
+
+// SaveDocumentWithID mocks base method
+func (m *MockResilienceStore) SaveDocumentWithID(arg0 context.Context, arg1 string, arg2 models.Document) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDocumentWithID", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDocumentWithID indicates an expected call of SaveDocumentWithID
+func (mr *MockResilienceStoreMockRecorder) SaveDocumentWithID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDocumentWithID", reflect.TypeOf((*MockResilienceStore)(nil).SaveDocumentWithID), arg0, arg1, arg2)
+}
+
+// SearchDocuments mocks base method
+func (m *MockResilienceStore) SearchDocuments(arg0 context.Context, arg1 models.SearchQuery) ([]models.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchDocuments", arg0, arg1)
+	ret0, _ := ret[0].([]models.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchDocuments indicates an expected call of SearchDocuments
+func (mr *MockResilienceStoreMockRecorder) SearchDocuments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchDocuments", reflect.TypeOf((*MockResilienceStore)(nil).SearchDocuments), arg0, arg1)
+}
+
+// Teardown mocks base method
+func (m *MockResilienceStore) Teardown(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Teardown", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Teardown indicates an expected call of Teardown
+func (mr *MockResilienceStoreMockRecorder) Teardown(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Teardown", reflect.TypeOf((*MockResilienceStore)(nil).Teardown), arg0)
+}
+
+// UpdateDocument mocks base method
+func (m *MockResilienceStore) UpdateDocument(arg0 context.Context, arg1 string, arg2 models.Document) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDocument", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDocument indicates an expected call of UpdateDocument
+func (mr *MockResilienceStoreMockRecorder) UpdateDocument(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDocument", reflect.TypeOf((*MockResilienceStore)(nil).UpdateDocument), arg0, arg1, arg2)
+}
+
+// UpsertDocumentByName mocks base method
+func (m *MockResilienceStore) UpsertDocumentByName(arg0 context.Context, arg1 models.Document) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertDocumentByName", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// UpsertDocumentByName indicates an expected call of UpsertDocumentByName
+func (mr *MockResilienceStoreMockRecorder) UpsertDocumentByName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertDocumentByName", reflect.TypeOf((*MockResilienceStore)(nil).UpsertDocumentByName), arg0, arg1)
+}
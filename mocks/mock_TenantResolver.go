@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: microservice/internal/app/drivers/rest (interfaces: TenantResolver)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	models "microservice/models"
+	http "net/http"
+	reflect "reflect"
+)
+
+// MockTenantResolver is a mock of TenantResolver interface
+type MockTenantResolver struct {
+	ctrl     *gomock.Controller
+	recorder *MockTenantResolverMockRecorder
+}
+
+// MockTenantResolverMockRecorder is the mock recorder for MockTenantResolver
+type MockTenantResolverMockRecorder struct {
+	mock *MockTenantResolver
+}
+
+// NewMockTenantResolver creates a new mock instance
+func NewMockTenantResolver(ctrl *gomock.Controller) *MockTenantResolver {
+	mock := &MockTenantResolver{ctrl: ctrl}
+	mock.recorder = &MockTenantResolverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockTenantResolver) EXPECT() *MockTenantResolverMockRecorder {
+	return m.recorder
+}
+
+// ResolveTenant mocks base method
+func (m *MockTenantResolver) ResolveTenant(arg0 *http.Request, arg1 models.Principal) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveTenant", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveTenant indicates an expected call of ResolveTenant
+func (mr *MockTenantResolverMockRecorder) ResolveTenant(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveTenant", reflect.TypeOf((*MockTenantResolver)(nil).ResolveTenant), arg0, arg1)
+}
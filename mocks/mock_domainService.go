@@ -35,33 +35,93 @@ func (m *MockDomainService) EXPECT() *MockDomainServiceMockRecorder {
 }
 
 // AddDocument mocks base method
-func (m *MockDomainService) AddDocument(arg0 context.Context, arg1 models.Document) (string, error) {
+func (m *MockDomainService) AddDocument(arg0 context.Context, arg1 models.Document, arg2 string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddDocument", arg0, arg1)
+	ret := m.ctrl.Call(m, "AddDocument", arg0, arg1, arg2)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AddDocument indicates an expected call of AddDocument
-func (mr *MockDomainServiceMockRecorder) AddDocument(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockDomainServiceMockRecorder) AddDocument(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDocument", reflect.TypeOf((*MockDomainService)(nil).AddDocument), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDocument", reflect.TypeOf((*MockDomainService)(nil).AddDocument), arg0, arg1, arg2)
 }
 
 // GetDocument mocks base method
-func (m *MockDomainService) GetDocument(arg0 context.Context, arg1 string) (models.Document, error) {
+func (m *MockDomainService) GetDocument(arg0 context.Context, arg1 string, arg2 models.ReadOptions) (models.Document, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetDocument", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetDocument", arg0, arg1, arg2)
 	ret0, _ := ret[0].(models.Document)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetDocument indicates an expected call of GetDocument
-func (mr *MockDomainServiceMockRecorder) GetDocument(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockDomainServiceMockRecorder) GetDocument(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDocument", reflect.TypeOf((*MockDomainService)(nil).GetDocument), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDocument", reflect.TypeOf((*MockDomainService)(nil).GetDocument), arg0, arg1, arg2)
+}
+
+// GetDocumentByName mocks base method
+func (m *MockDomainService) GetDocumentByName(arg0 context.Context, arg1 string) (models.Document, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDocumentByName", arg0, arg1)
+	ret0, _ := ret[0].(models.Document)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDocumentByName indicates an expected call of GetDocumentByName
+func (mr *MockDomainServiceMockRecorder) GetDocumentByName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDocumentByName", reflect.TypeOf((*MockDomainService)(nil).GetDocumentByName), arg0, arg1)
+}
+
+// PatchDocument mocks base method
+func (m *MockDomainService) PatchDocument(arg0 context.Context, arg1 string, arg2 models.PatchType, arg3 []byte) (models.Document, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchDocument", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(models.Document)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PatchDocument indicates an expected call of PatchDocument
+func (mr *MockDomainServiceMockRecorder) PatchDocument(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchDocument", reflect.TypeOf((*MockDomainService)(nil).PatchDocument), arg0, arg1, arg2, arg3)
+}
+
+// PutDocument mocks base method
+func (m *MockDomainService) PutDocument(arg0 context.Context, arg1 string, arg2 models.Document) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutDocument", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutDocument indicates an expected call of PutDocument
+func (mr *MockDomainServiceMockRecorder) PutDocument(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutDocument", reflect.TypeOf((*MockDomainService)(nil).PutDocument), arg0, arg1, arg2)
+}
+
+// SearchDocuments mocks base method
+func (m *MockDomainService) SearchDocuments(arg0 context.Context, arg1 models.SearchQuery) ([]models.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchDocuments", arg0, arg1)
+	ret0, _ := ret[0].([]models.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchDocuments indicates an expected call of SearchDocuments
+func (mr *MockDomainServiceMockRecorder) SearchDocuments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchDocuments", reflect.TypeOf((*MockDomainService)(nil).SearchDocuments), arg0, arg1)
 }
 
 // Teardown mocks base method
@@ -77,3 +137,19 @@ func (mr *MockDomainServiceMockRecorder) Teardown(arg0 interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Teardown", reflect.TypeOf((*MockDomainService)(nil).Teardown), arg0)
 }
+
+// UpsertDocument mocks base method
+func (m *MockDomainService) UpsertDocument(arg0 context.Context, arg1 models.Document) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertDocument", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// UpsertDocument indicates an expected call of UpsertDocument
+func (mr *MockDomainServiceMockRecorder) UpsertDocument(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertDocument", reflect.TypeOf((*MockDomainService)(nil).UpsertDocument), arg0, arg1)
+}
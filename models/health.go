@@ -0,0 +1,14 @@
+package models
+
+// HealthCheck is the state of a dependency of the service
+type HealthCheck struct {
+	Name    string                 `json:"name"`
+	Ready   bool                   `json:"ready"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Health is the readiness of the service, ready when all of its checks are
+type Health struct {
+	Ready  bool          `json:"ready"`
+	Checks []HealthCheck `json:"checks"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// IdempotencyRecord is the outcome of a request made with an idempotency key
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+
+	// DocumentID is empty while the request of the key is still in progress
+	DocumentID string
+	CreatedAt  time.Time
+
+	// ReservedUntil is when a request still in progress is considered abandoned, so its key can be reused
+	ReservedUntil time.Time
+}
@@ -0,0 +1,19 @@
+package models
+
+// IndexReport describes the outcome of reconciling the declared indexes of a collection with its existing indexes
+type IndexReport struct {
+	Database   string
+	Collection string
+
+	// Created lists the declared indexes that were missing and created
+	Created []string
+
+	// Drifted describes declared indexes whose existing definition differs from the declaration
+	Drifted []string
+
+	// Undeclared lists the existing indexes that are not declared
+	Undeclared []string
+
+	// Dropped lists the undeclared indexes that were dropped
+	Dropped []string
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// MigrationStatus describes whether a migration was applied to a database
+type MigrationStatus struct {
+	Database    string
+	Version     int64
+	Description string
+	Applied     bool
+	AppliedAt   time.Time
+}
+
+// MigrationResult describes a migration that was applied or reverted in a database
+type MigrationResult struct {
+	Database    string
+	Version     int64
+	Description string
+	Down        bool
+
+	// Affected is the number of documents changed by the migration, or that would be changed on a dry run
+	Affected int64
+	DryRun   bool
+}
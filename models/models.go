@@ -1,7 +1,45 @@
 package models
 
+import "time"
+
 // Document is a representation of a single document
 type Document struct {
 	Name string
 	Doc  map[string]interface{}
+
+	// Tenant is the tenant that owns the document when tenants share a collection
+	Tenant string `json:"-" bson:"tenant,omitempty"`
+
+	// CreatedBy is the subject of the principal that created the document
+	CreatedBy string `json:"-"`
+
+	// Version is incremented on every update and used for optimistic concurrency
+	Version int64 `json:"-"`
+
+	// ModifiedAt is the time the document was last written, it is zero for documents not written since it was introduced
+	ModifiedAt time.Time `json:"-" bson:"modifiedat,omitempty"`
+}
+
+// ReadOptions selects the fields of the documents read, and whether they may be served from a cache.
+// Fields are paths of dot separated keys, such as name or doc.address.city
+type ReadOptions struct {
+	// Fields are the only fields read, all fields are read when it is empty
+	Fields []string
+
+	// Exclude are the fields not read. It can't be combined with Fields
+	Exclude []string
+
+	// Fresh reads skip caches and load the stored documents
+	Fresh bool
 }
+
+// PatchType defines the format of a document patch
+type PatchType string
+
+const (
+	// PatchTypeJSONPatch is a JSON Patch (RFC 6902) document
+	PatchTypeJSONPatch PatchType = "application/json-patch+json"
+
+	// PatchTypeMergePatch is a JSON Merge Patch (RFC 7386) document
+	PatchTypeMergePatch PatchType = "application/merge-patch+json"
+)
@@ -0,0 +1,10 @@
+package models
+
+// Principal is the authenticated identity that issued a request
+type Principal struct {
+	Subject string
+	Roles   []string
+
+	// Tenant is the tenant the principal is bound to, empty if it may act on behalf of any tenant
+	Tenant string
+}
@@ -0,0 +1,45 @@
+package models
+
+// SearchQuery finds documents by their content
+type SearchQuery struct {
+	// Text is searched for in the fields of the text index of the documents. Documents aren't searched by text when
+	// it is empty
+	Text string
+
+	// Filter selects documents by the values of their fields. Documents aren't filtered when it is nil
+	Filter *Filter
+
+	// Limit is the largest number of results
+	Limit int
+
+	// ReadOptions selects the fields of the documents found
+	ReadOptions
+}
+
+// Filter is a condition on documents. It either combines filters with And, Or or Not, or tests the value of the
+// field at path Field: equal to Eq, within the range of Gt, Gte, Lt and Lte, one of In, or whether it Exists
+type Filter struct {
+	And []Filter `json:"and,omitempty"`
+	Or  []Filter `json:"or,omitempty"`
+	Not *Filter  `json:"not,omitempty"`
+
+	Field  string        `json:"field,omitempty"`
+	Eq     interface{}   `json:"eq,omitempty"`
+	Gt     interface{}   `json:"gt,omitempty"`
+	Gte    interface{}   `json:"gte,omitempty"`
+	Lt     interface{}   `json:"lt,omitempty"`
+	Lte    interface{}   `json:"lte,omitempty"`
+	In     []interface{} `json:"in,omitempty"`
+	Exists *bool         `json:"exists,omitempty"`
+}
+
+// SearchResult is a document found by a search, along with the id it is stored under
+type SearchResult struct {
+	ID string `json:"id"`
+
+	// Score is the relevance of the document to the searched text, higher is more relevant. It is 0 for searches
+	// without text
+	Score float64 `json:"score"`
+
+	Document Document `json:"document"`
+}
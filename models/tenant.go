@@ -0,0 +1,7 @@
+package models
+
+// TenantQuota limits the resources a single tenant may use. Zero values are unlimited
+type TenantQuota struct {
+	MaxDocuments     int64
+	MaxDocumentBytes int64
+}
@@ -8,28 +8,69 @@ import (
 	"microservice/internal/app"
 	"microservice/internal/app/domain"
 	"microservice/internal/app/drivers/rest"
+	"microservice/internal/pkg/auth"
+	"microservice/internal/pkg/cache"
+	"microservice/internal/pkg/config"
 	"microservice/internal/pkg/jsonschema"
 	"microservice/internal/pkg/mongodb"
+	"microservice/internal/pkg/ratelimit"
+	"microservice/internal/pkg/resilience"
+	"microservice/internal/pkg/secrets"
+	"microservice/internal/pkg/tenancy"
 	"microservice/internal/pkg/viper"
 
 	"github.com/google/wire"
 )
 
-func InitializeApplication(ctx context.Context) (*app.App, error) {
+func InitializeApplication(ctx context.Context, opts viper.Options) (*app.App, error) {
 	wire.Build(
 		viper.NewConfiguration,
-		wire.Bind(new(app.Configuration), new(*viper.Service)),
-		wire.Bind(new(rest.Configuration), new(*viper.Service)),
-		wire.Bind(new(mongodb.Configuration), new(*viper.Service)),
+		wire.Bind(new(config.Source), new(*viper.Service)),
+		config.NewConfig,
+		wire.FieldsOf(new(*config.Config), "Server", "Log", "Startup", "Mongo", "Cache"),
+		wire.Bind(new(app.ConfigReloader), new(*viper.Service)),
+		wire.Bind(new(auth.Configuration), new(*viper.Service)),
+
+		auth.NewService,
+		wire.Bind(new(rest.Authenticator), new(*auth.Service)),
+
+		tenancy.NewService,
+		wire.Bind(new(tenancy.Configuration), new(*viper.Service)),
+		wire.Bind(new(rest.TenantResolver), new(*tenancy.Service)),
+		wire.Bind(new(domain.QuotaProvider), new(*tenancy.Service)),
+		wire.Bind(new(mongodb.Tenants), new(*tenancy.Service)),
+
+		ratelimit.NewMemoryStore,
+		wire.Bind(new(ratelimit.Store), new(*ratelimit.MemoryStore)),
+		ratelimit.NewService,
+		wire.Bind(new(ratelimit.Configuration), new(*viper.Service)),
+		wire.Bind(new(rest.RateLimiter), new(*ratelimit.Service)),
 
 		jsonschema.NewJSONSchemaService,
 		wire.Bind(new(rest.JSONSchemaValidator), new(*jsonschema.Service)),
+		wire.Bind(new(domain.JSONSchemaValidator), new(*jsonschema.Service)),
 
 		domain.NewDomain,
 		wire.Bind(new(rest.DomainSvc), new(*domain.Domain)),
 
+		secrets.NewService,
+		wire.Bind(new(secrets.Configuration), new(*viper.Service)),
+		wire.Bind(new(mongodb.Secrets), new(*secrets.Service)),
+
 		mongodb.NewClient,
-		wire.Bind(new(domain.DocumentDB), new(*mongodb.MongoDB)),
+		wire.Bind(new(resilience.Store), new(*mongodb.MongoDB)),
+		wire.Bind(new(domain.IdempotencyStore), new(*mongodb.MongoDB)),
+		wire.Bind(new(app.IndexManager), new(*mongodb.MongoDB)),
+		wire.Bind(new(app.Migrator), new(*mongodb.MongoDB)),
+
+		resilience.NewDocumentDB,
+		wire.Bind(new(cache.Store), new(*resilience.DocumentDB)),
+
+		cache.NewLocalBackend,
+		wire.Bind(new(cache.Backend), new(*cache.LRU)),
+		cache.NewDocumentDB,
+		wire.Bind(new(domain.DocumentDB), new(*cache.DocumentDB)),
+		wire.Bind(new(rest.HealthChecker), new(*resilience.DocumentDB)),
 
 		rest.NewServer,
 		wire.Bind(new(app.RestServer), new(*rest.Adapter)),
@@ -38,3 +79,59 @@ func InitializeApplication(ctx context.Context) (*app.App, error) {
 	)
 	return &app.App{}, nil
 }
+
+func InitializeMongoDB(ctx context.Context, opts viper.Options) (*mongodb.MongoDB, error) {
+	wire.Build(
+		viper.NewConfiguration,
+		wire.Bind(new(config.Source), new(*viper.Service)),
+		config.NewConfig,
+		wire.FieldsOf(new(*config.Config), "Mongo"),
+		wire.Bind(new(tenancy.Configuration), new(*viper.Service)),
+
+		tenancy.NewService,
+		wire.Bind(new(mongodb.Tenants), new(*tenancy.Service)),
+
+		secrets.NewService,
+		wire.Bind(new(secrets.Configuration), new(*viper.Service)),
+		wire.Bind(new(mongodb.Secrets), new(*secrets.Service)),
+
+		mongodb.NewClient,
+	)
+	return &mongodb.MongoDB{}, nil
+}
+
+func InitializeDomain(ctx context.Context, opts viper.Options) (*domain.Domain, error) {
+	wire.Build(
+		viper.NewConfiguration,
+		wire.Bind(new(config.Source), new(*viper.Service)),
+		config.NewConfig,
+		wire.FieldsOf(new(*config.Config), "Mongo", "Cache"),
+		wire.Bind(new(tenancy.Configuration), new(*viper.Service)),
+
+		tenancy.NewService,
+		wire.Bind(new(domain.QuotaProvider), new(*tenancy.Service)),
+		wire.Bind(new(mongodb.Tenants), new(*tenancy.Service)),
+
+		jsonschema.NewJSONSchemaService,
+		wire.Bind(new(domain.JSONSchemaValidator), new(*jsonschema.Service)),
+
+		secrets.NewService,
+		wire.Bind(new(secrets.Configuration), new(*viper.Service)),
+		wire.Bind(new(mongodb.Secrets), new(*secrets.Service)),
+
+		mongodb.NewClient,
+		wire.Bind(new(resilience.Store), new(*mongodb.MongoDB)),
+		wire.Bind(new(domain.IdempotencyStore), new(*mongodb.MongoDB)),
+
+		resilience.NewDocumentDB,
+		wire.Bind(new(cache.Store), new(*resilience.DocumentDB)),
+
+		cache.NewLocalBackend,
+		wire.Bind(new(cache.Backend), new(*cache.LRU)),
+		cache.NewDocumentDB,
+		wire.Bind(new(domain.DocumentDB), new(*cache.DocumentDB)),
+
+		domain.NewDomain,
+	)
+	return &domain.Domain{}, nil
+}
@@ -10,34 +10,147 @@ import (
 	"microservice/internal/app"
 	"microservice/internal/app/domain"
 	"microservice/internal/app/drivers/rest"
+	"microservice/internal/pkg/auth"
+	"microservice/internal/pkg/cache"
+	"microservice/internal/pkg/config"
 	"microservice/internal/pkg/jsonschema"
 	"microservice/internal/pkg/mongodb"
+	"microservice/internal/pkg/ratelimit"
+	"microservice/internal/pkg/resilience"
+	"microservice/internal/pkg/secrets"
+	"microservice/internal/pkg/tenancy"
 	"microservice/internal/pkg/viper"
 )
 
 // Injectors from wire.go:
 
-func InitializeApplication(ctx context.Context) (*app.App, error) {
-	service, err := viper.NewConfiguration()
+func InitializeApplication(ctx context.Context, opts viper.Options) (*app.App, error) {
+	service, err := viper.NewConfiguration(opts)
 	if err != nil {
 		return nil, err
 	}
-	mongoDB, err := mongodb.NewClient(ctx, service)
+	configConfig, err := config.NewConfig(service)
 	if err != nil {
 		return nil, err
 	}
-	domainDomain, err := domain.NewDomain(mongoDB)
+	log := configConfig.Log
+	startup := configConfig.Startup
+	mongo := configConfig.Mongo
+	server := configConfig.Server
+	configCache := configConfig.Cache
+	secretsService, err := secrets.NewService(service)
+	if err != nil {
+		return nil, err
+	}
+	tenancyService, err := tenancy.NewService(service)
+	if err != nil {
+		return nil, err
+	}
+	mongoDB, err := mongodb.NewClient(ctx, mongo, secretsService, tenancyService)
+	if err != nil {
+		return nil, err
+	}
+	documentDB, err := resilience.NewDocumentDB(mongo, mongoDB)
+	if err != nil {
+		return nil, err
+	}
+	lru, err := cache.NewLocalBackend(configCache)
+	if err != nil {
+		return nil, err
+	}
+	cacheDocumentDB, err := cache.NewDocumentDB(configCache, documentDB, lru)
 	if err != nil {
 		return nil, err
 	}
 	jsonschemaService := jsonschema.NewJSONSchemaService()
-	adapter, err := rest.NewServer(service, domainDomain, jsonschemaService)
+	domainDomain, err := domain.NewDomain(cacheDocumentDB, jsonschemaService, tenancyService, mongoDB)
+	if err != nil {
+		return nil, err
+	}
+	authService, err := auth.NewService(service)
 	if err != nil {
 		return nil, err
 	}
-	appApp, err := app.NewApp(service, adapter)
+	memoryStore := ratelimit.NewMemoryStore()
+	ratelimitService, err := ratelimit.NewService(service, memoryStore)
+	if err != nil {
+		return nil, err
+	}
+	adapter, err := rest.NewServer(server, domainDomain, jsonschemaService, authService, tenancyService, ratelimitService, documentDB)
+	if err != nil {
+		return nil, err
+	}
+	appApp, err := app.NewApp(ctx, log, startup, mongo, service, adapter, mongoDB, mongoDB)
 	if err != nil {
 		return nil, err
 	}
 	return appApp, nil
 }
+
+func InitializeMongoDB(ctx context.Context, opts viper.Options) (*mongodb.MongoDB, error) {
+	service, err := viper.NewConfiguration(opts)
+	if err != nil {
+		return nil, err
+	}
+	configConfig, err := config.NewConfig(service)
+	if err != nil {
+		return nil, err
+	}
+	mongo := configConfig.Mongo
+	secretsService, err := secrets.NewService(service)
+	if err != nil {
+		return nil, err
+	}
+	tenancyService, err := tenancy.NewService(service)
+	if err != nil {
+		return nil, err
+	}
+	mongoDB, err := mongodb.NewClient(ctx, mongo, secretsService, tenancyService)
+	if err != nil {
+		return nil, err
+	}
+	return mongoDB, nil
+}
+
+func InitializeDomain(ctx context.Context, opts viper.Options) (*domain.Domain, error) {
+	service, err := viper.NewConfiguration(opts)
+	if err != nil {
+		return nil, err
+	}
+	configConfig, err := config.NewConfig(service)
+	if err != nil {
+		return nil, err
+	}
+	configCache := configConfig.Cache
+	mongo := configConfig.Mongo
+	secretsService, err := secrets.NewService(service)
+	if err != nil {
+		return nil, err
+	}
+	tenancyService, err := tenancy.NewService(service)
+	if err != nil {
+		return nil, err
+	}
+	mongoDB, err := mongodb.NewClient(ctx, mongo, secretsService, tenancyService)
+	if err != nil {
+		return nil, err
+	}
+	documentDB, err := resilience.NewDocumentDB(mongo, mongoDB)
+	if err != nil {
+		return nil, err
+	}
+	lru, err := cache.NewLocalBackend(configCache)
+	if err != nil {
+		return nil, err
+	}
+	cacheDocumentDB, err := cache.NewDocumentDB(configCache, documentDB, lru)
+	if err != nil {
+		return nil, err
+	}
+	jsonschemaService := jsonschema.NewJSONSchemaService()
+	domainDomain, err := domain.NewDomain(cacheDocumentDB, jsonschemaService, tenancyService, mongoDB)
+	if err != nil {
+		return nil, err
+	}
+	return domainDomain, nil
+}